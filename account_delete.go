@@ -0,0 +1,72 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrAccountInUse is returned by DeleteAccountChecked when the account still
+// owns records on the node
+var ErrAccountInUse = errors.New("account still owns images or containers")
+
+// DeleteAccountOptions configures DeleteAccountChecked
+type DeleteAccountOptions struct {
+	// Force deletes the account even if it still owns records, leaving them
+	// orphaned
+	Force bool
+	// DryRun reports the records the account owns without deleting it
+	DryRun bool
+}
+
+// AccountDeletion reports the records an account owns and whether it was deleted
+type AccountDeletion struct {
+	Account    string
+	Images     []ImageRecord
+	Containers []ContainerRecord
+	Deleted    bool
+}
+
+// HasDependents reports whether the account owns any records
+func (d *AccountDeletion) HasDependents() bool {
+	return len(d.Images) > 0 || len(d.Containers) > 0
+}
+
+// DeleteAccountChecked deletes an account like DeleteAccount, but first looks
+// up the images and containers it owns in the node's database and refuses
+// with ErrAccountInUse if there are any, unless opts.Force is set. The report
+// is returned along with ErrAccountInUse.
+func (rpc *CCClient) DeleteAccountChecked(acc, passphrase string, opts DeleteAccountOptions) (*AccountDeletion, error) {
+	records, err := rpc.lvldbRecords(context.Background(), "select", LvlDBFilter{Account: acc})
+	if err != nil {
+		return nil, fmt.Errorf("looking up records of %s: %w", acc, err)
+	}
+	report := &AccountDeletion{Account: acc, Images: records.Images, Containers: records.Containers}
+	if opts.DryRun {
+		return report, nil
+	}
+	if report.HasDependents() && !opts.Force {
+		return report, fmt.Errorf("%w: %s owns %d images and %d containers", ErrAccountInUse, acc, len(report.Images), len(report.Containers))
+	}
+	if err := rpc.DeleteAccount(acc, passphrase); err != nil {
+		return report, err
+	}
+	report.Deleted = true
+	return report, nil
+}
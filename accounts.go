@@ -0,0 +1,53 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// AccountDetails is an account of the node with the metadata it keeps.
+// Nodes that only list addresses leave all other fields zero.
+type AccountDetails struct {
+	Address   string    `json:"address"`
+	CreatedAt Timestamp `json:"createdAt"`
+	// Unlocked reports whether a session of the account is open
+	Unlocked bool `json:"unlocked"`
+	// KeyFile is the path of the account's key file on the node
+	KeyFile string `json:"keyFile,omitempty"`
+}
+
+// UnmarshalJSON accepts a bare address as listed by older nodes as well
+func (a *AccountDetails) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		*a = AccountDetails{}
+		return json.Unmarshal(data, &a.Address)
+	}
+	type plain AccountDetails
+	return json.Unmarshal(data, (*plain)(a))
+}
+
+// ListAccountsDetails returns the accounts of the node with their metadata,
+// where the node provides it, in a single call
+func (rpc *CCClient) ListAccountsDetails() ([]AccountDetails, error) {
+	var accounts []AccountDetails
+	if err := rpc.callInto(context.Background(), &accounts, "accounts_listAccounts"); err != nil {
+		return nil, err
+	}
+	return accounts, nil
+}
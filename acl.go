@@ -0,0 +1,142 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// ACLSubject is the kind of identity a node ACL entry matches
+type ACLSubject string
+
+const (
+	// ACLAccount entries match the accounts submitting work
+	ACLAccount ACLSubject = "account"
+	// ACLPeer entries match the peer ids of the nodes forwarding work
+	ACLPeer ACLSubject = "peer"
+)
+
+// NodeACL lists whom a node accepts work from. Denied entries always win;
+// if any entry of a subject is allowed, all others of it are rejected.
+type NodeACL struct {
+	AllowAccounts []string `json:"allowAccounts"`
+	DenyAccounts  []string `json:"denyAccounts"`
+	AllowPeers    []string `json:"allowPeers"`
+	DenyPeers     []string `json:"denyPeers"`
+}
+
+// IsEmpty reports whether the list has no entries, so the node accepts work
+// from everyone
+func (a *NodeACL) IsEmpty() bool {
+	return len(a.AllowAccounts) == 0 && len(a.DenyAccounts) == 0 && len(a.AllowPeers) == 0 && len(a.DenyPeers) == 0
+}
+
+// GetNodeACL returns the access control list of the given node
+func (rpc *CCClient) GetNodeACL(nodeID string) (*NodeACL, error) {
+	acl := new(NodeACL)
+	if err := rpc.callInto(context.Background(), acl, "acl_list", nodeID); err != nil {
+		return nil, err
+	}
+	return acl, nil
+}
+
+// AllowOnNode adds id to the allowed accounts or peers of the node
+func (rpc *CCClient) AllowOnNode(nodeID string, subject ACLSubject, id string) error {
+	_, err := rpc.call("acl_allow", nodeID, subject, id)
+	return err
+}
+
+// DenyOnNode adds id to the denied accounts or peers of the node
+func (rpc *CCClient) DenyOnNode(nodeID string, subject ACLSubject, id string) error {
+	_, err := rpc.call("acl_deny", nodeID, subject, id)
+	return err
+}
+
+// RemoveFromNodeACL removes id from the allowed and denied entries of the node
+func (rpc *CCClient) RemoveFromNodeACL(nodeID string, subject ACLSubject, id string) error {
+	_, err := rpc.call("acl_remove", nodeID, subject, id)
+	return err
+}
+
+// nodeDenylist holds the worker nodes the client doesn't dispatch to, shared
+// by derived clients
+type nodeDenylist struct {
+	mu    sync.Mutex
+	nodes map[string]bool
+}
+
+func (d *nodeDenylist) denied(nodeID string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.nodes[nodeID]
+}
+
+func (d *nodeDenylist) len() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.nodes)
+}
+
+// DenyNode keeps jobs from being placed on the given worker nodes
+func (rpc *CCClient) DenyNode(nodeIDs ...string) {
+	rpc.denylist.mu.Lock()
+	defer rpc.denylist.mu.Unlock()
+	for _, id := range nodeIDs {
+		rpc.denylist.nodes[id] = true
+	}
+}
+
+// UndenyNode removes worker nodes from the denylist
+func (rpc *CCClient) UndenyNode(nodeIDs ...string) {
+	rpc.denylist.mu.Lock()
+	defer rpc.denylist.mu.Unlock()
+	for _, id := range nodeIDs {
+		delete(rpc.denylist.nodes, id)
+	}
+}
+
+// DeniedNodes returns the worker nodes jobs aren't placed on, sorted
+func (rpc *CCClient) DeniedNodes() []string {
+	rpc.denylist.mu.Lock()
+	defer rpc.denylist.mu.Unlock()
+	nodes := make([]string, 0, len(rpc.denylist.nodes))
+	for id := range rpc.denylist.nodes {
+		nodes = append(nodes, id)
+	}
+	sort.Strings(nodes)
+	return nodes
+}
+
+// discoverWorkers discovers up to num workers matching filter that aren't denied
+func (rpc *CCClient) discoverWorkers(ctx context.Context, num int, filter NodeFilter) ([]NodeInfo, error) {
+	nodes, err := rpc.discoverNodesWithFilter(ctx, num+rpc.denylist.len(), filter)
+	if err != nil {
+		return nil, err
+	}
+	allowed := nodes[:0]
+	for _, n := range nodes {
+		if !rpc.denylist.denied(n.ID) {
+			allowed = append(allowed, n)
+		}
+	}
+	if len(allowed) > num {
+		allowed = allowed[:num]
+	}
+	return allowed, nil
+}
@@ -0,0 +1,99 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"context"
+	"time"
+)
+
+// LogLevel is the verbosity of a node's log
+type LogLevel string
+
+const (
+	LogDebug LogLevel = "debug"
+	LogInfo  LogLevel = "info"
+	LogWarn  LogLevel = "warn"
+	LogError LogLevel = "error"
+)
+
+// NodeLogOptions controls which lines NodeLogs returns
+type NodeLogOptions struct {
+	// Since only returns lines written at or after this time
+	Since time.Time `json:"-"`
+	// Tail limits the result to the last lines, all if zero
+	Tail int `json:"tail,omitempty"`
+	// Level only returns lines of this level or above
+	Level LogLevel `json:"level,omitempty"`
+}
+
+// NodeLogEntry is a line of a node's own log
+type NodeLogEntry struct {
+	Timestamp time.Time `json:"timestamp"`
+	Level     LogLevel  `json:"level"`
+	Module    string    `json:"module,omitempty"`
+	Message   string    `json:"message"`
+}
+
+// NodeKeys identifies a node after its keys were rotated
+type NodeKeys struct {
+	// PeerID is derived from the new public key
+	PeerID    string `json:"peerID"`
+	PublicKey string `json:"publicKey"`
+}
+
+// RestartNodeService restarts a service of the given node, e.g. "rpc" or
+// "p2p". Calls to the node fail until it is back.
+func (rpc *CCClient) RestartNodeService(nodeID, service string) error {
+	_, err := rpc.call("admin_restartService", nodeID, service)
+	return err
+}
+
+// ReloadNodeConfig has the node reread its configuration file
+func (rpc *CCClient) ReloadNodeConfig(nodeID string) error {
+	_, err := rpc.call("admin_reloadConfig", nodeID)
+	return err
+}
+
+// SetNodeLogLevel changes the verbosity of the node's log until it restarts
+func (rpc *CCClient) SetNodeLogLevel(nodeID string, level LogLevel) error {
+	_, err := rpc.call("admin_setLogLevel", nodeID, level)
+	return err
+}
+
+// RotateNodeKeys replaces the node's key pair and returns its new identity.
+// Peers know the node under the new peer id afterwards.
+func (rpc *CCClient) RotateNodeKeys(nodeID string) (*NodeKeys, error) {
+	keys := new(NodeKeys)
+	if err := rpc.callInto(context.Background(), keys, "admin_rotateKeys", nodeID); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// NodeLogs returns lines of the node's own log, oldest first
+func (rpc *CCClient) NodeLogs(nodeID string, opts NodeLogOptions) ([]NodeLogEntry, error) {
+	var since string
+	if !opts.Since.IsZero() {
+		since = opts.Since.UTC().Format(time.RFC3339Nano)
+	}
+	var entries []NodeLogEntry
+	if err := rpc.callInto(context.Background(), &entries, "admin_nodeLogs", nodeID, opts, since); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
@@ -0,0 +1,340 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const artifactIndexFile = "artifacts.json"
+
+// ArtifactCacheOptions configures an ArtifactCache
+type ArtifactCacheOptions struct {
+	// Dir holds the cache index, cc-go-sdk in the user's cache directory
+	// by default
+	Dir string
+	// MaxAge evicts entries not used for longer, never if zero
+	MaxAge time.Duration
+	// MaxEntries evicts the least recently used entries beyond it, none if zero
+	MaxEntries int
+}
+
+// ArtifactCache remembers which files were uploaded to which node and
+// which images were loaded onto which workers, by content hash, so that
+// uploads and image pushes are skipped when their result already exists,
+// also across process restarts. It is set with WithArtifactCache.
+type ArtifactCache struct {
+	path string
+	opts ArtifactCacheOptions
+
+	mu      sync.Mutex
+	entries map[string]*artifactEntry
+	files   map[string]cachedFileHash
+}
+
+// artifactEntry is what is known about the content with one hash
+type artifactEntry struct {
+	// Uploads maps upload urls to the results of uploading the content
+	Uploads map[string]UploadResult `json:"uploads,omitempty"`
+	// Images maps worker node ids to the id of the image loaded from it
+	Images   map[string]string `json:"images,omitempty"`
+	LastUsed time.Time         `json:"lastUsed"`
+}
+
+// cachedFileHash is the hash of a local file, valid while its size and
+// modification time are unchanged
+type cachedFileHash struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+	Hash    string    `json:"hash"`
+}
+
+type artifactIndex struct {
+	Entries map[string]*artifactEntry `json:"entries"`
+	Files   map[string]cachedFileHash `json:"files"`
+}
+
+// NewArtifactCache opens the cache in opts.Dir, creating it if needed
+func NewArtifactCache(opts ArtifactCacheOptions) (*ArtifactCache, error) {
+	if opts.Dir == "" {
+		dir, err := os.UserCacheDir()
+		if err != nil {
+			return nil, err
+		}
+		opts.Dir = filepath.Join(dir, "cc-go-sdk")
+	}
+	if err := os.MkdirAll(opts.Dir, 0700); err != nil {
+		return nil, err
+	}
+	c := &ArtifactCache{
+		path:    filepath.Join(opts.Dir, artifactIndexFile),
+		opts:    opts,
+		entries: make(map[string]*artifactEntry),
+		files:   make(map[string]cachedFileHash),
+	}
+	data, err := ioutil.ReadFile(c.path)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return nil, err
+	default:
+		var index artifactIndex
+		// a corrupt index only costs the uploads it would have saved
+		if json.Unmarshal(data, &index) == nil {
+			for hash, e := range index.Entries {
+				c.entries[hash] = e
+			}
+			for path, f := range index.Files {
+				c.files[path] = f
+			}
+		}
+	}
+	return c, nil
+}
+
+// HashFile returns the hash of the file like HashFile, reusing the one
+// computed earlier while the file is unchanged
+func (c *ArtifactCache) HashFile(filename string) (string, error) {
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return "", err
+	}
+	c.mu.Lock()
+	f, ok := c.files[abs]
+	c.mu.Unlock()
+	if ok && f.Size == info.Size() && f.ModTime.Equal(info.ModTime()) {
+		return f.Hash, nil
+	}
+	hash, err := HashFile(abs)
+	if err != nil {
+		return "", err
+	}
+	c.mu.Lock()
+	c.files[abs] = cachedFileHash{Size: info.Size(), ModTime: info.ModTime(), Hash: hash}
+	err = c.save()
+	c.mu.Unlock()
+	return hash, err
+}
+
+// entry returns the entry of hash, marking it used, nil if there is none
+// unless create is set. c.mu must be held.
+func (c *ArtifactCache) entry(hash string, create bool) *artifactEntry {
+	hash = artifactKey(hash)
+	e := c.entries[hash]
+	if e == nil {
+		if !create {
+			return nil
+		}
+		e = new(artifactEntry)
+		c.entries[hash] = e
+	}
+	e.LastUsed = time.Now()
+	return e
+}
+
+// Upload returns the result of the earlier upload of hash to uploadURL
+func (c *ArtifactCache) Upload(uploadURL, hash string) (*UploadResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e := c.entry(hash, false)
+	if e == nil {
+		return nil, false
+	}
+	r, ok := e.Uploads[uploadURL]
+	if !ok {
+		return nil, false
+	}
+	return &r, true
+}
+
+// AddUpload records the upload of a file to uploadURL
+func (c *ArtifactCache) AddUpload(uploadURL string, result UploadResult) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e := c.entry(result.Hash, true)
+	if e.Uploads == nil {
+		e.Uploads = make(map[string]UploadResult)
+	}
+	e.Uploads[uploadURL] = result
+	return c.save()
+}
+
+// Image returns the id of the image loaded from hash onto the node
+func (c *ArtifactCache) Image(nodeID, hash string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e := c.entry(hash, false)
+	if e == nil {
+		return "", false
+	}
+	id, ok := e.Images[nodeID]
+	return id, ok
+}
+
+// AddImage records the image loaded from hash onto the node
+func (c *ArtifactCache) AddImage(nodeID, hash, imageID string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e := c.entry(hash, true)
+	if e.Images == nil {
+		e.Images = make(map[string]string)
+	}
+	e.Images[nodeID] = imageID
+	return c.save()
+}
+
+// ForgetUpload drops the uploads of hash, e.g. once it was deleted from the node
+func (c *ArtifactCache) ForgetUpload(hash string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e := c.entries[artifactKey(hash)]; e != nil {
+		e.Uploads = nil
+	}
+	return c.save()
+}
+
+// ForgetImage drops the image with the given id on the node
+func (c *ArtifactCache) ForgetImage(nodeID, imageID string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, e := range c.entries {
+		if e.Images[nodeID] == imageID {
+			delete(e.Images, nodeID)
+		}
+	}
+	return c.save()
+}
+
+// Clear drops all entries
+func (c *ArtifactCache) Clear() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]*artifactEntry)
+	c.files = make(map[string]cachedFileHash)
+	return c.save()
+}
+
+// artifactKey normalizes a hash like sameHash compares them
+func artifactKey(hash string) string {
+	return strings.TrimPrefix(strings.ToLower(strings.TrimSpace(hash)), "sha256:")
+}
+
+// evict drops unused and surplus entries. c.mu must be held.
+func (c *ArtifactCache) evict() {
+	now := time.Now()
+	for hash, e := range c.entries {
+		if (len(e.Uploads) == 0 && len(e.Images) == 0) || (c.opts.MaxAge > 0 && now.Sub(e.LastUsed) > c.opts.MaxAge) {
+			delete(c.entries, hash)
+		}
+	}
+	if c.opts.MaxEntries > 0 && len(c.entries) > c.opts.MaxEntries {
+		hashes := make([]string, 0, len(c.entries))
+		for hash := range c.entries {
+			hashes = append(hashes, hash)
+		}
+		sort.Slice(hashes, func(i, j int) bool {
+			return c.entries[hashes[i]].LastUsed.Before(c.entries[hashes[j]].LastUsed)
+		})
+		for _, hash := range hashes[:len(hashes)-c.opts.MaxEntries] {
+			delete(c.entries, hash)
+		}
+	}
+	for path := range c.files {
+		if _, err := os.Stat(path); err != nil {
+			delete(c.files, path)
+		}
+	}
+}
+
+// save evicts entries and writes the index atomically. c.mu must be held.
+func (c *ArtifactCache) save() error {
+	c.evict()
+	data, err := json.Marshal(artifactIndex{Entries: c.entries, Files: c.files})
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(c.path), ".artifacts-")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), c.path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// uploadFile uploads the file unless the artifact cache knows it was
+// uploaded to the node already, over IPFS if configured
+func (rpc *CCClient) uploadFile(ctx context.Context, filename, token string) (*UploadResult, error) {
+	if rpc.uploader == nil {
+		return nil, ErrNoUploadClient
+	}
+	var hash string
+	if rpc.artifacts != nil {
+		var err error
+		if hash, err = rpc.artifacts.HashFile(filename); err != nil {
+			return nil, err
+		}
+		if result, ok := rpc.artifacts.Upload(rpc.uploader.url, hash); ok {
+			return result, nil
+		}
+	}
+	var cid string
+	if rpc.ipfs != nil {
+		result, published, ok, err := rpc.uploadViaIPFS(ctx, filename, hash)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			if rpc.artifacts != nil {
+				rpc.artifacts.AddUpload(rpc.uploader.url, *result)
+			}
+			return result, nil
+		}
+		cid = published
+	}
+	result, err := rpc.uploader.UploadFileContext(ctx, filename, token)
+	if err != nil {
+		return nil, err
+	}
+	if cid != "" {
+		result.CID = cid
+	}
+	if rpc.artifacts != nil && sameHash(result.Hash, hash) {
+		rpc.artifacts.AddUpload(rpc.uploader.url, *result)
+	}
+	return result, nil
+}
@@ -0,0 +1,158 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// auditRedacted replaces secrets in audit records
+const auditRedacted = "[REDACTED]"
+
+// AuditRecord describes a mutating call made by a client
+type AuditRecord struct {
+	Time          time.Time     `json:"time"`
+	Method        string        `json:"method"`
+	CorrelationID string        `json:"correlationID"`
+	Account       string        `json:"account,omitempty"`
+	Params        []interface{} `json:"params"`
+	Duration      time.Duration `json:"duration"`
+	// Result is the raw result of a successful call, Error the failure
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// AuditSink stores audit records, e.g. in a file or database. It is called
+// synchronously after each call and must be safe for concurrent use.
+type AuditSink interface {
+	Audit(record AuditRecord) error
+}
+
+// AuditSinkFunc is a function implementing AuditSink
+type AuditSinkFunc func(record AuditRecord) error
+
+// Audit calls f
+func (f AuditSinkFunc) Audit(record AuditRecord) error {
+	return f(record)
+}
+
+// jsonAuditSink writes records as JSON lines
+type jsonAuditSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewJSONAuditSink returns a sink writing each record as a line of JSON to
+// w, e.g. an append-only file or a *syslog.Writer
+func NewJSONAuditSink(w io.Writer) AuditSink {
+	return &jsonAuditSink{w: w}
+}
+
+func (s *jsonAuditSink) Audit(record AuditRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.w.Write(append(line, '\n'))
+	return err
+}
+
+// readOnlyVerbs are the method name prefixes of calls that don't change state
+// and aren't audited
+var readOnlyVerbs = []string{
+	"get", "list", "select", "inspect", "discover", "validate", "estimate",
+	"ping", "version", "modules", "logs", "nodeLogs", "containerLogs", "containerStats",
+	"imageExists", "benchmark", "subscribe", "unsubscribe", "poll", "search",
+}
+
+// auditSecrets are the positions of the secret params of methods
+var auditSecrets = map[string][]int{
+	"accounts_createAccount":           {0},
+	"accounts_unlockAccount":           {1},
+	"accounts_unlockAccountWithFactor": {1, 2},
+	"accounts_unlockAccounts":          {0},
+	"accounts_deleteAccount":           {1},
+}
+
+// auditSecretResults are the methods whose result holds a credential, e.g. a
+// session token or signing secret. Audit records, JSON debug logs and HAR
+// files all redact the results of these methods.
+var auditSecretResults = map[string]bool{
+	"accounts_unlockAccount":           true,
+	"accounts_unlockAccountWithFactor": true,
+	"accounts_unlockAccounts":          true,
+	"imagemanager_registerWebhook":     true,
+}
+
+// redactParams returns a copy of the params of method with its secrets redacted
+func redactParams(method string, params []interface{}) []interface{} {
+	redacted := make([]interface{}, len(params))
+	copy(redacted, params)
+	for _, i := range auditSecrets[method] {
+		if i < len(redacted) {
+			redacted[i] = auditRedacted
+		}
+	}
+	return redacted
+}
+
+// isMutating reports whether method changes state on the node
+func isMutating(method string) bool {
+	verb := method
+	if i := strings.IndexByte(method, '_'); i >= 0 {
+		verb = method[i+1:]
+	}
+	for _, prefix := range readOnlyVerbs {
+		if strings.HasPrefix(verb, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// audit records a finished mutating call with the audit sink, if any
+func (rpc *CCClient) audit(ctx context.Context, method, correlationID string, params []interface{}, start time.Time, result json.RawMessage, err error) {
+	if rpc.auditSink == nil || !isMutating(method) {
+		return
+	}
+	record := AuditRecord{
+		Time:          start,
+		Method:        method,
+		CorrelationID: correlationID,
+		Account:       rpc.session(ctx).Account,
+		Params:        redactParams(method, params),
+		Duration:      time.Since(start),
+		Result:        result,
+	}
+	if auditSecretResults[method] && result != nil {
+		record.Result, _ = json.Marshal(auditRedacted)
+	}
+	if err != nil {
+		record.Error = err.Error()
+	}
+	if err := rpc.auditSink.Audit(record); err != nil && rpc.Debug {
+		log.Printf("audit %s: %v\n", method, err)
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestRedactParams(t *testing.T) {
+	tests := []struct {
+		method string
+		params []interface{}
+		want   []interface{}
+	}{
+		{"accounts_createAccount", []interface{}{"pass"}, []interface{}{auditRedacted}},
+		{"accounts_unlockAccount", []interface{}{"0x1", "pass"}, []interface{}{"0x1", auditRedacted}},
+		{"accounts_unlockAccountWithFactor", []interface{}{"0x1", "pass", "123456"}, []interface{}{"0x1", auditRedacted, auditRedacted}},
+		{"accounts_unlockAccounts", []interface{}{map[string]string{"0x1": "pass"}}, []interface{}{auditRedacted}},
+		{"accounts_deleteAccount", []interface{}{"0x1", "pass"}, []interface{}{"0x1", auditRedacted}},
+		// missing params are left missing
+		{"accounts_unlockAccount", []interface{}{"0x1"}, []interface{}{"0x1"}},
+		{"imagemanager_registerWebhook", []interface{}{"https://hooks.example", nil}, []interface{}{"https://hooks.example", nil}},
+		{"imagemanager_runImage", []interface{}{"node", "image"}, []interface{}{"node", "image"}},
+	}
+	for _, tt := range tests {
+		params := append([]interface{}(nil), tt.params...)
+		got := redactParams(tt.method, params)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("redactParams(%s, %v) = %v, want %v", tt.method, tt.params, got, tt.want)
+		}
+		if !reflect.DeepEqual(params, tt.params) {
+			t.Errorf("redactParams(%s) modified its params to %v", tt.method, params)
+		}
+	}
+}
+
+// recordingSink keeps the audit records it is given
+type recordingSink struct {
+	mu      sync.Mutex
+	records []AuditRecord
+}
+
+func (s *recordingSink) Audit(record AuditRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = append(s.records, record)
+	return nil
+}
+
+func TestSecretResultsRedacted(t *testing.T) {
+	const secret = "s3cr3t-credential"
+	tests := []struct {
+		method string
+		call   func(rpc *CCClient) error
+		secret bool
+	}{
+		{"accounts_unlockAccount", func(rpc *CCClient) error {
+			_, err := rpc.UnlockAccount("0x1", "pass")
+			return err
+		}, true},
+		{"imagemanager_registerWebhook", func(rpc *CCClient) error {
+			_, err := rpc.RegisterWebhook("https://hooks.example", nil)
+			return err
+		}, true},
+		{"imagemanager_runImage", func(rpc *CCClient) error {
+			_, err := rpc.ExecuteImage("node", "image")
+			return err
+		}, false},
+	}
+	results := map[string]string{
+		"accounts_unlockAccount":       `"` + secret + `"`,
+		"imagemanager_registerWebhook": `{"id":"w1","url":"https://hooks.example","secret":"` + secret + `"}`,
+		"imagemanager_runImage":        `"` + secret + `"`,
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req rpcRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":` + results[req.Method] + `}`))
+	}))
+	defer srv.Close()
+
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			if got := auditSecretResults[tt.method]; got != tt.secret {
+				t.Fatalf("auditSecretResults[%s] = %v, want %v", tt.method, got, tt.secret)
+			}
+			sink := new(recordingSink)
+			var debug bytes.Buffer
+			har := NewHARRecorder(nil)
+			rpc := NewCCClient(srv.URL, WithHTTPClient(har.Client()), WithAuditSink(sink), WithDebugJSON(&debug), WithThrottleRetries(0, 0))
+			if err := tt.call(rpc); err != nil {
+				t.Fatal(err)
+			}
+			var harFile bytes.Buffer
+			if _, err := har.WriteTo(&harFile); err != nil {
+				t.Fatal(err)
+			}
+			if len(sink.records) != 1 {
+				t.Fatalf("got %d audit records, want 1", len(sink.records))
+			}
+			outputs := map[string]string{
+				"audit record": string(sink.records[0].Result),
+				"debug log":    debug.String(),
+				"HAR":          harFile.String(),
+			}
+			for name, out := range outputs {
+				if leaked := strings.Contains(out, secret); leaked != !tt.secret {
+					t.Errorf("%s contains the result: %v, want %v\n%s", name, leaked, !tt.secret, out)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ErrTokenExpired is returned when the node rejects the session token,
+// because it expired, was revoked or is missing
+var ErrTokenExpired = errors.New("session token rejected")
+
+// ReauthenticateFunc returns a fresh session token, e.g. by unlocking the
+// account again, see WithReauthenticate
+type ReauthenticateFunc func(ctx context.Context) (string, error)
+
+// authStatusError reports an http status rejecting the credentials
+func authStatusError(status int) error {
+	if status != http.StatusUnauthorized && status != http.StatusForbidden {
+		return nil
+	}
+	return fmt.Errorf("%w: %d %s", ErrTokenExpired, status, http.StatusText(status))
+}
+
+// isAuthError reports whether an rpc error rejects the session token
+func isAuthError(err rpcError) bool {
+	msg := strings.ToLower(err.Message)
+	return strings.Contains(msg, "unauthorized") ||
+		(strings.Contains(msg, "token") && (strings.Contains(msg, "expired") || strings.Contains(msg, "invalid")))
+}
+
+// reauthenticate replaces the token after ErrTokenExpired, reporting
+// whether the call should be retried
+func (rpc *CCClient) reauthenticate(ctx context.Context, err error) (bool, error) {
+	if (rpc.reauth == nil && rpc.auth == nil) || !errors.Is(err, ErrTokenExpired) {
+		return false, nil
+	}
+	// tokens set per call belong to the caller, which has to renew them
+	if _, ok := ctx.Value(sessionKey{}).(Session); ok {
+		return false, nil
+	}
+	if rpc.session(ctx).Token == "" && rpc.auth != nil {
+		if _, rerr := rpc.auth.Refresh(ctx); rerr != nil {
+			return false, fmt.Errorf("reauthenticating: %v (after %w)", rerr, err)
+		}
+		return true, nil
+	}
+	if rpc.reauth == nil {
+		return false, nil
+	}
+	_, rerr := rpc.renewed.get(ctx, true, func(ctx context.Context) (string, time.Time, error) {
+		token, err := rpc.reauth(ctx)
+		return token, time.Time{}, err
+	})
+	if rerr != nil {
+		return false, fmt.Errorf("reauthenticating: %v (after %w)", rerr, err)
+	}
+	return true, nil
+}
+
+type sessionKey struct{}
+
+// ContextWithToken returns a context authenticating the calls made with it
+// with token instead of the client's token, so that one client can serve
+// many users without sharing credentials between their calls
+func ContextWithToken(ctx context.Context, token string) context.Context {
+	return ContextWithSession(ctx, Session{Token: token})
+}
+
+// ContextWithSession is ContextWithToken for the token of session, whose
+// account is also recorded by the audit sink
+func ContextWithSession(ctx context.Context, session Session) context.Context {
+	return context.WithValue(ctx, sessionKey{}, session)
+}
+
+// session returns the session of the calls made with ctx: the one set with
+// ContextWithSession, or otherwise the client's
+func (rpc *CCClient) session(ctx context.Context) Session {
+	if s, ok := ctx.Value(sessionKey{}).(Session); ok {
+		return s
+	}
+	if token := rpc.renewed.current(); token != "" {
+		return Session{Account: rpc.account, Token: token}
+	}
+	return Session{Account: rpc.account, Token: rpc.token}
+}
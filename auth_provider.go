@@ -0,0 +1,191 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AuthProvider supplies the tokens calls are authenticated with, e.g. from
+// an identity provider or SSO gateway fronting the node, see WithAuthProvider
+type AuthProvider interface {
+	// Token returns the current token, obtaining one if there is none
+	Token(ctx context.Context) (string, error)
+	// Refresh obtains a new token after the node rejected the current one
+	Refresh(ctx context.Context) (string, error)
+}
+
+// tokenExpirySkew renews tokens this long before they expire
+const tokenExpirySkew = 30 * time.Second
+
+// cachedToken holds a token until shortly before it expires
+type cachedToken struct {
+	// mu serializes fetches, value guards token and expires so that they
+	// can be read while fetching
+	mu      sync.Mutex
+	value   sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// get returns the cached token, calling fetch for a new one if there is
+// none, it expired or force is set. A forced fetch is skipped if the token
+// was replaced while waiting for another fetch, e.g. after concurrent calls
+// were rejected. fetch returns a zero expiry for tokens that don't expire.
+func (c *cachedToken) get(ctx context.Context, force bool, fetch func(ctx context.Context) (string, time.Time, error)) (string, error) {
+	seen := c.current()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.value.Lock()
+	token, expires := c.token, c.expires
+	c.value.Unlock()
+	if force && token != "" && token != seen {
+		return token, nil
+	}
+	if !force && token != "" && (expires.IsZero() || time.Now().Add(tokenExpirySkew).Before(expires)) {
+		return token, nil
+	}
+	token, expires, err := fetch(ctx)
+	if err != nil {
+		return "", err
+	}
+	c.value.Lock()
+	c.token, c.expires = token, expires
+	c.value.Unlock()
+	return token, nil
+}
+
+// current returns the cached token, or an empty one if there is none
+func (c *cachedToken) current() string {
+	c.value.Lock()
+	defer c.value.Unlock()
+	return c.token
+}
+
+// accountAuth unlocks an account for its tokens
+type accountAuth struct {
+	rpc        *CCClient
+	account    string
+	passphrase string
+	cache      cachedToken
+}
+
+// NewAccountAuth returns a provider of the tokens of account, unlocked on the
+// node of rpc with accounts_unlockAccount
+func NewAccountAuth(rpc *CCClient, account, passphrase string) AuthProvider {
+	return &accountAuth{rpc: rpc, account: account, passphrase: passphrase}
+}
+
+func (a *accountAuth) fetch(ctx context.Context) (string, time.Time, error) {
+	// unlocking must not ask the provider for a token itself
+	rpc := a.rpc.derive()
+	rpc.auth = nil
+	token, err := rpc.unlockAccount(ctx, a.account, a.passphrase)
+	return token, time.Time{}, err
+}
+
+func (a *accountAuth) Token(ctx context.Context) (string, error) {
+	return a.cache.get(ctx, false, a.fetch)
+}
+
+func (a *accountAuth) Refresh(ctx context.Context) (string, error) {
+	return a.cache.get(ctx, true, a.fetch)
+}
+
+// ClientCredentialsAuth obtains tokens from an OAuth 2.0 / OIDC identity
+// provider with the client credentials grant, caching them until they expire
+type ClientCredentialsAuth struct {
+	// TokenURL is the token endpoint of the identity provider
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	// Audience is sent if set, as some providers require
+	Audience string
+	// HTTPClient sends the token requests, http.DefaultClient by default
+	HTTPClient *http.Client
+
+	cache cachedToken
+}
+
+func (a *ClientCredentialsAuth) fetch(ctx context.Context) (string, time.Time, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(a.Scopes) > 0 {
+		form.Set("scope", strings.Join(a.Scopes, " "))
+	}
+	if a.Audience != "" {
+		form.Set("audience", a.Audience)
+	}
+	req, err := http.NewRequest("POST", a.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(a.ClientID), url.QueryEscape(a.ClientSecret))
+	client := a.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	defer resp.Body.Close()
+	var body struct {
+		AccessToken      string `json:"access_token"`
+		ExpiresIn        int64  `json:"expires_in"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", time.Time{}, fmt.Errorf("token endpoint: %s: %v", resp.Status, err)
+	}
+	if resp.StatusCode != http.StatusOK || body.AccessToken == "" {
+		return "", time.Time{}, fmt.Errorf("token endpoint: %s: %s %s", resp.Status, body.Error, body.ErrorDescription)
+	}
+	var expires time.Time
+	if body.ExpiresIn > 0 {
+		expires = time.Now().Add(time.Duration(body.ExpiresIn) * time.Second)
+	}
+	return body.AccessToken, expires, nil
+}
+
+// Token returns the cached token or obtains a new one
+func (a *ClientCredentialsAuth) Token(ctx context.Context) (string, error) {
+	return a.cache.get(ctx, false, a.fetch)
+}
+
+// Refresh obtains a new token
+func (a *ClientCredentialsAuth) Refresh(ctx context.Context) (string, error) {
+	return a.cache.get(ctx, true, a.fetch)
+}
+
+// authToken returns the token of the calls made with ctx: the per-call or
+// client token if set, otherwise one from the auth provider
+func (rpc *CCClient) authToken(ctx context.Context) (string, error) {
+	if token := rpc.session(ctx).Token; token != "" || rpc.auth == nil {
+		return token, nil
+	}
+	return rpc.auth.Token(ctx)
+}
@@ -0,0 +1,114 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCacheTTLs are the read-only methods cached by WithCache when no
+// TTLs are given
+var DefaultCacheTTLs = map[string]time.Duration{
+	"bootnodes_getBootnodes": 30 * time.Second,
+	"accounts_listAccounts":  10 * time.Second,
+	"lvldb_selectImage":      5 * time.Second,
+	"lvldb_selectType":       5 * time.Second,
+	"lvldb_selectAll":        5 * time.Second,
+	"lvldb_select":           5 * time.Second,
+}
+
+type cacheEntry struct {
+	result  json.RawMessage
+	expires time.Time
+}
+
+// callCache holds the results of read-only calls until their TTL expires
+type callCache struct {
+	ttls map[string]time.Duration
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+func newCallCache(ttls map[string]time.Duration) *callCache {
+	if ttls == nil {
+		ttls = DefaultCacheTTLs
+	}
+	return &callCache{ttls: ttls, entries: make(map[string]cacheEntry)}
+}
+
+// key identifies a call, including the token since results may depend on it.
+// It is empty for methods that aren't cached.
+func (c *callCache) key(token, method string, params []interface{}) string {
+	if c.ttls[method] <= 0 {
+		return ""
+	}
+	p, err := json.Marshal(params)
+	if err != nil {
+		return ""
+	}
+	return method + "\x00" + token + "\x00" + string(p)
+}
+
+func (c *callCache) get(key string) (json.RawMessage, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(e.expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return e.result, true
+}
+
+func (c *callCache) put(key, method string, result json.RawMessage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = cacheEntry{result: result, expires: time.Now().Add(c.ttls[method])}
+}
+
+// invalidate drops the entries of the given methods, or all entries
+func (c *callCache) invalidate(methods []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(methods) == 0 {
+		c.entries = make(map[string]cacheEntry)
+		return
+	}
+	for key := range c.entries {
+		for _, m := range methods {
+			if strings.HasPrefix(key, m+"\x00") {
+				delete(c.entries, key)
+				break
+			}
+		}
+	}
+}
+
+// InvalidateCache drops the cached results of the given methods, e.g.
+// "bootnodes_getBootnodes", or of all methods if none are given
+func (rpc *CCClient) InvalidateCache(methods ...string) {
+	if rpc.cache != nil {
+		rpc.cache.invalidate(methods)
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"context"
+	"sync"
+)
+
+// CancelContainer stops the container on the given node and removes it
+func (rpc *CCClient) CancelContainer(nodeID, containerID string) error {
+	if err := rpc.StopContainer(nodeID, containerID); err != nil {
+		return err
+	}
+	return rpc.RemoveContainer(nodeID, containerID)
+}
+
+// RemoveImage removes the image with the given id from the node
+func (rpc *CCClient) RemoveImage(nodeID, imageID string) error {
+	if _, err := rpc.call("imagemanager_removeImage", nodeID, imageID); err != nil {
+		return err
+	}
+	if rpc.artifacts != nil {
+		rpc.artifacts.ForgetImage(nodeID, imageID)
+	}
+	return nil
+}
+
+// Job is a job running in the background, see SubmitJob
+type Job struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu     sync.Mutex
+	state  JobState
+	nodeID string
+	result *JobResult
+	err    error
+}
+
+// SubmitJob runs the job like RunJob in the background and returns a handle
+// to follow and cancel it. The job is cancelled when ctx is done.
+func (rpc *CCClient) SubmitJob(ctx context.Context, spec JobSpec) *Job {
+	return submitJob(ctx, rpc, spec)
+}
+
+// SubmitJob runs the job like RunJob in the background, see CCClient.SubmitJob
+func (b *LocalBackend) SubmitJob(ctx context.Context, spec JobSpec) *Job {
+	return submitJob(ctx, b, spec)
+}
+
+func submitJob(ctx context.Context, runner JobRunner, spec JobSpec) *Job {
+	job, ctx, spec := newJob(ctx, spec)
+	go job.run(ctx, runner, spec)
+	return job
+}
+
+// newJob returns a queued job, and the context and spec to run it with
+func newJob(ctx context.Context, spec JobSpec) (*Job, context.Context, JobSpec) {
+	ctx, cancel := context.WithCancel(ctx)
+	job := &Job{cancel: cancel, done: make(chan struct{}), state: JobQueued, nodeID: spec.NodeID}
+	onChange := spec.OnStateChange
+	spec.OnStateChange = func(change JobStateChange) {
+		job.mu.Lock()
+		job.state, job.nodeID = change.To, change.NodeID
+		job.mu.Unlock()
+		if onChange != nil {
+			onChange(change)
+		}
+	}
+	return job, ctx, spec
+}
+
+// run runs the job with runner until it finished
+func (j *Job) run(ctx context.Context, runner JobRunner, spec JobSpec) {
+	defer j.cancel()
+	result, err := runner.RunJob(ctx, spec)
+	j.finish(result, err)
+}
+
+func (j *Job) finish(result *JobResult, err error) {
+	j.mu.Lock()
+	j.result, j.err = result, err
+	j.mu.Unlock()
+	close(j.done)
+}
+
+// State returns the current state of the job
+func (j *Job) State() JobState {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.state
+}
+
+// NodeID returns the worker node the job was placed on, if any yet
+func (j *Job) NodeID() string {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.nodeID
+}
+
+// Done is closed once the job finished
+func (j *Job) Done() <-chan struct{} {
+	return j.done
+}
+
+// Wait waits for the job to finish and returns its result like RunJob.
+// It returns ctx.Err() if ctx is done first, leaving the job running.
+func (j *Job) Wait(ctx context.Context) (*JobResult, error) {
+	select {
+	case <-j.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.result, j.err
+}
+
+// Cancel cancels the job, which stops and removes its container, and waits
+// for it to finish. The job's state is JobCancelled afterwards unless it
+// finished before. It returns ctx.Err() if ctx is done first.
+func (j *Job) Cancel(ctx context.Context) error {
+	j.cancel()
+	_, err := j.Wait(ctx)
+	if ctx.Err() != nil {
+		return err
+	}
+	return nil
+}
@@ -20,35 +20,180 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"net/http"
-
-	"golang.org/x/oauth2"
+	"sync"
+	"time"
 )
 
 type CCClient struct {
 	url            string
+	wsURL          string
 	client         *http.Client
+	uploader       *UploadClient
+	downloader     *DownloadClient
+	token          string
 	versionJSONRPC string
 	Debug          bool
+
+	userAgent string
+	header    http.Header
+	cache     *callCache
+	codec     Codec
+	wire      *wireFormat
+	modules   *moduleSet
+	// negotiation is set if calls are adapted to the node's version
+	negotiation *negotiation
+	reauth      ReauthenticateFunc
+	// renewed is the token obtained with reauth, replacing token once set
+	renewed cachedToken
+	// maxResponseSize bounds the size of responses if positive
+	maxResponseSize int64
+	account         string
+	life            *lifecycle
+	// endpoints is set if the node url is resolved, url is the fallback
+	endpoints *endpointSet
+	auditSink AuditSink
+	denylist  *nodeDenylist
+	auth      AuthProvider
+	throttle  throttlePolicy
+	// inflight is set if the number of outstanding requests is bounded
+	inflight *inflightLimiter
+	// transports selects the transport of subscriptions
+	transports *transportSelection
+	reconnect  ReconnectPolicy
+	events     eventBuffer
+	artifacts  *ArtifactCache
+	// debugLog is set if calls are logged as JSON lines
+	debugLog *debugLog
+	stats    *callStats
+	// ipfs is set if uploads are distributed over IPFS
+	ipfs *ipfsDistribution
+}
+
+// lifecycle tracks the streams of a client and the clients derived from it
+type lifecycle struct {
+	// closed is closed by Close to end all streams, which are counted by streams
+	closed    chan struct{}
+	closeOnce sync.Once
+	streams   sync.WaitGroup
 }
 
 // NewCCClient creates new rpc client with given url
-func NewCCClient(url string) *CCClient {
+func NewCCClient(url string, opts ...Option) *CCClient {
 	rpc := &CCClient{
 		url:            url,
 		client:         http.DefaultClient,
 		versionJSONRPC: "2.0",
+		codec:          stdCodec{},
+		modules:        new(moduleSet),
+		life:           &lifecycle{closed: make(chan struct{})},
+		denylist:       &nodeDenylist{nodes: make(map[string]bool)},
+		throttle:       defaultThrottlePolicy,
+		transports:     new(transportSelection),
+		reconnect:      DefaultReconnectPolicy,
+		events:         eventBuffer{size: DefaultEventBuffer},
+		stats:          newCallStats(),
+	}
+	for _, opt := range opts {
+		opt(rpc)
+	}
+	if rpc.inflight != nil && rpc.uploader != nil {
+		rpc.uploader = rpc.uploader.limited(rpc.inflight)
 	}
 	return rpc
 }
 
-func fatalIfErr(err error, message string) {
-	if err != nil {
-		log.Fatalf("%s. ERROR: %v", message, err)
+// Close ends all subscriptions, container streams and run timeout enforcement
+// of the client, waits for their goroutines to stop and closes idle
+// connections. Calls can still be made afterwards but new streams end
+// immediately. Clients derived with WithToken or WithAccount share this,
+// closing any of them closes all.
+func (rpc *CCClient) Close() error {
+	rpc.life.closeOnce.Do(func() {
+		close(rpc.life.closed)
+	})
+	rpc.life.streams.Wait()
+	rpc.client.CloseIdleConnections()
+	return nil
+}
+
+// tokenContext authenticates the calls made with it with token, for methods
+// taking one. An empty token authenticates them as the client.
+func tokenContext(token string) context.Context {
+	if token == "" {
+		return context.Background()
 	}
+	return ContextWithToken(context.Background(), token)
+}
+
+// Session is an unlocked account and the token authenticating it
+type Session struct {
+	Account string
+	Token   string
+}
+
+// derive returns an unauthenticated copy of rpc sharing its connections
+func (rpc *CCClient) derive() *CCClient {
+	return &CCClient{
+		url:             rpc.url,
+		wsURL:           rpc.wsURL,
+		client:          rpc.client,
+		uploader:        rpc.uploader,
+		downloader:      rpc.downloader,
+		versionJSONRPC:  rpc.versionJSONRPC,
+		Debug:           rpc.Debug,
+		userAgent:       rpc.userAgent,
+		header:          rpc.header,
+		cache:           rpc.cache,
+		codec:           rpc.codec,
+		wire:            rpc.wire,
+		modules:         rpc.modules,
+		negotiation:     rpc.negotiation,
+		reauth:          rpc.reauth,
+		maxResponseSize: rpc.maxResponseSize,
+		life:            rpc.life,
+		endpoints:       rpc.endpoints,
+		auditSink:       rpc.auditSink,
+		denylist:        rpc.denylist,
+		auth:            rpc.auth,
+		throttle:        rpc.throttle,
+		inflight:        rpc.inflight,
+		transports:      rpc.transports,
+		reconnect:       rpc.reconnect,
+		events:          rpc.events,
+		artifacts:       rpc.artifacts,
+		debugLog:        rpc.debugLog,
+		stats:           rpc.stats,
+		ipfs:            rpc.ipfs,
+	}
+}
+
+// WithToken returns a client authenticating with token. It shares the
+// connection pool and configuration of rpc and leaves rpc unchanged, so
+// services can act on behalf of many accounts concurrently.
+func (rpc *CCClient) WithToken(token string) *CCClient {
+	c := rpc.derive()
+	c.token = token
+	return c
+}
+
+// WithAccount is WithToken for the token of session, the returned client
+// reports the account of the session from Account
+func (rpc *CCClient) WithAccount(session Session) *CCClient {
+	c := rpc.WithToken(session.Token)
+	c.account = session.Account
+	return c
+}
+
+// Account returns the account of the session the client was derived for
+// with WithAccount, or an empty string
+func (rpc *CCClient) Account() string {
+	return rpc.account
 }
 
 type rpcError struct {
@@ -60,6 +205,56 @@ func (err rpcError) Error() string {
 	return fmt.Sprintf("Error %d (%s)", err.Code, err.Message)
 }
 
+// codeMethodNotFound is the JSON-RPC error code of unknown methods
+const codeMethodNotFound = -32601
+
+// isMethodNotFound reports whether err is the node not knowing the method called
+func isMethodNotFound(err error) bool {
+	var rerr rpcError
+	return errors.As(err, &rerr) && rerr.Code == codeMethodNotFound
+}
+
+// ErrResponseTooLarge is returned for responses exceeding the size set
+// with WithMaxResponseSize
+var ErrResponseTooLarge = errors.New("response exceeds the maximum size")
+
+// limitedReader fails with ErrResponseTooLarge once more than n bytes are read
+type limitedReader struct {
+	r io.Reader
+	n int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.n < 0 {
+		return 0, ErrResponseTooLarge
+	}
+	if int64(len(p)) > l.n+1 {
+		p = p[:l.n+1]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	if l.n < 0 {
+		return n, ErrResponseTooLarge
+	}
+	return n, err
+}
+
+// CallError is returned by failed calls. CorrelationID is sent to the node
+// with the call, so that the failure can be found in the node's logs.
+type CallError struct {
+	Method        string
+	CorrelationID string
+	Err           error
+}
+
+func (e *CallError) Error() string {
+	return fmt.Sprintf("%s [%s]: %v", e.Method, e.CorrelationID, e.Err)
+}
+
+func (e *CallError) Unwrap() error {
+	return e.Err
+}
+
 type rpcResponse struct {
 	ID      int             `json:"id"`
 	JSONRPC string          `json:"jsonrpc"`
@@ -76,85 +271,298 @@ type rpcRequest struct {
 
 // Call returns raw response of method call
 func (rpc *CCClient) call(method string, params ...interface{}) (json.RawMessage, error) {
+	return rpc.callContext(context.Background(), method, params...)
+}
+
+// callInto calls method and decodes its result into result
+func (rpc *CCClient) callInto(ctx context.Context, result interface{}, method string, params ...interface{}) error {
+	res, err := rpc.callContext(ctx, method, params...)
+	if err != nil {
+		return err
+	}
+	return decodeInto(rpc.codec.Unmarshal, method, res, result)
+}
+
+// callContext returns raw response of method call, aborting the request when ctx is done.
+// Errors are returned as *CallError carrying the correlation id of the call.
+func (rpc *CCClient) callContext(ctx context.Context, method string, params ...interface{}) (json.RawMessage, error) {
+	var key string
+	if rpc.cache != nil {
+		if key = rpc.cache.key(rpc.session(ctx).Token, method, params); key != "" {
+			if res, ok := rpc.cache.get(key); ok {
+				return res, nil
+			}
+		}
+	}
+	id := newCorrelationID()
+	called := method
+	if rpc.negotiation != nil {
+		var err error
+		if called, params, err = rpc.adapt(method, params); err != nil {
+			return nil, &CallError{Method: method, CorrelationID: id, Err: err}
+		}
+	}
+	start := time.Now()
+	res, err := rpc.doCall(ctx, id, called, params...)
+	for attempt := 0; rpc.throttle.wait(ctx, err, attempt); attempt++ {
+		res, err = rpc.doCall(ctx, id, called, params...)
+	}
+	if retry, rerr := rpc.reauthenticate(ctx, err); retry {
+		res, err = rpc.doCall(ctx, id, called, params...)
+	} else if rerr != nil {
+		err = rerr
+	}
+	rpc.stats.record(method, time.Since(start), err)
+	rpc.audit(ctx, called, id, params, start, res, err)
+	if err != nil {
+		return nil, &CallError{Method: method, CorrelationID: id, Err: err}
+	}
+	if key != "" {
+		rpc.cache.put(key, method, res)
+	}
+	return res, nil
+}
+
+// newRequest encodes payload into a pooled buffer and builds the http request
+// sending it. The buffer must only be returned to the pool once a response
+// was read, failed requests may still be read by the transport after Do returns.
+func (rpc *CCClient) newRequest(ctx context.Context, correlationID string, payload interface{}) (*http.Request, *bytes.Buffer, BinaryCodec, error) {
+	buf := getBuffer()
+	if err := rpc.codec.Encode(buf, payload); err != nil {
+		putBuffer(buf)
+		return nil, nil, nil, err
+	}
+	contentType := "application/json"
+	binary := rpc.wire.binary()
+	if binary != nil {
+		encoded, err := toBinary(binary, buf.Bytes())
+		if err != nil {
+			putBuffer(buf)
+			return nil, nil, nil, err
+		}
+		buf.Reset()
+		buf.Write(encoded)
+		contentType = binary.ContentType()
+	}
+	req, err := http.NewRequest("POST", rpc.endpoint(ctx), bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		putBuffer(buf)
+		return nil, nil, nil, err
+	}
+	setHeaders(ctx, req.Header, rpc.userAgent, rpc.header)
+	req.Header.Set("Content-Type", contentType)
+	if binary != nil {
+		req.Header.Set("Accept", contentType+", application/json")
+	}
+	req.Header.Set(correlationHeader, correlationID)
+	token, err := rpc.authToken(ctx)
+	if err != nil {
+		putBuffer(buf)
+		return nil, nil, nil, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return req.WithContext(ctx), buf, binary, nil
+}
+
+func (rpc *CCClient) doCall(ctx context.Context, correlationID, method string, params ...interface{}) (json.RawMessage, error) {
+	if rpc.debugLog == nil {
+		return rpc.roundTrip(ctx, nil, correlationID, method, params...)
+	}
+	trace := newCallTrace(correlationID, method, params)
+	res, err := rpc.roundTrip(ctx, trace, correlationID, method, params...)
+	rpc.debugLog.write(trace, err)
+	return res, err
+}
+
+// roundTrip sends the call and decodes its response, recording it with
+// trace if not nil
+func (rpc *CCClient) roundTrip(ctx context.Context, trace *callTrace, correlationID, method string, params ...interface{}) (json.RawMessage, error) {
 	request := rpcRequest{
 		ID:      1,
 		JSONRPC: rpc.versionJSONRPC,
 		Method:  method,
 		Params:  params,
 	}
-	body, err := json.Marshal(request)
+	req, buf, binary, err := rpc.newRequest(ctx, correlationID, request)
 	if err != nil {
 		return nil, err
 	}
-	response, err := rpc.client.Post(rpc.url, "application/json", bytes.NewBuffer(body))
+	body := buf.Bytes()
+	if trace != nil {
+		trace.record.RequestSize = len(body)
+	}
+	release, err := rpc.inflight.acquire(ctx)
+	if err != nil {
+		putBuffer(buf)
+		return nil, err
+	}
+	defer release()
+	response, err := rpc.client.Do(req)
 	if response != nil {
 		defer response.Body.Close()
 	}
 	if err != nil {
+		if rpc.endpoints != nil && ctx.Err() == nil {
+			rpc.endpoints.failed(req.URL.String())
+		}
 		return nil, err
 	}
-	data, err := ioutil.ReadAll(response.Body)
-	if err != nil {
+	if trace != nil {
+		trace.record.Status = response.StatusCode
+	}
+	if binary != nil && rpc.wire.refused(response) {
+		release()
+		return rpc.roundTrip(ctx, trace, correlationID, method, params...)
+	}
+	if err := authStatusError(response.StatusCode); err != nil {
+		return nil, err
+	}
+	if err := throttleStatusError(response); err != nil {
 		return nil, err
 	}
+	if rpc.maxResponseSize > 0 && response.ContentLength > rpc.maxResponseSize {
+		return nil, ErrResponseTooLarge
+	}
+	var r io.Reader = response.Body
+	if rpc.maxResponseSize > 0 {
+		r = &limitedReader{r: r, n: rpc.maxResponseSize}
+	}
+	var data bytes.Buffer
+	head := &prefixWriter{max: maxDecodeErrorPayload}
 	if rpc.Debug {
-		log.Println(fmt.Sprintf("%s\nRequest: %s, \nResponse: %s\n", method, body, data))
+		r = io.TeeReader(r, &data)
+	} else {
+		r = io.TeeReader(r, head)
+	}
+	if trace != nil {
+		r = io.TeeReader(r, trace.responseWriter())
+	}
+	if binary != nil && !isJSON(response.Header.Get("Content-Type")) {
+		decoded, err := fromBinary(binary, r)
+		if err != nil {
+			return nil, err
+		}
+		r = bytes.NewReader(decoded)
 	}
 	resp := new(rpcResponse)
-	if err := json.Unmarshal(data, resp); err != nil {
-		return nil, err
+	err = rpc.codec.Decode(r, resp)
+	if rpc.Debug {
+		log.Println(fmt.Sprintf("%s [%s]\nRequest: %s, \nResponse: %s\n", method, correlationID, body, data.Bytes()))
+	}
+	if err != nil {
+		if errors.Is(err, ErrResponseTooLarge) {
+			return nil, err
+		}
+		payload := head.buf
+		if rpc.Debug {
+			payload = data.Bytes()
+		}
+		return nil, &DecodeError{Method: method, Type: "JSON-RPC response", Payload: payload, Err: err}
 	}
+	putBuffer(buf)
 	if resp.Error != nil {
+		if isAuthError(*resp.Error) {
+			return nil, fmt.Errorf("%w: %v", ErrTokenExpired, *resp.Error)
+		}
 		return nil, *resp.Error
 	}
 	return resp.Result, nil
 }
 
+// Notify sends a JSON-RPC notification, a call without id the node sends no
+// result for, e.g. for telemetry. Only delivery failures are reported.
+func (rpc *CCClient) Notify(method string, params ...interface{}) error {
+	notification := struct {
+		JSONRPC string        `json:"jsonrpc"`
+		Method  string        `json:"method"`
+		Params  []interface{} `json:"params"`
+	}{rpc.versionJSONRPC, method, params}
+	id := newCorrelationID()
+	start := time.Now()
+	err := rpc.notify(id, notification)
+	rpc.audit(context.Background(), method, id, params, start, nil, err)
+	if err != nil {
+		return &CallError{Method: method, CorrelationID: id, Err: err}
+	}
+	return nil
+}
+
+func (rpc *CCClient) notify(correlationID string, notification interface{}) error {
+	req, buf, _, err := rpc.newRequest(context.Background(), correlationID, notification)
+	if err != nil {
+		return err
+	}
+	release, err := rpc.inflight.acquire(req.Context())
+	if err != nil {
+		putBuffer(buf)
+		return err
+	}
+	defer release()
+	response, err := rpc.client.Do(req)
+	if err != nil {
+		return err
+	}
+	io.Copy(ioutil.Discard, response.Body)
+	response.Body.Close()
+	putBuffer(buf)
+	if response.StatusCode >= 300 {
+		return fmt.Errorf("notification rejected: %s", response.Status)
+	}
+	return nil
+}
+
 // ACCOUNTS
 func (rpc *CCClient) CreateAccount(passphrase string) (string, error) {
-	res, err := rpc.call("accounts_createAccount", passphrase)
 	var account string
-	unErr := json.Unmarshal(res, &account)
-	fatalIfErr(unErr, fmt.Sprintf("The result is not of type \"%T\" \n", account))
+	err := rpc.callInto(context.Background(), &account, "accounts_createAccount", passphrase)
 	return account, err
 }
 
+// UnlockAccount unlocks the account and returns the session token. Accounts
+// protected with a second factor fail with ErrSecondFactorRequired, see
+// UnlockAccountWithFactor.
 func (rpc *CCClient) UnlockAccount(acc, passphrase string) (string, error) {
-	res, err := rpc.call("accounts_unlockAccount", acc, passphrase)
+	return rpc.unlockAccount(context.Background(), acc, passphrase)
+}
+
+func (rpc *CCClient) unlockAccount(ctx context.Context, acc, passphrase string) (string, error) {
 	var token string
-	unErr := json.Unmarshal(res, &token)
-	fatalIfErr(unErr, fmt.Sprintf("The result is not of type \"%T\" \n", token))
-	return token, err
+	err := rpc.callInto(ctx, &token, "accounts_unlockAccount", acc, passphrase)
+	return token, secondFactorError(err)
 }
 
 func (rpc *CCClient) LockAccount(account, token string) error {
-	rpc.client = oauth2.NewClient(context.Background(), oauth2.StaticTokenSource(&oauth2.Token{
-		TokenType:   "Bearer",
-		AccessToken: token,
-	}))
-	_, err := rpc.call("accounts_lockAccount", account)
+	_, err := rpc.callContext(tokenContext(token), "accounts_lockAccount", account)
 	return err
 }
 
+// DeleteAccount irreversibly deletes the account, see DeleteAccountChecked to
+// keep it from orphaning the records it owns
 func (rpc *CCClient) DeleteAccount(acc, passphrase string) error {
 	_, err := rpc.call("accounts_deleteAccount", acc, passphrase)
 	return err
 }
 
+// ListAccounts returns the addresses of the accounts of the node, see
+// ListAccountsDetails for their metadata
 func (rpc *CCClient) ListAccounts() ([]string, error) {
-	res, err := rpc.call("accounts_listAccounts")
-	var accounts []string
-	unErr := json.Unmarshal(res, &accounts)
-	fatalIfErr(unErr, fmt.Sprintf("The result is not of type \"%T\" \n", accounts))
-	return accounts, err
+	accounts, err := rpc.ListAccountsDetails()
+	if err != nil {
+		return nil, err
+	}
+	addresses := make([]string, len(accounts))
+	for i, a := range accounts {
+		addresses[i] = a.Address
+	}
+	return addresses, nil
 }
 
 // // BOOTNODES
 func (rpc *CCClient) GetBootnodes() ([]string, error) {
-	res, err := rpc.call("bootnodes_getBootnodes")
 	var bootnodes []string
-	unErr := json.Unmarshal(res, &bootnodes)
-	fatalIfErr(unErr, fmt.Sprintf("The result is not of type \"%T\" \n", bootnodes))
+	err := rpc.callInto(context.Background(), &bootnodes, "bootnodes_getBootnodes")
 	return bootnodes, err
 }
 
@@ -181,103 +589,46 @@ func (rpc *CCClient) RemoveSwarmService(serviceName string) error {
 
 // DISCOVER NODES
 func (rpc *CCClient) DiscoverNodes(num int) (string, error) {
-	res, err := rpc.call("discovery_discover", num)
 	var msg string
-	unErr := json.Unmarshal(res, &msg)
-	fatalIfErr(unErr, fmt.Sprintf("The result is not of type \"%T\" \n", msg))
+	err := rpc.callInto(context.Background(), &msg, "discovery_discover", num)
 	return msg, err
 }
 
 // DOCKER IMAGE MANAGER
 func (rpc *CCClient) LoadImageToNode(nodeID, imageHash, token string) (string, error) {
-	rpc.client = oauth2.NewClient(context.Background(), oauth2.StaticTokenSource(&oauth2.Token{
-		TokenType:   "Bearer",
-		AccessToken: token,
-	}))
-	res, err := rpc.call("imagemanager_pushImage", nodeID, imageHash)
-	var imgID string
-	unErr := json.Unmarshal(res, &imgID)
-	fatalIfErr(unErr, fmt.Sprintf("The result is not of type \"%T\" \n", imgID))
-	return imgID, err
+	return rpc.pushImage(tokenContext(token), nodeID, imageHash)
+}
+
+// ImageExists reports whether an image with the given hash is already stored
+// on the node, see HashFile to compute the hash of a local image tarball
+func (rpc *CCClient) ImageExists(imageHash string) (bool, error) {
+	var exists bool
+	err := rpc.callInto(context.Background(), &exists, "imagemanager_imageExists", imageHash)
+	return exists, err
 }
 
 func (rpc *CCClient) ExecuteImage(nodeID, dockImageID string) (string, error) {
-	res, err := rpc.call("imagemanager_runImage", nodeID, dockImageID)
 	var contID string
-	unErr := json.Unmarshal(res, &contID)
-	fatalIfErr(unErr, fmt.Sprintf("The result is not of type \"%T\" \n", contID))
+	err := rpc.callInto(context.Background(), &contID, "imagemanager_runImage", nodeID, dockImageID)
 	return contID, err
 }
 
 func (rpc *CCClient) InspectContainer(nodeID, containerID string) (string, error) {
-	res, err := rpc.call("imagemanager_inspectContainer", nodeID, containerID)
 	var inspect string
-	unErr := json.Unmarshal(res, &inspect)
-	fatalIfErr(unErr, fmt.Sprintf("The result is not of type \"%T\" \n", inspect))
+	err := rpc.callInto(context.Background(), &inspect, "imagemanager_inspectContainer", nodeID, containerID)
 	return inspect, err
 }
 
 func (rpc *CCClient) ListNodeImages(nodeID, token string) (string, error) {
-	rpc.client = oauth2.NewClient(context.Background(), oauth2.StaticTokenSource(&oauth2.Token{
-		TokenType:   "Bearer",
-		AccessToken: token,
-	}))
-	res, err := rpc.call("imagemanager_listImages", nodeID)
 	var list string
-	unErr := json.Unmarshal(res, &list)
-	fatalIfErr(unErr, fmt.Sprintf("The result is not of type \"%T\" \n", list))
+	err := rpc.callInto(tokenContext(token), &list, "imagemanager_listImages", nodeID)
 	return list, err
 }
 
+// ListNodeContainers returns the containers of the given node as the JSON
+// the node encodes them in, see ListContainers for typed and filtered ones
 func (rpc *CCClient) ListNodeContainers(nodeID, token string) (string, error) {
-	rpc.client = oauth2.NewClient(context.Background(), oauth2.StaticTokenSource(&oauth2.Token{
-		TokenType:   "Bearer",
-		AccessToken: token,
-	}))
-	res, err := rpc.call("imagemanager_listContainers", nodeID)
 	var list string
-	unErr := json.Unmarshal(res, &list)
-	fatalIfErr(unErr, fmt.Sprintf("The result is not of type \"%T\" \n", list))
+	err := rpc.callInto(tokenContext(token), &list, "imagemanager_listContainers", nodeID)
 	return list, err
 }
-
-// LEVEL DB
-func (rpc *CCClient) LvlDBStats() (string, error) {
-	res, err := rpc.call("lvldb_getDBStats")
-	var stats string
-	unErr := json.Unmarshal(res, &stats)
-	fatalIfErr(unErr, fmt.Sprintf("The result is not of type \"%T\" \n", stats))
-	return stats, err
-}
-
-func (rpc *CCClient) LvlDBSelectImage(imageID string) (string, error) {
-	res, err := rpc.call("lvldb_selectImage", imageID)
-	var image string
-	unErr := json.Unmarshal(res, &image)
-	fatalIfErr(unErr, fmt.Sprintf("The result is not of type \"%T\" \n", image))
-	return image, err
-}
-
-func (rpc *CCClient) LvlDBSelectImageAccount(imageHash string) (string, error) {
-	res, err := rpc.call("lvldb_selectImageAccount", imageHash)
-	var image string
-	unErr := json.Unmarshal(res, &image)
-	fatalIfErr(unErr, fmt.Sprintf("The result is not of type \"%T\" \n", image))
-	return image, err
-}
-
-func (rpc *CCClient) LvlDBSelectType(typeName string) (string, error) {
-	res, err := rpc.call("lvldb_selectType", typeName)
-	var all string
-	unErr := json.Unmarshal(res, &all)
-	fatalIfErr(unErr, fmt.Sprintf("The result is not of type \"%T\" \n", all))
-	return all, err
-}
-
-func (rpc *CCClient) LvlDBSelectAll() (string, error) {
-	res, err := rpc.call("lvldb_selectAll")
-	var all string
-	unErr := json.Unmarshal(res, &all)
-	fatalIfErr(unErr, fmt.Sprintf("The result is not of type \"%T\" \n", all))
-	return all, err
-}
@@ -0,0 +1,261 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+// Command ccgen generates typed CCClient wrappers from an OpenRPC document
+// describing the node's methods:
+//
+//	ccgen -spec node.openrpc.json -out zz_generated_rpc.go
+//
+// Every method becomes a CCClient method named after it, e.g.
+// imagemanager_runImage becomes ImagemanagerRunImage, and every schema under
+// components/schemas a struct. The output belongs in the SDK package as it
+// uses the client's unexported call helpers.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// spec is the subset of an OpenRPC document ccgen reads
+type spec struct {
+	Methods    []method `json:"methods"`
+	Components struct {
+		Schemas map[string]*schema `json:"schemas"`
+	} `json:"components"`
+}
+
+type method struct {
+	Name    string       `json:"name"`
+	Summary string       `json:"summary"`
+	Params  []descriptor `json:"params"`
+	Result  *descriptor  `json:"result"`
+}
+
+type descriptor struct {
+	Name     string  `json:"name"`
+	Required bool    `json:"required"`
+	Schema   *schema `json:"schema"`
+}
+
+type schema struct {
+	Ref         string             `json:"$ref"`
+	Type        string             `json:"type"`
+	Description string             `json:"description"`
+	Items       *schema            `json:"items"`
+	Properties  map[string]*schema `json:"properties"`
+	Required    []string           `json:"required"`
+}
+
+func main() {
+	specPath := flag.String("spec", "", "OpenRPC document to read")
+	out := flag.String("out", "", "file to write, stdout if empty")
+	pkg := flag.String("package", "ccgosdk", "package of the generated file")
+	flag.Parse()
+	if *specPath == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	data, err := ioutil.ReadFile(*specPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var s spec
+	if err := json.Unmarshal(data, &s); err != nil {
+		log.Fatalf("parsing %s: %v", *specPath, err)
+	}
+	src, err := generate(&s, *pkg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *out == "" {
+		os.Stdout.Write(src)
+		return
+	}
+	if err := ioutil.WriteFile(*out, src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// generate renders the Go source for s
+func generate(s *spec, pkg string) ([]byte, error) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by ccgen. DO NOT EDIT.\n\npackage %s\n\n", pkg)
+	b.WriteString("import (\n\t\"context\"\n\t\"encoding/json\"\n)\n\n")
+	b.WriteString("var _ json.RawMessage\n\n")
+
+	names := make([]string, 0, len(s.Components.Schemas))
+	for name := range s.Components.Schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		writeStruct(&b, goName(name), s.Components.Schemas[name])
+	}
+
+	methods := append([]method(nil), s.Methods...)
+	sort.Slice(methods, func(i, j int) bool { return methods[i].Name < methods[j].Name })
+	for _, m := range methods {
+		if err := writeMethod(&b, m); err != nil {
+			return nil, err
+		}
+	}
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated code: %v\n%s", err, b.Bytes())
+	}
+	return src, nil
+}
+
+func writeStruct(b *bytes.Buffer, name string, s *schema) {
+	writeDoc(b, name, s.Description)
+	if s.Type != "object" || len(s.Properties) == 0 {
+		fmt.Fprintf(b, "type %s %s\n\n", name, goType(s))
+		return
+	}
+	required := make(map[string]bool, len(s.Required))
+	for _, r := range s.Required {
+		required[r] = true
+	}
+	props := make([]string, 0, len(s.Properties))
+	for prop := range s.Properties {
+		props = append(props, prop)
+	}
+	sort.Strings(props)
+	fmt.Fprintf(b, "type %s struct {\n", name)
+	for _, prop := range props {
+		tag := prop
+		if !required[prop] {
+			tag += ",omitempty"
+		}
+		fmt.Fprintf(b, "\t%s %s `json:%q`\n", goName(prop), goType(s.Properties[prop]), tag)
+	}
+	b.WriteString("}\n\n")
+}
+
+func writeMethod(b *bytes.Buffer, m method) error {
+	if m.Name == "" {
+		return fmt.Errorf("method without name")
+	}
+	name := goName(m.Name)
+	params := make([]string, 0, len(m.Params))
+	args := make([]string, 0, len(m.Params))
+	for _, p := range m.Params {
+		arg := paramName(p.Name)
+		params = append(params, arg+" "+goType(p.Schema))
+		args = append(args, arg)
+	}
+	writeDoc(b, name, m.Summary)
+	callArgs := ""
+	if len(args) > 0 {
+		callArgs = ", " + strings.Join(args, ", ")
+	}
+	fmt.Fprintf(b, "func (rpc *CCClient) %s(ctx context.Context", name)
+	for _, p := range params {
+		b.WriteString(", " + p)
+	}
+	if m.Result == nil {
+		fmt.Fprintf(b, ") error {\n\t_, err := rpc.callContext(ctx, %q%s)\n\treturn err\n}\n\n", m.Name, callArgs)
+		return nil
+	}
+	result := goType(m.Result.Schema)
+	fmt.Fprintf(b, ") (%s, error) {\n\tvar result %s\n\terr := rpc.callInto(ctx, &result, %q%s)\n\treturn result, err\n}\n\n", result, result, m.Name, callArgs)
+	return nil
+}
+
+func writeDoc(b *bytes.Buffer, name, doc string) {
+	doc = strings.TrimSpace(doc)
+	if doc == "" {
+		return
+	}
+	for i, line := range strings.Split(doc, "\n") {
+		if i == 0 {
+			line = name + " " + line
+		}
+		fmt.Fprintf(b, "// %s\n", strings.TrimSpace(line))
+	}
+}
+
+// goType maps a JSON schema to a Go type
+func goType(s *schema) string {
+	if s == nil {
+		return "json.RawMessage"
+	}
+	if s.Ref != "" {
+		return goName(s.Ref[strings.LastIndex(s.Ref, "/")+1:])
+	}
+	switch s.Type {
+	case "string":
+		return "string"
+	case "integer":
+		return "int64"
+	case "number":
+		return "float64"
+	case "boolean":
+		return "bool"
+	case "array":
+		return "[]" + goType(s.Items)
+	case "object":
+		if len(s.Properties) == 0 {
+			return "map[string]interface{}"
+		}
+	}
+	return "json.RawMessage"
+}
+
+// goName turns names like imagemanager_runImage or image-id into exported
+// identifiers, ImagemanagerRunImage and ImageID
+func goName(name string) string {
+	parts := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	var b strings.Builder
+	for _, p := range parts {
+		if strings.EqualFold(p, "id") || strings.EqualFold(p, "url") {
+			b.WriteString(strings.ToUpper(p))
+			continue
+		}
+		r := []rune(p)
+		r[0] = unicode.ToUpper(r[0])
+		b.WriteString(string(r))
+	}
+	if b.Len() == 0 || unicode.IsDigit([]rune(b.String())[0]) {
+		return "X" + b.String()
+	}
+	return b.String()
+}
+
+// paramName turns a parameter name into an unexported identifier
+func paramName(name string) string {
+	r := []rune(goName(name))
+	r[0] = unicode.ToLower(r[0])
+	s := string(r)
+	switch s {
+	case "type", "func", "var", "range", "map", "chan", "interface", "select", "default", "go", "ctx", "rpc", "result", "err":
+		s += "Param"
+	}
+	return s
+}
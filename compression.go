@@ -0,0 +1,142 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/crowdcompute/cc-go-sdk/internal/zstd"
+)
+
+// Compression is the content encoding used for transfers to and from the node
+type Compression int
+
+const (
+	// CompressionNone transfers files as they are
+	CompressionNone Compression = iota
+	// CompressionGzip gzips uploads and asks for gzipped downloads, which
+	// pays off for sparse image tarballs
+	CompressionGzip
+	// CompressionZstd zstd-encodes uploads and asks for zstd or gzipped
+	// downloads. Encoding takes less CPU than gzip, which compresses text
+	// better. Uploads fall back to gzip for nodes that only accept that.
+	CompressionZstd
+)
+
+// encoding returns the content coding of c
+func (c Compression) encoding() string {
+	switch c {
+	case CompressionGzip:
+		return "gzip"
+	case CompressionZstd:
+		return "zstd"
+	}
+	return ""
+}
+
+// acceptEncoding returns the Accept-Encoding asking for downloads encoded
+// with c, or "" for plain ones
+func (c Compression) acceptEncoding() string {
+	if c == CompressionZstd {
+		return "zstd, gzip"
+	}
+	return c.encoding()
+}
+
+// compress returns a reader yielding r encoded with c, which must be closed
+// to release the encoder if it isn't read to the end
+func (c Compression) compress(r io.Reader) io.ReadCloser {
+	if c != CompressionGzip && c != CompressionZstd {
+		return ioutil.NopCloser(r)
+	}
+	pr, pw := io.Pipe()
+	go func() {
+		var enc io.WriteCloser
+		if c == CompressionZstd {
+			enc = zstd.NewWriter(pw)
+		} else {
+			enc = gzip.NewWriter(pw)
+		}
+		_, err := io.Copy(enc, r)
+		if cerr := enc.Close(); err == nil {
+			err = cerr
+		}
+		pw.CloseWithError(err)
+	}()
+	return pr
+}
+
+// acceptedEncodings returns the set of encodings listed in Accept-Encoding
+// header values, as bits indexed by Compression. Plain content is always
+// accepted.
+func acceptedEncodings(values []string) int32 {
+	accepts := int32(1 << CompressionNone)
+	for _, v := range values {
+		for _, coding := range strings.Split(v, ",") {
+			params := strings.Split(coding, ";")
+			if zeroQuality(params[1:]) {
+				continue
+			}
+			name := strings.TrimSpace(params[0])
+			for _, c := range []Compression{CompressionGzip, CompressionZstd} {
+				if name == "*" || strings.EqualFold(name, c.encoding()) {
+					accepts |= 1 << c
+				}
+			}
+		}
+	}
+	return accepts
+}
+
+// zeroQuality reports whether the parameters of a coding give it a quality
+// of 0, which refuses it
+func zeroQuality(params []string) bool {
+	for _, p := range params {
+		p = strings.TrimSpace(p)
+		if len(p) > 2 && strings.EqualFold(p[:2], "q=") {
+			q, err := strconv.ParseFloat(p[2:], 64)
+			return err == nil && q == 0
+		}
+	}
+	return false
+}
+
+// decodeBody returns the body of resp with its content encoding removed
+func decodeBody(resp *http.Response) (io.ReadCloser, error) {
+	var dec io.Reader
+	switch resp.Header.Get("Content-Encoding") {
+	case "gzip":
+		gz, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		dec = gz
+	case "zstd":
+		dec = zstd.NewReader(resp.Body)
+	default:
+		return resp.Body, nil
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{dec, resp.Body}, nil
+}
@@ -0,0 +1,194 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/crowdcompute/cc-go-sdk/internal/zstd"
+)
+
+// encodedUploads is a node storing uploads in the encodings it accepts
+type encodedUploads struct {
+	accepts map[string]bool
+	// listed is the Accept-Encoding of its answers
+	listed string
+
+	mu        sync.Mutex
+	encodings []string
+	files     [][]byte
+}
+
+func (n *encodedUploads) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	encoding := r.Header.Get("Content-Encoding")
+	n.mu.Lock()
+	n.encodings = append(n.encodings, encoding)
+	n.mu.Unlock()
+	if n.listed != "" {
+		w.Header().Set("Accept-Encoding", n.listed)
+	}
+	if encoding != "" && !n.accepts[encoding] {
+		http.Error(w, "unsupported encoding", http.StatusUnsupportedMediaType)
+		return
+	}
+	var body io.Reader = r.Body
+	switch encoding {
+	case "gzip":
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		body = gz
+	case "zstd":
+		body = zstd.NewReader(r.Body)
+	}
+	_, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	part, err := multipart.NewReader(body, params["boundary"]).NextPart()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	file, err := ioutil.ReadAll(part)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	n.mu.Lock()
+	n.files = append(n.files, file)
+	n.mu.Unlock()
+	sum := sha256.Sum256(file)
+	w.Write([]byte(hex.EncodeToString(sum[:])))
+}
+
+func TestUploadEncodingNegotiation(t *testing.T) {
+	content := append(bytes.Repeat([]byte("layer "), 1000), make([]byte, 64<<10)...)
+	path := filepath.Join(t.TempDir(), "image.tar")
+	if err := ioutil.WriteFile(path, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name        string
+		compression Compression
+		accepts     map[string]bool
+		listed      string
+		// want are the encodings of two uploads in a row
+		want []string
+	}{
+		{"zstd accepted", CompressionZstd, map[string]bool{"zstd": true, "gzip": true}, "", []string{"zstd", "zstd"}},
+		{"zstd refused for gzip", CompressionZstd, map[string]bool{"gzip": true}, "gzip", []string{"zstd", "gzip", "gzip"}},
+		{"zstd refused silently", CompressionZstd, map[string]bool{"gzip": true}, "", []string{"zstd", "", ""}},
+		{"encodings refused for plain", CompressionZstd, nil, "identity", []string{"zstd", "", ""}},
+		{"gzip refused", CompressionGzip, map[string]bool{"zstd": true}, "zstd", []string{"gzip", "", ""}},
+		{"zstd announced as not accepted", CompressionZstd, map[string]bool{"zstd": true, "gzip": true}, "gzip, zstd;q=0", []string{"zstd", "gzip"}},
+		{"plain", CompressionNone, nil, "", []string{"", ""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := &encodedUploads{accepts: tt.accepts, listed: tt.listed}
+			srv := httptest.NewServer(node)
+			defer srv.Close()
+			c := NewUploadClient(srv.URL)
+			c.Compression = tt.compression
+			for i := 0; i < 2; i++ {
+				if _, err := c.UploadFile(path, ""); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if !reflect.DeepEqual(node.encodings, tt.want) {
+				t.Errorf("uploads were encoded as %q, want %q", node.encodings, tt.want)
+			}
+			for _, file := range node.files {
+				if !bytes.Equal(file, content) {
+					t.Errorf("the node got %d bytes of different content", len(file))
+				}
+			}
+		})
+	}
+}
+
+func TestDownloadDecodes(t *testing.T) {
+	content := append(bytes.Repeat([]byte("artifact "), 1000), make([]byte, 64<<10)...)
+	sum := sha256.Sum256(content)
+	hash := hex.EncodeToString(sum[:])
+	tests := []struct {
+		compression Compression
+		// the node answers in the first encoding asked for that it knows
+		known []string
+		want  string
+	}{
+		{CompressionZstd, []string{"zstd", "gzip"}, "zstd"},
+		{CompressionZstd, []string{"gzip"}, "gzip"},
+		{CompressionGzip, []string{"zstd", "gzip"}, "gzip"},
+	}
+	for _, tt := range tests {
+		var served string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			accepted := acceptedEncodings(r.Header.Values("Accept-Encoding"))
+			served = ""
+			for _, enc := range tt.known {
+				if c := map[string]Compression{"gzip": CompressionGzip, "zstd": CompressionZstd}[enc]; accepted&(1<<c) != 0 {
+					served = enc
+					break
+				}
+			}
+			var buf bytes.Buffer
+			var enc io.WriteCloser = nopWriteCloser{&buf}
+			switch served {
+			case "gzip":
+				enc = gzip.NewWriter(&buf)
+			case "zstd":
+				enc = zstd.NewWriter(&buf)
+			}
+			enc.Write(content)
+			enc.Close()
+			if served != "" {
+				w.Header().Set("Content-Encoding", served)
+			}
+			w.Write(buf.Bytes())
+		}))
+		c := NewDownloadClient(srv.URL)
+		c.Compression = tt.compression
+		var got bytes.Buffer
+		if err := c.DownloadFile(hash, &got, ""); err != nil {
+			t.Errorf("%v: %v", tt.known, err)
+		} else if !bytes.Equal(got.Bytes(), content) {
+			t.Errorf("%v: downloaded %d bytes of different content", tt.known, got.Len())
+		}
+		if served != tt.want {
+			t.Errorf("%v: the node served %q, want %q", tt.known, served, tt.want)
+		}
+		srv.Close()
+	}
+}
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
@@ -0,0 +1,237 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// ContainerState is the runtime state of a container
+type ContainerState struct {
+	Status     string    `json:"Status"`
+	Running    bool      `json:"Running"`
+	Paused     bool      `json:"Paused"`
+	Restarting bool      `json:"Restarting"`
+	OOMKilled  bool      `json:"OOMKilled"`
+	Dead       bool      `json:"Dead"`
+	Pid        int       `json:"Pid"`
+	ExitCode   int       `json:"ExitCode"`
+	Error      string    `json:"Error"`
+	StartedAt  time.Time `json:"StartedAt"`
+	FinishedAt time.Time `json:"FinishedAt"`
+}
+
+// ContainerMount is a volume or bind mount of a container
+type ContainerMount struct {
+	Type        string `json:"Type"`
+	Name        string `json:"Name"`
+	Source      string `json:"Source"`
+	Destination string `json:"Destination"`
+	Mode        string `json:"Mode"`
+	RW          bool   `json:"RW"`
+}
+
+// ContainerResources are the resource limits a container was started with
+type ContainerResources struct {
+	NanoCPUs   int64 `json:"NanoCpus"`
+	CPUShares  int64 `json:"CpuShares"`
+	Memory     int64 `json:"Memory"`
+	MemorySwap int64 `json:"MemorySwap"`
+}
+
+// ContainerPortBinding is a host address a container port is published on
+type ContainerPortBinding struct {
+	HostIP   string `json:"HostIp"`
+	HostPort string `json:"HostPort"`
+}
+
+// ContainerNetwork is the configuration of a network a container is attached to
+type ContainerNetwork struct {
+	NetworkID  string `json:"NetworkID"`
+	IPAddress  string `json:"IPAddress"`
+	Gateway    string `json:"Gateway"`
+	MacAddress string `json:"MacAddress"`
+}
+
+// ContainerNetworkSettings are the network settings of a container
+type ContainerNetworkSettings struct {
+	IPAddress string                            `json:"IPAddress"`
+	Ports     map[string][]ContainerPortBinding `json:"Ports"`
+	Networks  map[string]ContainerNetwork       `json:"Networks"`
+}
+
+// ContainerDetails is the decoded docker inspect output of a container
+type ContainerDetails struct {
+	ID      string         `json:"Id"`
+	Name    string         `json:"Name"`
+	Image   string         `json:"Image"`
+	Created time.Time      `json:"Created"`
+	State   ContainerState `json:"State"`
+	Config  struct {
+		Image string   `json:"Image"`
+		Cmd   []string `json:"Cmd"`
+		Env   []string `json:"Env"`
+	} `json:"Config"`
+	Mounts          []ContainerMount         `json:"Mounts"`
+	HostConfig      ContainerResources       `json:"HostConfig"`
+	NetworkSettings ContainerNetworkSettings `json:"NetworkSettings"`
+
+	// Raw is the full inspect output, for fields not decoded above
+	Raw json.RawMessage `json:"-"`
+}
+
+// InspectContainerDetails returns the decoded inspect output of a container on the given node
+func (rpc *CCClient) InspectContainerDetails(nodeID, containerID string) (*ContainerDetails, error) {
+	return rpc.inspectContainerDetails(context.Background(), nodeID, containerID)
+}
+
+func (rpc *CCClient) inspectContainerDetails(ctx context.Context, nodeID, containerID string) (*ContainerDetails, error) {
+	var inspect string
+	if err := rpc.callInto(ctx, &inspect, "imagemanager_inspectContainer", nodeID, containerID); err != nil {
+		return nil, err
+	}
+	// an empty state would read as a container that exited successfully
+	if inspect == "" {
+		return nil, &DecodeError{Method: "imagemanager_inspectContainer", Type: "ContainerDetails", Err: errEmptyResult}
+	}
+	details := new(ContainerDetails)
+	if err := decodeJSON("imagemanager_inspectContainer", []byte(inspect), details); err != nil {
+		return nil, err
+	}
+	details.Raw = json.RawMessage(inspect)
+	return details, nil
+}
+
+// ContainerLogs returns the last tail lines of the output of a container on
+// the given node, or all of it if tail isn't positive
+func (rpc *CCClient) ContainerLogs(nodeID, containerID string, tail int) (string, error) {
+	var logs string
+	err := rpc.callInto(context.Background(), &logs, "imagemanager_containerLogs", nodeID, containerID, tail)
+	return logs, err
+}
+
+// ExecOptions configures a command run with ExecInContainer
+type ExecOptions struct {
+	Env        []string `json:"env,omitempty"`
+	WorkingDir string   `json:"workingDir,omitempty"`
+	User       string   `json:"user,omitempty"`
+}
+
+// ExecResult is the outcome of a command run inside a container
+type ExecResult struct {
+	ExitCode int    `json:"exitCode"`
+	Stdout   string `json:"stdout"`
+	Stderr   string `json:"stderr"`
+}
+
+// ExecInContainer runs cmd inside a running container on the given node,
+// waits for it to exit and returns its exit code and captured output
+func (rpc *CCClient) ExecInContainer(nodeID, containerID string, cmd []string, opts ExecOptions) (*ExecResult, error) {
+	res, err := rpc.callContext(context.Background(), "imagemanager_execContainer", nodeID, containerID, cmd, opts)
+	if err != nil {
+		return nil, err
+	}
+	result := new(ExecResult)
+	// an empty result would read as a command that exited successfully
+	if isNull(res) {
+		return nil, &DecodeError{Method: "imagemanager_execContainer", Type: decodeType(result), Payload: res, Err: errEmptyResult}
+	}
+	if err := decodeInto(rpc.codec.Unmarshal, "imagemanager_execContainer", res, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// containerPollInterval is how often WaitForContainer inspects the container
+const containerPollInterval = time.Second
+
+// StopContainer stops a running container on the given node
+func (rpc *CCClient) StopContainer(nodeID, containerID string) error {
+	_, err := rpc.call("imagemanager_stopContainer", nodeID, containerID)
+	return err
+}
+
+// RemoveContainer removes a stopped container from the given node
+func (rpc *CCClient) RemoveContainer(nodeID, containerID string) error {
+	_, err := rpc.call("imagemanager_removeContainer", nodeID, containerID)
+	return err
+}
+
+// WaitForContainer blocks until the container on the given node has exited
+// and returns its final state
+func (rpc *CCClient) WaitForContainer(ctx context.Context, nodeID, containerID string) (*ContainerState, error) {
+	var state *ContainerState
+	err := Poll(ctx, containerPollInterval, func(ctx context.Context) (bool, error) {
+		details, err := rpc.inspectContainerDetails(ctx, nodeID, containerID)
+		if err != nil {
+			return false, err
+		}
+		state = &details.State
+		return state.Exited(), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// ContainerEventType is the kind of a container lifecycle event
+type ContainerEventType string
+
+const (
+	ContainerCreated   ContainerEventType = "created"
+	ContainerStarted   ContainerEventType = "started"
+	ContainerDied      ContainerEventType = "died"
+	ContainerOOMKilled ContainerEventType = "oom"
+	ContainerRemoved   ContainerEventType = "removed"
+)
+
+// ContainerEvent is a lifecycle event of a container on a worker node
+type ContainerEvent struct {
+	Type        ContainerEventType `json:"type"`
+	NodeID      string             `json:"nodeID"`
+	ContainerID string             `json:"containerID"`
+	Image       string             `json:"image"`
+	// ExitCode is set for ContainerDied events
+	ExitCode int       `json:"exitCode"`
+	Time     time.Time `json:"time"`
+}
+
+// SubscribeContainerEvents delivers lifecycle events of the containers on the
+// given node, or on all nodes if nodeID is empty, as the node pushes them
+func (rpc *CCClient) SubscribeContainerEvents(ctx context.Context, nodeID string) (<-chan ContainerEvent, *Subscription, error) {
+	ch := make(chan ContainerEvent)
+	deliver := func(ctx context.Context, raw json.RawMessage) error {
+		var ev ContainerEvent
+		if err := json.Unmarshal(raw, &ev); err != nil {
+			return err
+		}
+		select {
+		case ch <- ev:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	sub, err := rpc.subscribeStream(ctx, deliver, func() { close(ch) }, "imagemanager", "containerEvents", nodeID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ch, sub, nil
+}
@@ -0,0 +1,164 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// attachFrame is a chunk of container output pushed by the node, or the
+// exit notification of the attached process
+type attachFrame struct {
+	Stream   string `json:"stream"`
+	Data     []byte `json:"data"`
+	ExitCode int    `json:"exitCode"`
+}
+
+// ContainerStream is an interactive session with a process in a container.
+// Stdout and Stderr must be read concurrently, like the pipes of an exec.Cmd,
+// as a stalled reader holds up both.
+type ContainerStream struct {
+	Stdin  io.WriteCloser
+	Stdout io.Reader
+	Stderr io.Reader
+
+	ws     *wsSubscription
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu       sync.Mutex
+	exitCode int
+	err      error
+}
+
+// AttachContainer attaches to the stdin, stdout and stderr of the main
+// process of a running container on the given node
+func (rpc *CCClient) AttachContainer(ctx context.Context, nodeID, containerID string) (*ContainerStream, error) {
+	return rpc.openContainerStream(ctx, "attach", nodeID, containerID)
+}
+
+// ExecInteractive starts cmd inside a running container on the given node and
+// returns a stream connected to its stdin, stdout and stderr
+func (rpc *CCClient) ExecInteractive(ctx context.Context, nodeID, containerID string, cmd []string, opts ExecOptions) (*ContainerStream, error) {
+	return rpc.openContainerStream(ctx, "exec", nodeID, containerID, cmd, opts)
+}
+
+func (rpc *CCClient) openContainerStream(ctx context.Context, kind string, params ...interface{}) (*ContainerStream, error) {
+	ws, err := rpc.wsSubscribe(ctx, "imagemanager", append([]interface{}{kind}, params...)...)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel, release := rpc.track(ctx)
+	stdoutR, stdoutW := io.Pipe()
+	stderrR, stderrW := io.Pipe()
+	s := &ContainerStream{
+		Stdin:    &containerStdin{ws: ws},
+		Stdout:   stdoutR,
+		Stderr:   stderrR,
+		ws:       ws,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+		exitCode: -1,
+	}
+	go func() {
+		<-ctx.Done()
+		ws.close()
+	}()
+	go func() {
+		err := s.read(stdoutW, stderrW)
+		if errors.Is(err, io.EOF) || ctx.Err() != nil {
+			err = nil
+		}
+		stdoutW.Close()
+		stderrW.Close()
+		s.mu.Lock()
+		s.err = err
+		s.mu.Unlock()
+		cancel()
+		close(s.done)
+		release()
+	}()
+	return s, nil
+}
+
+// read copies output frames to the pipes until the process exits
+func (s *ContainerStream) read(stdout, stderr io.Writer) error {
+	for {
+		raw, err := s.ws.next()
+		if err != nil {
+			return err
+		}
+		var frame attachFrame
+		if err := json.Unmarshal(raw, &frame); err != nil {
+			return err
+		}
+		switch frame.Stream {
+		case "stdout":
+			_, err = stdout.Write(frame.Data)
+		case "stderr":
+			_, err = stderr.Write(frame.Data)
+		case "exit":
+			s.mu.Lock()
+			s.exitCode = frame.ExitCode
+			s.mu.Unlock()
+			return io.EOF
+		default:
+			err = fmt.Errorf("unknown stream %q", frame.Stream)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Wait blocks until the process exits or the session ends and returns the
+// exit code of the process, -1 if it is not known
+func (s *ContainerStream) Wait() (int, error) {
+	<-s.done
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.exitCode, s.err
+}
+
+// Close ends the session, leaving the process running
+func (s *ContainerStream) Close() error {
+	s.cancel()
+	<-s.done
+	return nil
+}
+
+// containerStdin forwards writes to the stdin of the attached process
+type containerStdin struct {
+	ws *wsSubscription
+}
+
+func (w *containerStdin) Write(p []byte) (int, error) {
+	if err := w.ws.send("imagemanager_writeStdin", w.ws.id, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close sends EOF to the stdin of the attached process
+func (w *containerStdin) Close() error {
+	return w.ws.send("imagemanager_closeStdin", w.ws.id)
+}
@@ -0,0 +1,115 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path"
+)
+
+var (
+	// ErrNoUploadClient is returned when a method needs to upload files but
+	// the client was created without WithUploadClient
+	ErrNoUploadClient = errors.New("no upload client configured")
+	// ErrNoDownloadClient is returned when a method needs to download files but
+	// the client was created without WithDownloadClient
+	ErrNoDownloadClient = errors.New("no download client configured")
+)
+
+// CopyToContainer copies the local file or directory srcPath into the
+// directory dstPath of a container on the given node
+func (rpc *CCClient) CopyToContainer(nodeID, containerID, srcPath, dstPath string) error {
+	hash, err := rpc.uploadTar(context.Background(), srcPath)
+	if err != nil {
+		return err
+	}
+	_, err = rpc.call("imagemanager_copyToContainer", nodeID, containerID, hash, dstPath)
+	return err
+}
+
+// CopyFromContainer copies the file or directory srcPath out of a container
+// on the given node into the local directory dstPath
+func (rpc *CCClient) CopyFromContainer(nodeID, containerID, srcPath, dstPath string) error {
+	_, err := rpc.copyFromContainer(nodeID, containerID, srcPath, dstPath)
+	return err
+}
+
+// copyFromContainer copies srcPath out of the container like CopyFromContainer
+// and describes the archive the node stored it in
+func (rpc *CCClient) copyFromContainer(nodeID, containerID, srcPath, dstPath string) (*Artifact, error) {
+	if rpc.downloader == nil {
+		return nil, ErrNoDownloadClient
+	}
+	var hash string
+	if err := rpc.callInto(context.Background(), &hash, "imagemanager_copyFromContainer", nodeID, containerID, srcPath); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dstPath, 0755); err != nil {
+		return nil, err
+	}
+	token, err := rpc.authToken(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	pr, pw := io.Pipe()
+	counter := &countingWriter{}
+	go func() {
+		pw.CloseWithError(rpc.downloader.DownloadFile(hash, io.MultiWriter(pw, counter), token))
+	}()
+	err = extractTar(pr, dstPath)
+	// unblock the download if extraction stopped early
+	pr.CloseWithError(err)
+	if err != nil {
+		return nil, err
+	}
+	return &Artifact{
+		Name: path.Base(srcPath) + ".tar",
+		Size: counter.n,
+		Hash: hash,
+		URL:  rpc.downloader.FileURL(hash),
+	}, nil
+}
+
+// countingWriter counts the bytes written to it
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+// uploadTar uploads the local file or directory src as a tar archive
+// and returns its hash
+func (rpc *CCClient) uploadTar(ctx context.Context, src string) (string, error) {
+	if rpc.uploader == nil {
+		return "", ErrNoUploadClient
+	}
+	token, err := rpc.authToken(ctx)
+	if err != nil {
+		return "", err
+	}
+	result, err := rpc.uploader.UploadDirectoryContext(ctx, src, token)
+	if err != nil {
+		return "", err
+	}
+	return result.Hash, nil
+}
@@ -0,0 +1,96 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// exitedStatus matches the exit code in the status of exited containers,
+// e.g. "Exited (137) 2 minutes ago"
+var exitedStatus = regexp.MustCompile(`^Exited \((-?\d+)\)`)
+
+// ContainerSummary is a container of a node as listed by ListContainers
+type ContainerSummary struct {
+	ID      string    `json:"Id"`
+	Names   []string  `json:"Names"`
+	Image   string    `json:"Image"`
+	ImageID string    `json:"ImageID"`
+	Command string    `json:"Command"`
+	Created Timestamp `json:"Created"`
+	// State is the machine readable state, e.g. "running" or "exited",
+	// Status the human readable one, e.g. "Up 2 hours"
+	State  string `json:"State"`
+	Status string `json:"Status"`
+	// ExitCode is the exit code of exited containers
+	ExitCode int `json:"-"`
+}
+
+// Running reports whether the container is running
+func (c *ContainerSummary) Running() bool {
+	return c.State == "running"
+}
+
+// ContainerFilter selects the containers ListContainers returns. Zero
+// fields don't filter.
+type ContainerFilter struct {
+	// State matches the state of the containers, e.g. "running" or "exited"
+	State string
+	// Image matches the image name, full ID or hash of the containers
+	Image string
+	// Since selects the containers created at or after it
+	Since time.Time
+}
+
+// match reports whether c is selected by f
+func (f ContainerFilter) match(c *ContainerSummary) bool {
+	if f.State != "" && !strings.EqualFold(c.State, f.State) {
+		return false
+	}
+	if f.Image != "" && c.Image != f.Image && !sameHash(c.ImageID, f.Image) {
+		return false
+	}
+	return f.Since.IsZero() || !c.Created.Before(f.Since)
+}
+
+// ListContainers returns the containers of the given node selected by filter
+func (rpc *CCClient) ListContainers(nodeID string, filter ContainerFilter) ([]ContainerSummary, error) {
+	var list string
+	if err := rpc.callInto(context.Background(), &list, "imagemanager_listContainers", nodeID); err != nil {
+		return nil, err
+	}
+	var all []ContainerSummary
+	if list != "" {
+		if err := decodeJSON("imagemanager_listContainers", []byte(list), &all); err != nil {
+			return nil, err
+		}
+	}
+	containers := make([]ContainerSummary, 0, len(all))
+	for _, c := range all {
+		if m := exitedStatus.FindStringSubmatch(c.Status); m != nil {
+			c.ExitCode, _ = strconv.Atoi(m[1])
+		}
+		if filter.match(&c) {
+			containers = append(containers, c)
+		}
+	}
+	return containers, nil
+}
@@ -0,0 +1,170 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// statsInterval is how often a streamed ContainerStats samples the container,
+// matching the cadence of docker stats
+const statsInterval = time.Second
+
+// ContainerStats is a resource usage sample of a container.
+// Memory, network and block IO figures are in bytes.
+type ContainerStats struct {
+	Time          time.Time
+	CPUPercent    float64
+	MemoryUsage   uint64
+	MemoryLimit   uint64
+	MemoryPercent float64
+	NetworkRx     uint64
+	NetworkTx     uint64
+	BlockRead     uint64
+	BlockWrite    uint64
+}
+
+// dockerStats is the subset of docker's stats output used to compute ContainerStats
+type dockerStats struct {
+	Read     time.Time `json:"read"`
+	CPUStats struct {
+		CPUUsage struct {
+			TotalUsage  uint64   `json:"total_usage"`
+			PercpuUsage []uint64 `json:"percpu_usage"`
+		} `json:"cpu_usage"`
+		SystemUsage uint64 `json:"system_cpu_usage"`
+		OnlineCPUs  uint32 `json:"online_cpus"`
+	} `json:"cpu_stats"`
+	PreCPUStats struct {
+		CPUUsage struct {
+			TotalUsage uint64 `json:"total_usage"`
+		} `json:"cpu_usage"`
+		SystemUsage uint64 `json:"system_cpu_usage"`
+	} `json:"precpu_stats"`
+	MemoryStats struct {
+		Usage uint64            `json:"usage"`
+		Limit uint64            `json:"limit"`
+		Stats map[string]uint64 `json:"stats"`
+	} `json:"memory_stats"`
+	Networks map[string]struct {
+		RxBytes uint64 `json:"rx_bytes"`
+		TxBytes uint64 `json:"tx_bytes"`
+	} `json:"networks"`
+	BlkioStats struct {
+		IOServiceBytesRecursive []struct {
+			Op    string `json:"op"`
+			Value uint64 `json:"value"`
+		} `json:"io_service_bytes_recursive"`
+	} `json:"blkio_stats"`
+}
+
+// containerStats computes the figures shown by docker stats
+func (s *dockerStats) containerStats() ContainerStats {
+	stats := ContainerStats{Time: s.Read}
+
+	cpuDelta := float64(s.CPUStats.CPUUsage.TotalUsage) - float64(s.PreCPUStats.CPUUsage.TotalUsage)
+	systemDelta := float64(s.CPUStats.SystemUsage) - float64(s.PreCPUStats.SystemUsage)
+	cpus := float64(s.CPUStats.OnlineCPUs)
+	if cpus == 0 {
+		cpus = float64(len(s.CPUStats.CPUUsage.PercpuUsage))
+	}
+	if cpuDelta > 0 && systemDelta > 0 {
+		stats.CPUPercent = cpuDelta / systemDelta * cpus * 100
+	}
+
+	// page cache is reclaimable, docker doesn't count it as used memory
+	stats.MemoryUsage = s.MemoryStats.Usage
+	if cache := s.MemoryStats.Stats["cache"]; cache < stats.MemoryUsage {
+		stats.MemoryUsage -= cache
+	}
+	stats.MemoryLimit = s.MemoryStats.Limit
+	if stats.MemoryLimit > 0 {
+		stats.MemoryPercent = float64(stats.MemoryUsage) / float64(stats.MemoryLimit) * 100
+	}
+
+	for _, n := range s.Networks {
+		stats.NetworkRx += n.RxBytes
+		stats.NetworkTx += n.TxBytes
+	}
+	for _, entry := range s.BlkioStats.IOServiceBytesRecursive {
+		switch strings.ToLower(entry.Op) {
+		case "read":
+			stats.BlockRead += entry.Value
+		case "write":
+			stats.BlockWrite += entry.Value
+		}
+	}
+	return stats
+}
+
+func (rpc *CCClient) containerStats(ctx context.Context, nodeID, containerID string) (ContainerStats, error) {
+	var raw string
+	if err := rpc.callInto(ctx, &raw, "imagemanager_containerStats", nodeID, containerID); err != nil {
+		return ContainerStats{}, err
+	}
+	var s dockerStats
+	if err := decodeJSON("imagemanager_containerStats", []byte(raw), &s); err != nil {
+		return ContainerStats{}, err
+	}
+	return s.containerStats(), nil
+}
+
+// ContainerStats samples the resource usage of a container on the given node.
+// Without stream a single sample is delivered before the channel is closed,
+// otherwise a sample is delivered every second until the subscription ends.
+func (rpc *CCClient) ContainerStats(ctx context.Context, nodeID, containerID string, stream bool) (<-chan ContainerStats, *Subscription, error) {
+	first, err := rpc.containerStats(ctx, nodeID, containerID)
+	if err != nil {
+		return nil, nil, err
+	}
+	ch := make(chan ContainerStats, 1)
+	ch <- first
+	sub, ctx := rpc.newSubscription(ctx)
+	go func() {
+		var err error
+		if stream {
+			err = rpc.streamContainerStats(ctx, ch, nodeID, containerID)
+		}
+		if ctx.Err() != nil {
+			err = nil
+		}
+		close(ch)
+		sub.finish(err)
+	}()
+	return ch, sub, nil
+}
+
+func (rpc *CCClient) streamContainerStats(ctx context.Context, ch chan<- ContainerStats, nodeID, containerID string) error {
+	for {
+		select {
+		case <-time.After(statsInterval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		stats, err := rpc.containerStats(ctx, nodeID, containerID)
+		if err != nil {
+			return err
+		}
+		select {
+		case ch <- stats:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
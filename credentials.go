@@ -0,0 +1,83 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"errors"
+
+	"github.com/crowdcompute/cc-go-sdk/credentials"
+	"github.com/crowdcompute/cc-go-sdk/keyring"
+)
+
+// StorePassphrase stores the passphrase of account in kr, e.g. keyring.System()
+func StorePassphrase(kr keyring.Keyring, account, passphrase string) error {
+	return kr.Set(keyring.PassphraseKey(account), passphrase)
+}
+
+// UnlockAccountFromKeyring unlocks account with the passphrase stored in kr
+// and stores the session token there, for SessionFromKeyring
+func (rpc *CCClient) UnlockAccountFromKeyring(kr keyring.Keyring, account string) (Session, error) {
+	passphrase, err := kr.Get(keyring.PassphraseKey(account))
+	if err != nil {
+		return Session{}, err
+	}
+	token, err := rpc.UnlockAccount(account, passphrase)
+	if err != nil {
+		return Session{}, err
+	}
+	if err := kr.Set(keyring.TokenKey(account), token); err != nil {
+		return Session{}, err
+	}
+	return Session{Account: account, Token: token}, nil
+}
+
+// SessionFromKeyring returns the session of account stored in kr by
+// UnlockAccountFromKeyring. The token may have expired since.
+func SessionFromKeyring(kr keyring.Keyring, account string) (Session, error) {
+	token, err := kr.Get(keyring.TokenKey(account))
+	if err != nil {
+		return Session{}, err
+	}
+	return Session{Account: account, Token: token}, nil
+}
+
+// ForgetAccount removes the passphrase and session token of account from kr
+func ForgetAccount(kr keyring.Keyring, account string) error {
+	for _, key := range []string{keyring.PassphraseKey(account), keyring.TokenKey(account)} {
+		if err := kr.Delete(key); err != nil && !errors.Is(err, keyring.ErrNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+// WithCredentials returns a client authenticated with creds, e.g. from
+// credentials.Default. A passphrase is used to unlock the account if there
+// is no token.
+func (rpc *CCClient) WithCredentials(creds credentials.Credentials) (*CCClient, error) {
+	if creds.Token == "" {
+		if creds.Account == "" || creds.Passphrase == "" {
+			return nil, credentials.ErrNoCredentials
+		}
+		token, err := rpc.UnlockAccount(creds.Account, creds.Passphrase)
+		if err != nil {
+			return nil, err
+		}
+		creds.Token = token
+	}
+	return rpc.WithAccount(Session{Account: creds.Account, Token: creds.Token}), nil
+}
@@ -0,0 +1,225 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package credentials finds the account and token or passphrase a client
+// authenticates with, looking in order at explicitly given credentials, the
+// environment, the credentials file and the OS keyring.
+package credentials
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/crowdcompute/cc-go-sdk/keyring"
+	"gopkg.in/yaml.v2"
+)
+
+// Environment variables read by Env and the default File
+const (
+	EnvAccount    = "CC_ACCOUNT"
+	EnvToken      = "CC_TOKEN"
+	EnvPassphrase = "CC_PASSPHRASE"
+	// EnvFile overrides the path of the credentials file
+	EnvFile = "CC_CREDENTIALS_FILE"
+	// EnvProfile selects the profile of the credentials file
+	EnvProfile = "CC_PROFILE"
+)
+
+// DefaultProfile is the profile of the credentials file used by default
+const DefaultProfile = "default"
+
+// ErrNoCredentials is returned when a provider has no credentials
+var ErrNoCredentials = errors.New("credentials: none found")
+
+// Credentials authenticate an account with a session token or, to unlock
+// it, its passphrase
+type Credentials struct {
+	Account    string `yaml:"account"`
+	Token      string `yaml:"token"`
+	Passphrase string `yaml:"passphrase"`
+	// Source names the provider the credentials came from
+	Source string `yaml:"-"`
+}
+
+// usable reports whether c can authenticate
+func (c Credentials) usable() bool {
+	return c.Token != "" || (c.Account != "" && c.Passphrase != "")
+}
+
+// Provider retrieves credentials, failing with ErrNoCredentials if it has none
+type Provider interface {
+	Retrieve() (Credentials, error)
+}
+
+// Static provides explicitly given credentials
+type Static Credentials
+
+// Retrieve returns the credentials
+func (s Static) Retrieve() (Credentials, error) {
+	c := Credentials(s)
+	if !c.usable() {
+		return Credentials{}, ErrNoCredentials
+	}
+	c.Source = "static"
+	return c, nil
+}
+
+// Env provides the credentials set in CC_ACCOUNT, CC_TOKEN and CC_PASSPHRASE
+type Env struct{}
+
+// Retrieve reads the environment
+func (Env) Retrieve() (Credentials, error) {
+	c := Credentials{
+		Account:    os.Getenv(EnvAccount),
+		Token:      os.Getenv(EnvToken),
+		Passphrase: os.Getenv(EnvPassphrase),
+		Source:     "env",
+	}
+	if !c.usable() {
+		return Credentials{}, ErrNoCredentials
+	}
+	return c, nil
+}
+
+// File provides credentials from a YAML file of profiles:
+//
+//	default:
+//	  account: 0x...
+//	  token: ...
+type File struct {
+	// Path is the file, CC_CREDENTIALS_FILE or credentials.yaml in the
+	// cc-go-sdk directory of the user config directory by default
+	Path string
+	// Profile is the profile used, CC_PROFILE or DefaultProfile by default
+	Profile string
+}
+
+// DefaultFilePath returns the path of the credentials file used by default
+func DefaultFilePath() (string, error) {
+	if path := os.Getenv(EnvFile); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "cc-go-sdk", "credentials.yaml"), nil
+}
+
+// Retrieve reads the profile from the file
+func (f File) Retrieve() (Credentials, error) {
+	path := f.Path
+	if path == "" {
+		var err error
+		if path, err = DefaultFilePath(); err != nil {
+			return Credentials{}, err
+		}
+	}
+	profile := f.Profile
+	if profile == "" {
+		profile = os.Getenv(EnvProfile)
+	}
+	if profile == "" {
+		profile = DefaultProfile
+	}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return Credentials{}, ErrNoCredentials
+	} else if err != nil {
+		return Credentials{}, err
+	}
+	var profiles map[string]Credentials
+	if err := yaml.Unmarshal(data, &profiles); err != nil {
+		return Credentials{}, err
+	}
+	c, ok := profiles[profile]
+	if !ok || !c.usable() {
+		return Credentials{}, ErrNoCredentials
+	}
+	c.Source = "file:" + path + "#" + profile
+	return c, nil
+}
+
+// Keyring provides the token or passphrase of an account stored in a
+// keyring, see keyring.TokenKey and keyring.PassphraseKey
+type Keyring struct {
+	// Keyring is the keyring read, keyring.System() by default
+	Keyring keyring.Keyring
+	// Account is the account looked up, CC_ACCOUNT by default
+	Account string
+}
+
+// Retrieve reads the token and passphrase of the account
+func (k Keyring) Retrieve() (Credentials, error) {
+	kr := k.Keyring
+	if kr == nil {
+		kr = keyring.System()
+	}
+	account := k.Account
+	if account == "" {
+		account = os.Getenv(EnvAccount)
+	}
+	if account == "" {
+		return Credentials{}, ErrNoCredentials
+	}
+	c := Credentials{Account: account, Source: "keyring"}
+	var err error
+	if c.Token, err = secret(kr, keyring.TokenKey(account)); err != nil {
+		return Credentials{}, err
+	}
+	if c.Passphrase, err = secret(kr, keyring.PassphraseKey(account)); err != nil {
+		return Credentials{}, err
+	}
+	if !c.usable() {
+		return Credentials{}, ErrNoCredentials
+	}
+	return c, nil
+}
+
+// secret reads key from kr, a missing key or keyring is no error
+func secret(kr keyring.Keyring, key string) (string, error) {
+	s, err := kr.Get(key)
+	if errors.Is(err, keyring.ErrNotFound) || errors.Is(err, keyring.ErrUnsupported) {
+		return "", nil
+	}
+	return strings.TrimSpace(s), err
+}
+
+// Chain provides the credentials of the first of its providers having any
+type Chain []Provider
+
+// Retrieve asks the providers in order
+func (c Chain) Retrieve() (Credentials, error) {
+	for _, p := range c {
+		creds, err := p.Retrieve()
+		if err == nil {
+			return creds, nil
+		}
+		if !errors.Is(err, ErrNoCredentials) {
+			return Credentials{}, err
+		}
+	}
+	return Credentials{}, ErrNoCredentials
+}
+
+// Default returns the chain of explicit credentials, which may be empty,
+// the environment, the credentials file and the system keyring
+func Default(explicit Credentials) Chain {
+	return Chain{Static(explicit), Env{}, File{}, Keyring{}}
+}
@@ -0,0 +1,103 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// maxDebugBody bounds the request and response bodies of debug records
+const maxDebugBody = 1024
+
+// DebugRecord is a call written as a line of JSON by WithDebugJSON
+type DebugRecord struct {
+	Time          time.Time `json:"time"`
+	Method        string    `json:"method"`
+	CorrelationID string    `json:"correlationID"`
+	DurationMS    float64   `json:"durationMs"`
+	// Status is the http status of the response, zero if none arrived
+	Status       int   `json:"status,omitempty"`
+	RequestSize  int   `json:"requestSize"`
+	ResponseSize int64 `json:"responseSize"`
+	// Request holds the params with secrets redacted, Response the start
+	// of the response body, both truncated to 1KiB
+	Request  string `json:"request,omitempty"`
+	Response string `json:"response,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// debugLog writes debug records as JSON lines
+type debugLog struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// callTrace collects the debug record of a call while it is made
+type callTrace struct {
+	record   DebugRecord
+	start    time.Time
+	response prefixWriter
+	size     countingWriter
+}
+
+func newCallTrace(correlationID, method string, params []interface{}) *callTrace {
+	t := &callTrace{start: time.Now(), response: prefixWriter{max: maxDebugBody}}
+	t.record.Time = t.start
+	t.record.Method = method
+	t.record.CorrelationID = correlationID
+	if encoded, err := json.Marshal(redactParams(method, params)); err == nil {
+		t.record.Request = truncateDebug(encoded)
+	}
+	return t
+}
+
+// responseWriter returns the writer the response body is copied to
+func (t *callTrace) responseWriter() io.Writer {
+	return io.MultiWriter(&t.response, &t.size)
+}
+
+// truncateDebug returns b as string, cut to maxDebugBody bytes
+func truncateDebug(b []byte) string {
+	if len(b) > maxDebugBody {
+		return string(b[:maxDebugBody]) + "..."
+	}
+	return string(b)
+}
+
+func (l *debugLog) write(t *callTrace, err error) {
+	r := t.record
+	r.DurationMS = float64(time.Since(t.start)) / float64(time.Millisecond)
+	r.ResponseSize = t.size.n
+	if auditSecretResults[r.Method] && err == nil {
+		r.Response = auditRedacted
+	} else {
+		r.Response = truncateDebug(t.response.buf)
+	}
+	if err != nil {
+		r.Error = err.Error()
+	}
+	line, merr := json.Marshal(r)
+	if merr != nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.w.Write(append(line, '\n'))
+}
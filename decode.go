@@ -0,0 +1,123 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// errEmptyResult is the error of a DecodeError for null results of methods
+// that must return a value
+var errEmptyResult = errors.New("empty result")
+
+// maxDecodeErrorPayload bounds the payload quoted in DecodeError messages
+const maxDecodeErrorPayload = 256
+
+// DecodeError is returned when a node's response can't be decoded into the
+// expected type, e.g. because of a wrong type or truncated JSON
+type DecodeError struct {
+	Method string
+	// Type is the Go type the result was decoded into
+	Type string
+	// Payload is the offending response
+	Payload []byte
+	Err     error
+}
+
+func (e *DecodeError) Error() string {
+	payload := e.Payload
+	suffix := ""
+	if len(payload) > maxDecodeErrorPayload {
+		payload, suffix = payload[:maxDecodeErrorPayload], "..."
+	}
+	return fmt.Sprintf("decoding %s result into %s: %v (payload: %s%s)", e.Method, e.Type, e.Err, payload, suffix)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// decodeInto decodes data into result with unmarshal, turning failures and
+// panics of the decoder into a *DecodeError. Null or missing results, which
+// nodes return e.g. for empty lists, set structs, slices, maps and pointers
+// to their zero value, see the IsEmpty methods of such results. Scalars such
+// as tokens and ids must be present, a null one is a *DecodeError wrapping
+// errEmptyResult.
+func decodeInto(unmarshal func([]byte, interface{}) error, method string, data []byte, result interface{}) (err error) {
+	if isNull(data) {
+		v := reflect.ValueOf(result)
+		if v.Kind() != reflect.Ptr || v.IsNil() {
+			return nil
+		}
+		switch v.Elem().Kind() {
+		case reflect.Struct, reflect.Slice, reflect.Map, reflect.Ptr, reflect.Interface:
+			v.Elem().Set(reflect.Zero(v.Elem().Type()))
+			return nil
+		}
+		return &DecodeError{Method: method, Type: decodeType(result), Payload: data, Err: errEmptyResult}
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("decoder panic: %v", p)
+		}
+		if err != nil {
+			err = &DecodeError{Method: method, Type: decodeType(result), Payload: data, Err: err}
+		}
+	}()
+	return unmarshal(data, result)
+}
+
+// isNull reports whether data is an empty or null JSON value
+func isNull(data []byte) bool {
+	trimmed := bytes.TrimSpace(data)
+	return len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null"))
+}
+
+// decodeType names the type pointed to by result
+func decodeType(result interface{}) string {
+	t := reflect.TypeOf(result)
+	if t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return fmt.Sprint(t)
+}
+
+// decodeJSON is decodeInto for payloads that are JSON regardless of codec,
+// e.g. docker output the node passes on as a string
+func decodeJSON(method string, data []byte, result interface{}) error {
+	return decodeInto(json.Unmarshal, method, data, result)
+}
+
+// prefixWriter keeps the first max bytes written to it
+type prefixWriter struct {
+	buf []byte
+	max int
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	if room := w.max - len(w.buf); room > 0 {
+		if len(p) < room {
+			room = len(p)
+		}
+		w.buf = append(w.buf, p[:room]...)
+	}
+	return len(p), nil
+}
@@ -0,0 +1,352 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata/golden")
+
+// goldenCases decode the node responses in testdata/responses, named after
+// the case, with the client methods receiving them
+var goldenCases = []struct {
+	name string
+	call func(rpc *CCClient) (interface{}, error)
+}{
+	{"accounts_listAccounts", func(rpc *CCClient) (interface{}, error) { return rpc.ListAccountsDetails() }},
+	{"accounts_unlockAccount", func(rpc *CCClient) (interface{}, error) { return rpc.UnlockAccount("0x3e2c", "secret") }},
+	{"accounts_unlockAccount_null", func(rpc *CCClient) (interface{}, error) { return rpc.UnlockAccount("0x3e2c", "secret") }},
+	{"accounts_unlockAccount_error", func(rpc *CCClient) (interface{}, error) { return rpc.UnlockAccount("0x3e2c", "wrong") }},
+	{"node_getResources", func(rpc *CCClient) (interface{}, error) { return rpc.GetNodeResources("QmWorker1") }},
+	{"lvldb_selectAll", func(rpc *CCClient) (interface{}, error) { return rpc.LvlDBSelectAll() }},
+	{"imagemanager_inspectContainer", func(rpc *CCClient) (interface{}, error) {
+		return rpc.InspectContainerDetails("QmWorker1", "b81e4f2a9c3d")
+	}},
+	{"imagemanager_execContainer", func(rpc *CCClient) (interface{}, error) {
+		return rpc.ExecInContainer("QmWorker1", "b81e4f2a9c3d", []string{"ls", "/missing"}, ExecOptions{})
+	}},
+	{"imagemanager_execContainer_null", func(rpc *CCClient) (interface{}, error) {
+		return rpc.ExecInContainer("QmWorker1", "b81e4f2a9c3d", []string{"true"}, ExecOptions{})
+	}},
+	{"imagemanager_listUploads_null", func(rpc *CCClient) (interface{}, error) { return rpc.ListUploads("0x3e2c", "") }},
+	{"acl_list_null", func(rpc *CCClient) (interface{}, error) { return rpc.GetNodeACL("QmWorker1") }},
+	{"node_cleanup_null", func(rpc *CCClient) (interface{}, error) {
+		return rpc.CleanupNode("QmWorker1", CleanupPolicy{})
+	}},
+}
+
+// goldenError describes err without its random correlation id
+func goldenError(err error) string {
+	var derr *DecodeError
+	var rerr rpcError
+	switch {
+	case err == nil:
+		return ""
+	case errors.As(err, &derr):
+		return "decoding " + derr.Type + ": " + derr.Err.Error()
+	case errors.As(err, &rerr):
+		return rerr.Error()
+	}
+	return err.Error()
+}
+
+// inUTC converts the times reachable from v to UTC in place. Timestamps
+// given as unix seconds are decoded in the local zone.
+func inUTC(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() {
+			inUTC(v.Elem())
+		}
+	case reflect.Interface:
+		if v.IsNil() || !v.CanSet() {
+			return
+		}
+		elem := reflect.New(v.Elem().Type()).Elem()
+		elem.Set(v.Elem())
+		inUTC(elem)
+		v.Set(elem)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			inUTC(v.Index(i))
+		}
+	case reflect.Map:
+		for _, key := range v.MapKeys() {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			elem.Set(v.MapIndex(key))
+			inUTC(elem)
+			v.SetMapIndex(key, elem)
+		}
+	case reflect.Struct:
+		if t, ok := v.Interface().(time.Time); ok {
+			if v.CanSet() {
+				v.Set(reflect.ValueOf(t.UTC()))
+			}
+			return
+		}
+		for i := 0; i < v.NumField(); i++ {
+			if v.Field(i).CanSet() {
+				inUTC(v.Field(i))
+			}
+		}
+	}
+}
+
+// checkGolden compares got to the golden file of name, rewriting it with -update
+func checkGolden(t *testing.T, name string, got interface{}) {
+	t.Helper()
+	utc := reflect.New(reflect.TypeOf(got)).Elem()
+	utc.Set(reflect.ValueOf(got))
+	inUTC(utc)
+	data, err := json.MarshalIndent(utc.Interface(), "", "\t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = append(data, '\n')
+	path := filepath.Join("testdata", "golden", name+".golden")
+	if *update {
+		if err := ioutil.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v, run go test -update to create it", err)
+	}
+	if !bytes.Equal(data, want) {
+		t.Errorf("%s differs from %s:\n%s", name, path, data)
+	}
+}
+
+func TestDecodeGolden(t *testing.T) {
+	for _, tc := range goldenCases {
+		t.Run(tc.name, func(t *testing.T) {
+			body, err := ioutil.ReadFile(filepath.Join("testdata", "responses", tc.name+".json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.Write(body)
+			}))
+			defer srv.Close()
+			rpc := NewCCClient(srv.URL, WithThrottleRetries(0, 0))
+			result, err := tc.call(rpc)
+			checkGolden(t, tc.name, struct {
+				Result interface{} `json:"result"`
+				Error  string      `json:"error,omitempty"`
+			}{result, goldenError(err)})
+		})
+	}
+}
+
+func TestNullResultsAreEmpty(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":null}`))
+	}))
+	defer srv.Close()
+	rpc := NewCCClient(srv.URL, WithThrottleRetries(0, 0))
+	acl, err := rpc.GetNodeACL("QmWorker1")
+	if err != nil || !acl.IsEmpty() {
+		t.Errorf("GetNodeACL of a null result = %+v, %v, want an empty list", acl, err)
+	}
+	report, err := rpc.CleanupNode("QmWorker1", CleanupPolicy{})
+	if err != nil || !report.IsEmpty() {
+		t.Errorf("CleanupNode of a null result = %+v, %v, want an empty report", report, err)
+	}
+	if token, err := rpc.UnlockAccount("0x3e2c", "secret"); !errors.Is(err, errEmptyResult) {
+		t.Errorf("UnlockAccount of a null result = %q, %v, want %v", token, err, errEmptyResult)
+	}
+}
+
+// uploadCases are upload answers in testdata/responses with their status
+var uploadCases = []struct {
+	name   string
+	status int
+}{
+	{"upload_json", http.StatusOK},
+	{"upload_bare", http.StatusOK},
+	{"upload_error", http.StatusRequestEntityTooLarge},
+}
+
+func TestParseUploadResponseGolden(t *testing.T) {
+	for _, tc := range uploadCases {
+		t.Run(tc.name, func(t *testing.T) {
+			body, err := ioutil.ReadFile(filepath.Join("testdata", "responses", tc.name+".txt"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			result, err := parseUploadResponse(tc.status, body)
+			errMsg := ""
+			if err != nil {
+				errMsg = err.Error()
+			}
+			checkGolden(t, tc.name, struct {
+				Result *UploadResult `json:"result"`
+				Error  string        `json:"error,omitempty"`
+			}{result, errMsg})
+		})
+	}
+}
+
+// addResultSeeds adds the results of the recorded node responses to f
+func addResultSeeds(f *testing.F) {
+	f.Helper()
+	files, _ := filepath.Glob(filepath.Join("testdata", "responses", "*.json"))
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			f.Fatal(err)
+		}
+		var resp rpcResponse
+		if json.Unmarshal(data, &resp) == nil && resp.Result != nil {
+			f.Add([]byte(resp.Result))
+		}
+	}
+	f.Add([]byte("null"))
+	f.Add([]byte(""))
+}
+
+func FuzzDecodeInto(f *testing.F) {
+	addResultSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		results := []interface{}{
+			new(string), new(bool), new(NodeResources), new(ExecResult),
+			new([]AccountDetails), new([]RawRecord), new(map[string]json.RawMessage), new(*ImageRecord),
+		}
+		for _, result := range results {
+			err := decodeInto(json.Unmarshal, "fuzz_method", data, result)
+			var derr *DecodeError
+			if err != nil && !errors.As(err, &derr) {
+				t.Fatalf("decoding into %T: %v is not a *DecodeError", result, err)
+			}
+			if err == nil && isNull(data) {
+				if _, required := result.(*string); required {
+					t.Fatalf("null decoded into %T without error", result)
+				}
+			}
+		}
+	})
+}
+
+func FuzzParseUploadResponse(f *testing.F) {
+	for _, tc := range uploadCases {
+		body, err := ioutil.ReadFile(filepath.Join("testdata", "responses", tc.name+".txt"))
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(tc.status, body)
+	}
+	f.Add(http.StatusOK, []byte(""))
+	f.Add(http.StatusOK, []byte(`{"hash":""}`))
+	f.Fuzz(func(t *testing.T, status int, body []byte) {
+		result, err := parseUploadResponse(status, body)
+		if (result == nil) == (err == nil) {
+			t.Fatalf("got result %v and error %v", result, err)
+		}
+		if err != nil {
+			var uerr *UploadError
+			if !errors.As(err, &uerr) || uerr.StatusCode != status {
+				t.Fatalf("error %v is not an *UploadError with status %d", err, status)
+			}
+			return
+		}
+		if status != http.StatusOK || result.Hash == "" {
+			t.Fatalf("accepted upload with status %d and hash %q", status, result.Hash)
+		}
+	})
+}
+
+func FuzzAccountDetailsUnmarshalJSON(f *testing.F) {
+	f.Add([]byte(`"0x3e2c0a14b6a4f4e0a9c1f2b0d7e5c8a9b1d2e3f4"`))
+	f.Add([]byte(`{"address":"0x8f1d","createdAt":1571817600,"unlocked":true}`))
+	f.Add([]byte(`{"address":"0xa1b2","createdAt":"2019-11-02T14:30:00Z"}`))
+	f.Add([]byte(`null`))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var details AccountDetails
+		if err := json.Unmarshal(data, &details); err != nil {
+			return
+		}
+		var address string
+		if json.Unmarshal(data, &address) == nil && details.Address != address {
+			t.Fatalf("bare address %q decoded as %q", address, details.Address)
+		}
+	})
+}
+
+func FuzzTimestampUnmarshalJSON(f *testing.F) {
+	f.Add([]byte(`1571817600`))
+	f.Add([]byte(`"2019-11-02T14:30:00Z"`))
+	f.Add([]byte(`null`))
+	f.Add([]byte(`-1`))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var ts Timestamp
+		if err := ts.UnmarshalJSON(data); err != nil {
+			return
+		}
+		if secs, err := strconv.ParseInt(string(data), 10, 64); err == nil && ts.Unix() != secs {
+			t.Fatalf("%s decoded as %d", data, ts.Unix())
+		}
+	})
+}
+
+func FuzzRPCResponse(f *testing.F) {
+	files, _ := filepath.Glob(filepath.Join("testdata", "responses", "*.json"))
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(data)
+	}
+	f.Add([]byte(`{"jsonrpc":"2.0","id":1`))
+	f.Fuzz(func(t *testing.T, body []byte) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(body)
+		}))
+		defer srv.Close()
+		rpc := NewCCClient(srv.URL, WithThrottleRetries(0, 0))
+		var result json.RawMessage
+		err := rpc.callInto(context.Background(), &result, "fuzz_method")
+		if err == nil {
+			return
+		}
+		var (
+			derr *DecodeError
+			rerr rpcError
+		)
+		if !errors.As(err, &derr) && !errors.As(err, &rerr) && !errors.Is(err, ErrTokenExpired) {
+			t.Fatalf("unexpected error type: %v", err)
+		}
+	})
+}
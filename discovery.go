@@ -0,0 +1,253 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+)
+
+const (
+	// minDiscoveryPollInterval is the polling interval used right after the node pool changed
+	minDiscoveryPollInterval = 2 * time.Second
+	// maxDiscoveryPollInterval bounds the interval the poller backs off to while nothing changes
+	maxDiscoveryPollInterval = 30 * time.Second
+)
+
+// NodeInfo describes a worker node known to the network
+type NodeInfo struct {
+	ID    string   `json:"id"`
+	Addrs []string `json:"addrs"`
+	// GPUs are the graphics cards the node advertises
+	GPUs      []GPU          `json:"gpus,omitempty"`
+	Resources *NodeResources `json:"resources,omitempty"`
+	// OS and Arch are the platform of the node, e.g. "linux" and "arm64"
+	OS   string `json:"os,omitempty"`
+	Arch string `json:"arch,omitempty"`
+}
+
+// Platform returns the platform of the node, from its resources if it
+// doesn't advertise one itself
+func (n *NodeInfo) Platform() ImagePlatform {
+	if n.OS == "" && n.Arch == "" && n.Resources != nil {
+		return n.Resources.Platform()
+	}
+	return ImagePlatform{OS: n.OS, Architecture: normalizeArch(n.Arch)}
+}
+
+// HasGPU reports whether the node advertises at least one GPU
+func (n *NodeInfo) HasGPU() bool {
+	return len(n.GPUs) > 0 || (n.Resources != nil && len(n.Resources.GPUs) > 0)
+}
+
+// NodeEventType tells whether a node joined or left the network
+type NodeEventType string
+
+const (
+	NodeJoined NodeEventType = "joined"
+	NodeLeft   NodeEventType = "left"
+)
+
+// NodeEvent is delivered by SubscribeDiscovery when the node pool changes
+type NodeEvent struct {
+	Type NodeEventType `json:"type"`
+	Node NodeInfo      `json:"node"`
+}
+
+// NodeFilter restricts discovery to workers with the given capabilities.
+// Zero values are not used for filtering.
+type NodeFilter struct {
+	// MinMemory is the minimum total RAM in bytes
+	MinMemory uint64 `json:"minMemory,omitempty"`
+	// RequiresGPU only matches workers with at least one GPU
+	RequiresGPU bool `json:"requiresGPU,omitempty"`
+	// GPUs only matches workers able to serve the GPU request
+	GPUs *GPURequest `json:"gpus,omitempty"`
+	// OS and Arch match the worker platform, e.g. "linux" and "amd64"
+	OS   string `json:"os,omitempty"`
+	Arch string `json:"arch,omitempty"`
+	// MaxPrice is the highest price per CPU-hour a worker may ask
+	MaxPrice float64 `json:"maxPrice,omitempty"`
+}
+
+// DiscoverNodesWithFilter asks the node to find up to num workers matching filter
+func (rpc *CCClient) DiscoverNodesWithFilter(num int, filter NodeFilter) ([]NodeInfo, error) {
+	return rpc.discoverNodesWithFilter(context.Background(), num, filter)
+}
+
+func (rpc *CCClient) discoverNodesWithFilter(ctx context.Context, num int, filter NodeFilter) ([]NodeInfo, error) {
+	var nodes []NodeInfo
+	if err := rpc.callInto(ctx, &nodes, "discovery_discoverWithFilter", num, filter); err != nil {
+		return nil, err
+	}
+	return nodes, nil
+}
+
+// ListNodes returns the worker nodes currently known to the node
+func (rpc *CCClient) ListNodes() ([]NodeInfo, error) {
+	return rpc.listNodes(context.Background())
+}
+
+func (rpc *CCClient) listNodes(ctx context.Context) ([]NodeInfo, error) {
+	var nodes []NodeInfo
+	if err := rpc.callInto(ctx, &nodes, "discovery_listNodes"); err != nil {
+		return nil, err
+	}
+	return nodes, nil
+}
+
+// SubscribeDiscovery delivers a NodeJoined event for every currently known
+// worker and then an event whenever a worker joins or leaves the network.
+// Events are pushed by the node over the first subscription transport that
+// reaches it; when none does the node pool is polled, backing off while it
+// is stable.
+func (rpc *CCClient) SubscribeDiscovery(ctx context.Context) (<-chan NodeEvent, *Subscription, error) {
+	// workers joining or leaving while reconnecting are caught up with
+	var pool *nodePool
+	resync := func(ctx context.Context) error {
+		nodes, err := rpc.listNodes(ctx)
+		if err == nil {
+			_, err = pool.sync(nodes)
+		}
+		return err
+	}
+	src, srcErr := rpc.subscribeReconnecting(ctx, resync, "discovery", "nodes")
+	if srcErr != nil && rpc.Debug {
+		log.Printf("discovery subscription unavailable, polling instead: %v\n", srcErr)
+	}
+	nodes, err := rpc.listNodes(ctx)
+	if err != nil {
+		if src != nil {
+			src.close()
+		}
+		return nil, nil, err
+	}
+	ch := make(chan NodeEvent)
+	sub, ctx := rpc.newSubscription(ctx)
+	go func() {
+		pool = &nodePool{ctx: ctx, ch: ch, known: make(map[string]NodeInfo)}
+		var err error
+		if _, err = pool.sync(nodes); err == nil {
+			if src != nil {
+				err = pool.stream(src)
+			} else {
+				err = pool.poll(rpc)
+			}
+		}
+		if ctx.Err() != nil {
+			err = nil
+		}
+		close(ch)
+		sub.finish(err)
+	}()
+	return ch, sub, nil
+}
+
+// nodePool tracks the known workers of a discovery subscription so that
+// only actual changes are delivered
+type nodePool struct {
+	ctx   context.Context
+	ch    chan<- NodeEvent
+	known map[string]NodeInfo
+}
+
+// apply delivers ev unless it doesn't change the known workers and reports
+// whether it was delivered
+func (p *nodePool) apply(ev NodeEvent) (bool, error) {
+	_, known := p.known[ev.Node.ID]
+	switch {
+	case ev.Type == NodeJoined && !known:
+		p.known[ev.Node.ID] = ev.Node
+	case ev.Type == NodeLeft && known:
+		delete(p.known, ev.Node.ID)
+	default:
+		return false, nil
+	}
+	select {
+	case p.ch <- ev:
+		return true, nil
+	case <-p.ctx.Done():
+		return false, p.ctx.Err()
+	}
+}
+
+// sync delivers the difference between the known workers and nodes and
+// reports whether anything changed
+func (p *nodePool) sync(nodes []NodeInfo) (bool, error) {
+	changed := false
+	current := make(map[string]bool, len(nodes))
+	for _, n := range nodes {
+		current[n.ID] = true
+		sent, err := p.apply(NodeEvent{Type: NodeJoined, Node: n})
+		if err != nil {
+			return changed, err
+		}
+		changed = changed || sent
+	}
+	for id, n := range p.known {
+		if !current[id] {
+			sent, err := p.apply(NodeEvent{Type: NodeLeft, Node: n})
+			if err != nil {
+				return changed, err
+			}
+			changed = changed || sent
+		}
+	}
+	return changed, nil
+}
+
+func (p *nodePool) stream(src eventSource) error {
+	go func() {
+		<-p.ctx.Done()
+		src.close()
+	}()
+	for {
+		raw, err := src.next()
+		if err != nil {
+			return err
+		}
+		var ev NodeEvent
+		if err := json.Unmarshal(raw, &ev); err != nil {
+			return err
+		}
+		if _, err := p.apply(ev); err != nil {
+			return err
+		}
+	}
+}
+
+func (p *nodePool) poll(rpc *CCClient) error {
+	poller := newPoller(minDiscoveryPollInterval, PollBackoff(2, maxDiscoveryPollInterval))
+	for {
+		if err := poller.wait(p.ctx); err != nil {
+			return err
+		}
+		nodes, err := rpc.listNodes(p.ctx)
+		if err != nil {
+			return err
+		}
+		changed, err := p.sync(nodes)
+		if err != nil {
+			return err
+		}
+		if changed {
+			poller.reset()
+		}
+	}
+}
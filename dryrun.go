@@ -0,0 +1,75 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"context"
+	"os"
+)
+
+// RunPlan is what the node would do for a run, as validated without
+// starting a container
+type RunPlan struct {
+	NodeID  string `json:"nodeID"`
+	ImageID string `json:"imageID"`
+	// Valid reports whether the run would start, Problems says why not
+	Valid    bool     `json:"valid"`
+	Problems []string `json:"problems,omitempty"`
+	Warnings []string `json:"warnings,omitempty"`
+	// Resources are those of the node the run would be placed on
+	Resources *NodeResources `json:"resources,omitempty"`
+}
+
+// ValidateRun asks the node to validate the image, resource request and
+// placement of a run like ExecuteImageWithOptions, without starting a
+// container. image is an image id or the hash of an uploaded image.
+func (rpc *CCClient) ValidateRun(nodeID, image string, opts RunOptions) (*RunPlan, error) {
+	return rpc.validateRun(context.Background(), nodeID, image, opts)
+}
+
+func (rpc *CCClient) validateRun(ctx context.Context, nodeID, image string, opts RunOptions) (*RunPlan, error) {
+	plan := new(RunPlan)
+	if err := rpc.callInto(ctx, plan, "imagemanager_validateRun", nodeID, image, opts); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// dryRunJob places the job and validates it on the chosen worker
+func (rpc *CCClient) dryRunJob(ctx context.Context, spec JobSpec) (*JobResult, error) {
+	nodeID := spec.NodeID
+	if nodeID == "" {
+		var err error
+		if nodeID, err = rpc.pickNode(ctx, spec.Filter, nil); err != nil {
+			return nil, err
+		}
+	}
+	plan, err := rpc.validateRun(ctx, nodeID, spec.ImageHash, spec.Options)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, &NodeError{NodeID: nodeID, Err: err}
+	}
+	for _, in := range spec.Inputs {
+		if _, err := os.Stat(in.Source); err != nil {
+			plan.Valid = false
+			plan.Problems = append(plan.Problems, err.Error())
+		}
+	}
+	return &JobResult{NodeID: nodeID, Plan: plan}, nil
+}
@@ -0,0 +1,295 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultDownloadRetries    = 3
+	defaultDownloadRetryDelay = time.Second
+)
+
+type DownloadClient struct {
+	url    string
+	client *http.Client
+	Debug  bool
+	// Compression is the encoding asked for, downloads are decoded transparently
+	Compression Compression
+	// Retries is the number of times an interrupted download is resumed,
+	// 3 by default and none if negative
+	Retries int
+	// RetryDelay is the wait before the first retry, doubling with every
+	// further attempt, 1s by default
+	RetryDelay time.Duration
+}
+
+// NewDownloadClient creates new download client with given url
+func NewDownloadClient(url string) *DownloadClient {
+	rpc := &DownloadClient{
+		url:    url,
+		client: http.DefaultClient,
+	}
+	return rpc
+}
+
+// FileURL returns the url the file stored under hash is downloaded from
+func (c *DownloadClient) FileURL(hash string) string {
+	return strings.TrimSuffix(c.url, "/") + "/" + hash
+}
+
+// DownloadError is returned when the node refuses a download
+type DownloadError struct {
+	Hash       string
+	StatusCode int
+	Message    string
+}
+
+func (e *DownloadError) Error() string {
+	return fmt.Sprintf("download of %s failed (%d): %s", e.Hash, e.StatusCode, e.Message)
+}
+
+// temporary reports whether retrying the download may succeed
+func (e *DownloadError) temporary() bool {
+	return e.StatusCode >= 500 || e.StatusCode == http.StatusTooManyRequests
+}
+
+// DownloadFile writes the file stored on the node under hash to w. Interrupted
+// transfers are resumed where they stopped, and the content is verified
+// against hash once complete, returning a *ChecksumError on mismatch.
+func (c *DownloadClient) DownloadFile(hash string, w io.Writer, token string) error {
+	return c.DownloadFileContext(context.Background(), hash, w, token)
+}
+
+// DownloadFileContext is DownloadFile aborting the download and its retries
+// when ctx is done, in which case ctx.Err() is returned
+func (c *DownloadClient) DownloadFileContext(ctx context.Context, hash string, w io.Writer, token string) error {
+	return c.download(ctx, hash, w, sha256.New(), 0, token, nil, nil)
+}
+
+// ArtifactInfo describes a file downloaded with DownloadArtifact
+type ArtifactInfo struct {
+	Hash        string
+	Size        int64
+	ContentType string
+	// Filename is the name the node stored the file under, if it says
+	Filename string
+}
+
+// DownloadArtifact streams the file stored on the node under hash to w like
+// DownloadFile, verifying its hash as it arrives, and returns its metadata.
+// onProgress, if not nil, is called with the bytes written so far and the
+// size of the file, -1 while unknown. A corrupted download fails with an
+// error wrapping ErrChecksumMismatch.
+func (c *DownloadClient) DownloadArtifact(hash string, w io.Writer, token string, onProgress func(done, total int64)) (*ArtifactInfo, error) {
+	return c.DownloadArtifactContext(context.Background(), hash, w, token, onProgress)
+}
+
+// DownloadArtifactContext is DownloadArtifact aborting the download and its
+// retries when ctx is done, in which case ctx.Err() is returned
+func (c *DownloadClient) DownloadArtifactContext(ctx context.Context, hash string, w io.Writer, token string, onProgress func(done, total int64)) (*ArtifactInfo, error) {
+	info := &ArtifactInfo{Hash: hash, Size: -1}
+	if err := c.download(ctx, hash, w, sha256.New(), 0, token, info, onProgress); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// DownloadToFile downloads the file stored under hash to path. If path holds
+// the beginning of the file from an earlier, interrupted call the download is
+// resumed after it.
+func (c *DownloadClient) DownloadToFile(hash, path, token string) error {
+	return c.DownloadToFileContext(context.Background(), hash, path, token)
+}
+
+// DownloadToFileContext is DownloadToFile aborting the download and its
+// retries when ctx is done, in which case ctx.Err() is returned
+func (c *DownloadClient) DownloadToFileContext(ctx context.Context, hash, path, token string) error {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	sum := sha256.New()
+	offset, err := io.Copy(sum, f)
+	if err == nil {
+		err = c.download(ctx, hash, f, sum, offset, token, nil, nil)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// download fetches the file from offset on, retrying transient failures until
+// ctx is done, and checks the sum of all of its content. info, if not nil, is filled in from
+// the answers of the node, and onProgress, if not nil, is called as the file
+// is written.
+func (c *DownloadClient) download(ctx context.Context, want string, w io.Writer, sum hash.Hash, offset int64, token string, info *ArtifactInfo, onProgress func(done, total int64)) error {
+	retries := c.Retries
+	if retries == 0 {
+		retries = defaultDownloadRetries
+	}
+	delay := c.RetryDelay
+	if delay <= 0 {
+		delay = defaultDownloadRetryDelay
+	}
+
+	counter := &countingWriter{n: offset}
+	out := io.MultiWriter(w, sum, counter)
+	if onProgress != nil {
+		out = io.MultiWriter(out, progressFunc(func() {
+			total := int64(-1)
+			if info != nil {
+				total = info.Size
+			}
+			onProgress(counter.n, total)
+		}))
+	}
+	for attempt := 0; ; attempt++ {
+		err := c.fetch(ctx, want, out, counter.n, token, info)
+		if err == nil {
+			break
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		var derr *DownloadError
+		if (errors.As(err, &derr) && !derr.temporary()) || attempt >= retries {
+			return err
+		}
+		wait := delay << uint(attempt)
+		var terr *ThrottledError
+		if errors.As(err, &terr) && terr.RetryAfter > 0 {
+			if terr.RetryAfter > DefaultMaxThrottleWait {
+				return err
+			}
+			wait = terr.RetryAfter
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+	if local := hex.EncodeToString(sum.Sum(nil)); !sameHash(local, want) {
+		return &ChecksumError{Name: want, Expected: want, Actual: local}
+	}
+	if info != nil {
+		info.Size = counter.n
+	}
+	return nil
+}
+
+// progressFunc is a writer calling a function after every write
+type progressFunc func()
+
+func (f progressFunc) Write(p []byte) (int, error) {
+	f()
+	return len(p), nil
+}
+
+// describe fills in info from the headers of the node's answer
+func (info *ArtifactInfo) describe(resp *http.Response) {
+	if ct := resp.Header.Get("Content-Type"); ct != "" {
+		info.ContentType = ct
+	}
+	if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil && params["filename"] != "" {
+		info.Filename = params["filename"]
+	}
+	if cr := resp.Header.Get("Content-Range"); resp.StatusCode == http.StatusPartialContent && cr != "" {
+		// bytes <first>-<last>/<size>
+		if i := strings.LastIndexByte(cr, '/'); i >= 0 {
+			if size, err := strconv.ParseInt(cr[i+1:], 10, 64); err == nil {
+				info.Size = size
+			}
+		}
+		return
+	}
+	if resp.ContentLength >= 0 && resp.Header.Get("Content-Encoding") == "" && resp.StatusCode == http.StatusOK {
+		info.Size = resp.ContentLength
+	}
+}
+
+// fetch writes the file from offset on to w, describing it in info if not nil
+func (c *DownloadClient) fetch(ctx context.Context, hash string, w io.Writer, offset int64, token string, info *ArtifactInfo) error {
+	req, err := http.NewRequest("GET", c.FileURL(hash), nil)
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if offset > 0 {
+		// ranges apply to the encoded content, so resumed downloads are plain
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	} else if c.Compression != CompressionNone {
+		req.Header.Set("Accept-Encoding", c.Compression.acceptEncoding())
+	}
+	resp, err := c.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0 {
+		// everything was written already, the checksum tells if it is the file
+		return nil
+	}
+	if err := throttleStatusError(resp); err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK && !(resp.StatusCode == http.StatusPartialContent && offset > 0) {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return &DownloadError{Hash: hash, StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(msg))}
+	}
+	if info != nil {
+		info.describe(resp)
+	}
+	body, err := decodeBody(resp)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode == http.StatusOK && offset > 0 {
+		// the node ignored the range, skip what was already written
+		if _, err := io.CopyN(ioutil.Discard, body, offset); err != nil {
+			return err
+		}
+	}
+	_, err = io.Copy(w, body)
+	return err
+}
+
+// Close closes the idle connections of the client
+func (c *DownloadClient) Close() error {
+	c.client.CloseIdleConnections()
+	return nil
+}
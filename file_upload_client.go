@@ -19,66 +19,536 @@ package ccgosdk
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"mime/multipart"
 	"net/http"
 	"os"
-
-	"golang.org/x/oauth2"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
 )
 
 type UploadClient struct {
 	url    string
 	client *http.Client
 	Debug  bool
+	// Compression encodes uploads. Nodes that refuse the encoding are sent
+	// uploads in one they accept from then on, see compression.
+	Compression Compression
+	// accepts is the set of encodings the node accepts as bits indexed by
+	// Compression, 0 until it said
+	accepts int32
+	// chunking uploads large files in chunks, see WithChunkedUploads. It is
+	// off once unchunked is set after the node refused a chunked upload.
+	chunking  *chunking
+	unchunked int32
+	// unbatched is set once the node refused a batch upload
+	unbatched int32
+	userAgent string
+	header    http.Header
+	// inflight is set if the number of outstanding requests is bounded,
+	// shared with the CCClient the upload client is set on
+	inflight *inflightLimiter
+}
+
+// UploadResult describes a file stored by the node
+type UploadResult struct {
+	// Hash is the hex encoded sha256 the file is stored under
+	Hash     string `json:"hash"`
+	Size     int64  `json:"size"`
+	Filename string `json:"filename"`
+	// CID is the IPFS content id the file was published under, see WithIPFS
+	CID string `json:"cid,omitempty"`
+}
+
+// UploadError is returned when the node rejects an upload
+type UploadError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *UploadError) Error() string {
+	return fmt.Sprintf("upload failed (%d): %s", e.StatusCode, e.Message)
 }
 
-// New create new rpc client with given url
-func NewUploadClient(url string) *UploadClient {
-	rpc := &UploadClient{
+// NewUploadClient creates new upload client with given url
+func NewUploadClient(url string, opts ...UploadOption) *UploadClient {
+	c := &UploadClient{
 		url:    url,
 		client: http.DefaultClient,
 	}
-	return rpc
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
 }
 
-func (c *UploadClient) UploadFile(filename, token string) (string, error) {
-	c.client = oauth2.NewClient(context.Background(), oauth2.StaticTokenSource(&oauth2.Token{
-		TokenType:   "Bearer",
-		AccessToken: token,
-	}))
+// transport returns the transport of a copy of c.client for options to
+// modify, so that clients passed in or shared aren't changed
+func (c *UploadClient) transport() *http.Transport {
+	client := *c.client
+	t, ok := client.Transport.(*http.Transport)
+	if !ok {
+		t = http.DefaultTransport.(*http.Transport)
+	}
+	t = t.Clone()
+	client.Transport = t
+	c.client = &client
+	return t
+}
 
-	bodyBuf := &bytes.Buffer{}
-	bodyWriter := multipart.NewWriter(bodyBuf)
+// limited returns a copy of c whose requests take slots of l
+func (c *UploadClient) limited(l *inflightLimiter) *UploadClient {
+	limited := *c
+	limited.inflight = l
+	return &limited
+}
 
-	fileWriter, err := bodyWriter.CreateFormFile("file", filename)
+// do sends req once a slot of the in-flight limit is free, which is held
+// until the response body is closed
+func (c *UploadClient) do(ctx context.Context, req *http.Request) (*http.Response, error) {
+	release, err := c.inflight.acquire(ctx)
 	if err != nil {
-		fmt.Println("error writing to buffer")
-		return "", err
+		return nil, err
+	}
+	resp, err := c.client.Do(req.WithContext(ctx))
+	if err != nil {
+		release()
+		return nil, err
 	}
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+// releasingBody frees the in-flight slot of a response once closed
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	defer b.release()
+	return b.ReadCloser.Close()
+}
+
+// UploadFile uploads the file to the node and returns the hash it is stored
+// under. A *ChecksumError is returned if that isn't the sha256 of the file,
+// an *UploadError if the node rejected the upload.
+func (c *UploadClient) UploadFile(filename, token string) (*UploadResult, error) {
+	return c.UploadFileContext(context.Background(), filename, token)
+}
+
+// UploadFileContext is UploadFile aborting the upload when ctx is done, in
+// which case ctx.Err() is returned
+func (c *UploadClient) UploadFileContext(ctx context.Context, filename, token string) (*UploadResult, error) {
 	fh, err := os.Open(filename)
 	if err != nil {
 		fmt.Println("error opening file")
-		return "", err
+		return nil, err
 	}
 	defer fh.Close()
-
-	_, err = io.Copy(fileWriter, fh)
+	info, err := fh.Stat()
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	if c.chunking != nil && info.Size() >= c.chunking.threshold && atomic.LoadInt32(&c.unchunked) == 0 {
+		return c.UploadFileChunked(ctx, filename, token, c.chunking.opts)
 	}
-	contentType := bodyWriter.FormDataContentType()
+	used := c.compression()
+	result, err := c.UploadReaderContext(ctx, filename, fh, info.Size(), token)
+	// unlike streams, files can simply be resent after the node refused them
+	for attempt := 0; defaultThrottlePolicy.wait(ctx, err, attempt); attempt++ {
+		if _, err := fh.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		result, err = c.UploadReaderContext(ctx, filename, fh, info.Size(), token)
+	}
+	// the node doesn't accept the encoding, zstd falls back to gzip and
+	// then to a plain upload
+	for tries := 0; tries < 2 && refusedEncoding(err) && c.compression() != used; tries++ {
+		used = c.compression()
+		if _, err := fh.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		result, err = c.UploadReaderContext(ctx, filename, fh, info.Size(), token)
+	}
+	return result, err
+}
+
+// compression returns the encoding of the next upload, the preferred one
+// unless the node said it only accepts others
+func (c *UploadClient) compression() Compression {
+	accepts := atomic.LoadInt32(&c.accepts)
+	switch {
+	case accepts == 0 || accepts&(1<<c.Compression) != 0:
+		return c.Compression
+	case c.Compression == CompressionZstd && accepts&(1<<CompressionGzip) != 0:
+		return CompressionGzip
+	}
+	return CompressionNone
+}
+
+// noteEncodings records the encodings the node accepts from its answer to an
+// upload encoded with used. Nodes list them in Accept-Encoding, those
+// refusing an encoding without saying which they take get plain uploads.
+func (c *UploadClient) noteEncodings(resp *http.Response, used Compression) {
+	listed := resp.Header.Values("Accept-Encoding")
+	refused := resp.StatusCode == http.StatusUnsupportedMediaType && used != CompressionNone
+	if len(listed) == 0 && !refused {
+		return
+	}
+	accepts := acceptedEncodings(listed)
+	if refused {
+		accepts &^= 1 << used
+	}
+	atomic.StoreInt32(&c.accepts, accepts)
+}
+
+// refusedEncoding reports whether err is the node refusing the encoding of
+// an upload
+func refusedEncoding(err error) bool {
+	var uerr *UploadError
+	return errors.As(err, &uerr) && uerr.StatusCode == http.StatusUnsupportedMediaType
+}
+
+// UploadReader uploads the contents of r as a file called name, streaming it
+// without buffering so pipes and in-memory artifacts need no temp file.
+// size is the number of bytes r yields, or -1 if unknown.
+func (c *UploadClient) UploadReader(name string, r io.Reader, size int64, token string) (*UploadResult, error) {
+	return c.UploadReaderContext(context.Background(), name, r, size, token)
+}
+
+// UploadReaderContext is UploadReader aborting the upload when ctx is done,
+// in which case ctx.Err() is returned
+func (c *UploadClient) UploadReaderContext(ctx context.Context, name string, r io.Reader, size int64, token string) (*UploadResult, error) {
+	// the multipart framing is rendered up front so the file itself can be
+	// streamed between it. It is copied out of the scratch buffer since an
+	// aborted request may still be read after returning.
+	framing := getBuffer()
+	bodyWriter := multipart.NewWriter(framing)
+	if _, err := bodyWriter.CreateFormFile("file", name); err != nil {
+		putBuffer(framing)
+		fmt.Println("error writing to buffer")
+		return nil, err
+	}
+	split := framing.Len()
 	bodyWriter.Close()
-	resp, err := c.client.Post(c.url, contentType, bodyBuf)
+	frame := append([]byte(nil), framing.Bytes()...)
+	putBuffer(framing)
+	head, tail := frame[:split], frame[split:]
+
+	hash := sha256.New()
+	counter := new(countingWriter)
+	var body io.Reader = io.MultiReader(bytes.NewReader(head), io.TeeReader(r, io.MultiWriter(hash, counter)), bytes.NewReader(tail))
+	compression := c.compression()
+	encoded := compression.compress(body)
+	defer encoded.Close()
+	req, err := http.NewRequest("POST", c.url, encoded)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	setHeaders(ctx, req.Header, c.userAgent, c.header)
+	req.Header.Set("Content-Type", bodyWriter.FormDataContentType())
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	req.ContentLength = -1
+	if compression != CompressionNone {
+		req.Header.Set("Content-Encoding", compression.encoding())
+	} else if size >= 0 {
+		req.ContentLength = int64(len(head)) + size + int64(len(tail))
+	}
+	resp, err := c.do(ctx, req)
+	if err != nil {
+		// report cancellation as such rather than as the *url.Error it caused
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
 	}
 	defer resp.Body.Close()
 	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+	c.noteEncodings(resp, compression)
+	if err := throttleStatusError(resp); err != nil {
+		return nil, err
+	}
+	result, err := parseUploadResponse(resp.StatusCode, respBody)
+	if err != nil {
+		return nil, err
+	}
+	if result.Filename == "" {
+		result.Filename = name
+	}
+	if result.Size == 0 {
+		result.Size = counter.n
+	}
+	// the node answers with the hash of the stored file, which must match
+	// what was sent so that a corrupted upload is never pushed to workers
+	local := hex.EncodeToString(hash.Sum(nil))
+	if !sameHash(local, result.Hash) {
+		return nil, &ChecksumError{Name: name, Expected: local, Actual: result.Hash}
+	}
+	return result, nil
+}
+
+// parseUploadResponse decodes the node's answer to an upload, either a JSON
+// UploadResult or, from older nodes, the bare hash
+func parseUploadResponse(status int, body []byte) (*UploadResult, error) {
+	text := strings.TrimSpace(string(body))
+	var payload struct {
+		UploadResult
+		Error string `json:"error"`
+	}
+	isJSON := json.Unmarshal(body, &payload) == nil
+	if status != http.StatusOK {
+		if isJSON && payload.Error != "" {
+			text = payload.Error
+		}
+		return nil, &UploadError{StatusCode: status, Message: text}
+	}
+	if !isJSON {
+		if text == "" || strings.ContainsAny(text, " \t\n") {
+			return nil, &UploadError{StatusCode: status, Message: text}
+		}
+		return &UploadResult{Hash: text}, nil
+	}
+	if payload.Error != "" || payload.Hash == "" {
+		return nil, &UploadError{StatusCode: status, Message: payload.Error}
+	}
+	return &payload.UploadResult, nil
+}
+
+// UploadDirectory tars the directory dir on the fly and uploads it as a
+// single artifact. Entries are stored
+// under the base name of dir, a single file is archived the same way.
+func (c *UploadClient) UploadDirectory(dir, token string) (*UploadResult, error) {
+	return c.UploadDirectoryContext(context.Background(), dir, token)
+}
+
+// UploadDirectoryContext is UploadDirectory aborting the upload when ctx is
+// done, in which case ctx.Err() is returned
+func (c *UploadClient) UploadDirectoryContext(ctx context.Context, dir, token string) (*UploadResult, error) {
+	dir = filepath.Clean(dir)
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(writeTar(pw, dir, filepath.Dir(dir)))
+	}()
+	result, err := c.UploadReaderContext(ctx, archiveName(dir)+".tar", pr, -1, token)
+	pr.CloseWithError(err)
+	return result, err
+}
+
+// defaultArchiveName names the archive of a directory without a base name,
+// like the root directory
+const defaultArchiveName = "upload"
+
+// archiveName returns the base name of the absolute path of dir
+func archiveName(dir string) string {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return defaultArchiveName
+	}
+	name := filepath.Base(filepath.Clean(abs))
+	if name == "." || strings.Trim(name, `/\`) == "" {
+		return defaultArchiveName
+	}
+	return name
+}
+
+// errBatchUnsupported is returned by nodes storing one file per upload
+var errBatchUnsupported = errors.New("node doesn't accept batch uploads")
+
+// UploadFiles uploads the given files in a single multipart request and
+// returns their hashes by path, along with the error of the first file the
+// node didn't store. Nodes storing one file per upload are sent the files one
+// by one, as are all following batches, stopping at the first failure.
+func (c *UploadClient) UploadFiles(token string, paths ...string) (map[string]string, error) {
+	return c.UploadFilesContext(context.Background(), token, paths...)
+}
+
+// UploadFilesContext is UploadFiles aborting the upload when ctx is done, in
+// which case ctx.Err() is returned
+func (c *UploadClient) UploadFilesContext(ctx context.Context, token string, paths ...string) (map[string]string, error) {
+	hashes := make(map[string]string, len(paths))
+	if len(paths) > 1 && atomic.LoadInt32(&c.unbatched) == 0 {
+		used := c.compression()
+		results, err := c.uploadBatch(ctx, token, paths)
+		for attempt := 0; defaultThrottlePolicy.wait(ctx, err, attempt); attempt++ {
+			results, err = c.uploadBatch(ctx, token, paths)
+		}
+		// the node doesn't accept the encoding
+		for tries := 0; tries < 2 && refusedEncoding(err) && c.compression() != used; tries++ {
+			used = c.compression()
+			results, err = c.uploadBatch(ctx, token, paths)
+		}
+		if !errors.Is(err, errBatchUnsupported) {
+			if err != nil {
+				return hashes, err
+			}
+			var first error
+			for i, result := range results {
+				if result.err != nil {
+					if first == nil {
+						first = fmt.Errorf("uploading %s: %w", paths[i], result.err)
+					}
+					continue
+				}
+				hashes[paths[i]] = result.Hash
+			}
+			return hashes, first
+		}
+		atomic.StoreInt32(&c.unbatched, 1)
+	}
+	for _, p := range paths {
+		result, err := c.UploadFileContext(ctx, p, token)
+		if err != nil {
+			return hashes, fmt.Errorf("uploading %s: %w", p, err)
+		}
+		hashes[p] = result.Hash
+	}
+	return hashes, nil
+}
+
+// batchResult is the node's answer for one file of a batch upload
+type batchResult struct {
+	UploadResult
+	Error string `json:"error"`
+
+	err error
+}
+
+// batchURL returns the url of the batch upload endpoint
+func (c *UploadClient) batchURL() string {
+	return strings.TrimSuffix(c.url, "/") + "/batch"
+}
+
+// uploadBatch sends the files as the parts of one multipart request to the
+// batch endpoint and returns the node's answer for each, in order, with the
+// hashes verified like for UploadFile
+func (c *UploadClient) uploadBatch(ctx context.Context, token string, paths []string) ([]batchResult, error) {
+	pr, pw := io.Pipe()
+	bodyWriter := multipart.NewWriter(pw)
+	sums := make([]string, len(paths))
+	written := make(chan error, 1)
+	go func() {
+		err := writeBatch(bodyWriter, paths, sums)
+		pw.CloseWithError(err)
+		written <- err
+	}()
+	compression := c.compression()
+	encoded := compression.compress(pr)
+	defer encoded.Close()
+	req, err := http.NewRequest("POST", c.batchURL(), encoded)
+	if err != nil {
+		pr.CloseWithError(err)
+		<-written
+		return nil, err
+	}
+	setHeaders(ctx, req.Header, c.userAgent, c.header)
+	req.Header.Set("Content-Type", bodyWriter.FormDataContentType())
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if compression != CompressionNone {
+		req.Header.Set("Content-Encoding", compression.encoding())
+	}
+	resp, err := c.do(ctx, req)
+	// the node may answer before reading all files, unblock the writer
+	pr.CloseWithError(errors.New("batch upload answered"))
+	werr := <-written
+	if err != nil {
+		switch {
+		case ctx.Err() != nil:
+			return nil, ctx.Err()
+		case werr != nil:
+			return nil, werr
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented {
+		return nil, errBatchUnsupported
+	}
+	c.noteEncodings(resp, compression)
+	if err := throttleStatusError(resp); err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		_, err := parseUploadResponse(resp.StatusCode, respBody)
+		return nil, err
+	}
+	if werr != nil {
+		return nil, werr
+	}
+	var results []batchResult
+	if err := json.Unmarshal(respBody, &results); err != nil || len(results) != len(paths) {
+		return nil, &UploadError{StatusCode: resp.StatusCode, Message: fmt.Sprintf("unexpected answer to a batch of %d files: %s", len(paths), strings.TrimSpace(string(respBody)))}
+	}
+	for i := range results {
+		result := &results[i]
+		switch {
+		case result.Error != "" || result.Hash == "":
+			result.err = &UploadError{StatusCode: resp.StatusCode, Message: result.Error}
+		case !sameHash(sums[i], result.Hash):
+			result.err = &ChecksumError{Name: filepath.Base(paths[i]), Expected: sums[i], Actual: result.Hash}
+		case result.Filename == "":
+			result.Filename = filepath.Base(paths[i])
+		}
+	}
+	return results, nil
+}
+
+// writeBatch writes the files as the parts of a multipart body, recording
+// the hex encoded sha256 of each in sums
+func writeBatch(bodyWriter *multipart.Writer, paths []string, sums []string) error {
+	for i, p := range paths {
+		sum, err := writeBatchPart(bodyWriter, p)
+		if err != nil {
+			return fmt.Errorf("uploading %s: %w", p, err)
+		}
+		sums[i] = sum
+	}
+	return bodyWriter.Close()
+}
+
+func writeBatchPart(bodyWriter *multipart.Writer, path string) (string, error) {
+	fh, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer fh.Close()
+	part, err := bodyWriter.CreateFormFile("file", filepath.Base(path))
 	if err != nil {
 		return "", err
 	}
-	return string(respBody), nil
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(part, hash), fh); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Close closes the idle connections of the client
+func (c *UploadClient) Close() error {
+	c.client.CloseIdleConnections()
+	return nil
 }
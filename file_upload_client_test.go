@@ -0,0 +1,49 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestArchiveName(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		dir  string
+		want string
+	}{
+		{"/", defaultArchiveName},
+		{"", filepath.Base(wd)},
+		{".", filepath.Base(wd)},
+		{"./", filepath.Base(wd)},
+		{"testdata/..", filepath.Base(wd)},
+		{"..", filepath.Base(filepath.Dir(wd))},
+		{"testdata", "testdata"},
+		{"testdata/golden/", "golden"},
+		{"/tmp/build", "build"},
+	}
+	for _, tt := range tests {
+		if got := archiveName(tt.dir); got != tt.want {
+			t.Errorf("archiveName(%q) = %q, want %q", tt.dir, got, tt.want)
+		}
+	}
+}
@@ -1,3 +1,6 @@
 module github.com/crowdcompute/cc-go-sdk
 
-require golang.org/x/oauth2 v0.0.0-20190402181905-9f3314589c9a
+require (
+	github.com/gorilla/websocket v1.4.1
+	gopkg.in/yaml.v2 v2.4.0
+)
@@ -0,0 +1,354 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// maxHARBody bounds the bodies kept per request and response, larger ones
+// are cut off
+const maxHARBody = 1 << 20
+
+// harRedactedHeaders are never written to captures
+var harRedactedHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+}
+
+// HARRecorder is an http.RoundTripper capturing the traffic of the clients
+// using it as a HAR 1.2 archive, e.g. to attach a trace to a bug report.
+// Credentials and the secret params and results of calls are redacted,
+// uploads are kept by size only. Websocket subscriptions aren't captured.
+//
+//	rec := ccgosdk.NewHARRecorder(nil)
+//	client := ccgosdk.NewCCClient(url, ccgosdk.WithHTTPClient(rec.Client()))
+//	uploader := ccgosdk.NewUploadClient(uploadURL, ccgosdk.WithUploadHTTPClient(rec.Client()))
+//	...
+//	rec.WriteFile("trace.har")
+type HARRecorder struct {
+	next http.RoundTripper
+
+	mu      sync.Mutex
+	entries []*harEntry
+}
+
+// NewHARRecorder returns a recorder sending requests through next,
+// http.DefaultTransport if nil
+func NewHARRecorder(next http.RoundTripper) *HARRecorder {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &HARRecorder{next: next}
+}
+
+// Client returns an http client using the recorder
+func (r *HARRecorder) Client() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+type harLog struct {
+	Log struct {
+		Version string      `json:"version"`
+		Creator harCreator  `json:"creator"`
+		Entries []*harEntry `json:"entries"`
+	} `json:"log"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	StartedDateTime time.Time   `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+	Comment         string      `json:"comment,omitempty"`
+
+	// secretResult is set for calls whose result is redacted
+	secretResult bool
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	PostData    *harPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+type harContent struct {
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+}
+
+type harTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// harHeaders lists h with credentials redacted
+func harHeaders(h http.Header) []harNameValue {
+	headers := []harNameValue{}
+	for name, values := range h {
+		for _, v := range values {
+			if harRedactedHeaders[http.CanonicalHeaderKey(name)] {
+				v = auditRedacted
+			}
+			headers = append(headers, harNameValue{Name: name, Value: v})
+		}
+	}
+	return headers
+}
+
+// isJSONBody reports whether a body of the given content type is JSON
+func isJSONBody(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return mediaType == "application/json"
+}
+
+// RoundTrip sends req through the next transport and captures it
+func (r *HARRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	entry := &harEntry{StartedDateTime: time.Now()}
+	entry.Request = harRequest{
+		Method:      req.Method,
+		URL:         req.URL.String(),
+		HTTPVersion: req.Proto,
+		Cookies:     []harNameValue{},
+		Headers:     harHeaders(req.Header),
+		QueryString: []harNameValue{},
+		HeadersSize: -1,
+		BodySize:    req.ContentLength,
+	}
+	for name, values := range req.URL.Query() {
+		for _, v := range values {
+			entry.Request.QueryString = append(entry.Request.QueryString, harNameValue{Name: name, Value: v})
+		}
+	}
+	contentType := req.Header.Get("Content-Type")
+	if req.Body != nil && isJSONBody(contentType) {
+		body, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		entry.Request.BodySize = int64(len(body))
+		entry.Request.PostData = &harPostData{MimeType: contentType, Text: string(redactRequestBody(body, entry))}
+	} else if req.Body != nil {
+		// uploads may be large and hold user data, only their size is kept
+		entry.Request.PostData = &harPostData{MimeType: contentType}
+	}
+
+	r.mu.Lock()
+	r.entries = append(r.entries, entry)
+	r.mu.Unlock()
+
+	resp, err := r.next.RoundTrip(req)
+	wait := time.Since(entry.StartedDateTime)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entry.Timings.Wait = durationMS(wait)
+	entry.Time = entry.Timings.Wait
+	if err != nil {
+		entry.Comment = err.Error()
+		entry.Response = harResponse{Cookies: []harNameValue{}, Headers: []harNameValue{}, HeadersSize: -1, BodySize: -1}
+		return nil, err
+	}
+	entry.Response = harResponse{
+		Status:      resp.StatusCode,
+		StatusText:  http.StatusText(resp.StatusCode),
+		HTTPVersion: resp.Proto,
+		Cookies:     []harNameValue{},
+		Headers:     harHeaders(resp.Header),
+		Content:     harContent{MimeType: resp.Header.Get("Content-Type")},
+		HeadersSize: -1,
+		BodySize:    -1,
+	}
+	mediaType, _, _ := mime.ParseMediaType(entry.Response.Content.MimeType)
+	resp.Body = &harBody{
+		ReadCloser: resp.Body,
+		rec:        r,
+		entry:      entry,
+		start:      time.Now(),
+		text:       mediaType == "application/json" || mediaType == "text/plain",
+		buf:        prefixWriter{max: maxHARBody},
+	}
+	return resp, nil
+}
+
+// redactRequestBody returns a JSON-RPC request body with the secret params
+// of its method redacted, noting on entry whether its result is secret
+func redactRequestBody(body []byte, entry *harEntry) []byte {
+	var call rpcRequest
+	if json.Unmarshal(body, &call) != nil || call.Method == "" {
+		return body
+	}
+	entry.secretResult = auditSecretResults[call.Method]
+	call.Params = redactParams(call.Method, call.Params)
+	redacted, err := json.Marshal(call)
+	if err != nil {
+		return body
+	}
+	return redacted
+}
+
+// harBody captures a response body as the caller reads it
+type harBody struct {
+	io.ReadCloser
+	rec   *HARRecorder
+	entry *harEntry
+	start time.Time
+	text  bool
+
+	buf  prefixWriter
+	size int64
+	once sync.Once
+}
+
+func (b *harBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.size += int64(n)
+	if b.text {
+		b.buf.Write(p[:n])
+	}
+	if err == io.EOF {
+		b.finish()
+	}
+	return n, err
+}
+
+func (b *harBody) Close() error {
+	b.finish()
+	return b.ReadCloser.Close()
+}
+
+// finish records the body read so far with the entry
+func (b *harBody) finish() {
+	b.once.Do(func() {
+		text := b.buf.buf
+		if b.entry.secretResult {
+			text = redactResponseBody(text)
+		}
+		b.rec.mu.Lock()
+		defer b.rec.mu.Unlock()
+		b.entry.Response.Content.Size = b.size
+		b.entry.Response.Content.Text = string(text)
+		b.entry.Response.BodySize = b.size
+		b.entry.Timings.Receive = durationMS(time.Since(b.start))
+		b.entry.Time = b.entry.Timings.Wait + b.entry.Timings.Receive
+	})
+}
+
+// redactResponseBody replaces the result of a JSON-RPC response
+func redactResponseBody(body []byte) []byte {
+	var resp rpcResponse
+	if json.Unmarshal(body, &resp) != nil {
+		return []byte(auditRedacted)
+	}
+	if resp.Result == nil {
+		return body
+	}
+	resp.Result, _ = json.Marshal(auditRedacted)
+	redacted, err := json.Marshal(resp)
+	if err != nil {
+		return []byte(auditRedacted)
+	}
+	return redacted
+}
+
+func durationMS(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// WriteTo writes the captured traffic as HAR to w
+func (r *HARRecorder) WriteTo(w io.Writer) (int64, error) {
+	var archive harLog
+	archive.Log.Version = "1.2"
+	archive.Log.Creator = harCreator{Name: "cc-go-sdk", Version: Version}
+	r.mu.Lock()
+	archive.Log.Entries = append([]*harEntry{}, r.entries...)
+	data, err := json.MarshalIndent(archive, "", "  ")
+	r.mu.Unlock()
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(append(data, '\n'))
+	return int64(n), err
+}
+
+// WriteFile writes the captured traffic as HAR file
+func (r *HARRecorder) WriteFile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	_, err = r.WriteTo(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Reset drops the captured traffic
+func (r *HARRecorder) Reset() {
+	r.mu.Lock()
+	r.entries = nil
+	r.mu.Unlock()
+}
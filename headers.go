@@ -0,0 +1,72 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Version is the version of the SDK, sent in the default User-Agent
+const Version = "0.1.0"
+
+const defaultUserAgent = "cc-go-sdk/" + Version
+
+// correlationHeader carries the id identifying a call in the node's logs
+const correlationHeader = "X-Correlation-ID"
+
+type headerKey struct{}
+
+// ContextWithHeader returns a context that adds the header key: value to the
+// requests of calls made with it, e.g. an X-Request-ID
+func ContextWithHeader(ctx context.Context, key, value string) context.Context {
+	header := http.Header{}
+	if prev, ok := ctx.Value(headerKey{}).(http.Header); ok {
+		header = prev.Clone()
+	}
+	header.Add(key, value)
+	return context.WithValue(ctx, headerKey{}, header)
+}
+
+// setHeaders sets the user agent, static headers and the headers of ctx on h
+func setHeaders(ctx context.Context, h http.Header, userAgent string, static http.Header) {
+	if userAgent == "" {
+		userAgent = defaultUserAgent
+	}
+	h.Set("User-Agent", userAgent)
+	for key, values := range static {
+		h[key] = append([]string(nil), values...)
+	}
+	if perCall, ok := ctx.Value(headerKey{}).(http.Header); ok {
+		for key, values := range perCall {
+			h[key] = append([]string(nil), values...)
+		}
+	}
+}
+
+// newCorrelationID returns a random id for a call
+func newCorrelationID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,94 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/crowdcompute/cc-go-sdk/internal/docker"
+)
+
+// ImageBuildOptions configures BuildAndUploadImage
+type ImageBuildOptions struct {
+	// ContextDir is the local build context directory
+	ContextDir string
+	// Dockerfile is the path of the Dockerfile relative to ContextDir,
+	// "Dockerfile" by default
+	Dockerfile string
+	// Tag names the built image, required to save it
+	Tag       string
+	BuildArgs map[string]string
+	// Progress receives the build output if set
+	Progress io.Writer
+}
+
+// BuiltImage describes an image built and uploaded by BuildAndUploadImage
+type BuiltImage struct {
+	Tag string
+	// Hash is the hex encoded sha256 of the saved image tarball
+	Hash string
+	Size int64
+	// Upload is how the node stored the image
+	Upload *UploadResult
+}
+
+// BuildAndUploadImage builds an image with the local docker daemon (as
+// configured by DOCKER_HOST), saves it to a tarball and uploads it to the node,
+// replacing the usual docker build, docker save and UploadFile sequence
+func (c *UploadClient) BuildAndUploadImage(ctx context.Context, opts ImageBuildOptions, token string) (*BuiltImage, error) {
+	if opts.Tag == "" {
+		return nil, errors.New("an image tag is required")
+	}
+	engine, err := docker.NewClientFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(writeTar(pw, opts.ContextDir, opts.ContextDir))
+	}()
+	err = engine.BuildImage(ctx, pr, docker.BuildOptions{
+		Dockerfile: opts.Dockerfile,
+		Tag:        opts.Tag,
+		BuildArgs:  opts.BuildArgs,
+	}, opts.Progress)
+	pr.CloseWithError(err)
+	if err != nil {
+		return nil, err
+	}
+
+	image, err := engine.SaveImage(ctx, opts.Tag)
+	if err != nil {
+		return nil, err
+	}
+	defer image.Close()
+	name := strings.NewReplacer("/", "_", ":", "_").Replace(opts.Tag) + ".tar"
+	upload, err := c.UploadReaderContext(ctx, name, image, -1, token)
+	if err != nil {
+		return nil, err
+	}
+	return &BuiltImage{
+		Tag:    opts.Tag,
+		Hash:   upload.Hash,
+		Size:   upload.Size,
+		Upload: upload,
+	}, nil
+}
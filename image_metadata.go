@@ -0,0 +1,115 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrImageNotFound is returned by ResolveImageName for names no image is
+// registered under
+var ErrImageNotFound = errors.New("no image registered under that name")
+
+// ImageMetadata gives an uploaded image a human-meaningful name
+type ImageMetadata struct {
+	Hash        string   `json:"hash"`
+	Name        string   `json:"name"`
+	Tags        []string `json:"tags,omitempty"`
+	Description string   `json:"description,omitempty"`
+	// Account registered the metadata, set by the node
+	Account string    `json:"account,omitempty"`
+	Created Timestamp `json:"createdTime"`
+	Updated Timestamp `json:"updatedTime"`
+}
+
+// HasTag reports whether the image is tagged with tag
+func (m *ImageMetadata) HasTag(tag string) bool {
+	for _, t := range m.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// ImageMetadataFilter selects registered images. Zero fields don't filter.
+type ImageMetadataFilter struct {
+	Name string `json:"name,omitempty"`
+	// Tags selects the images tagged with all of them
+	Tags    []string `json:"tags,omitempty"`
+	Account string   `json:"account,omitempty"`
+}
+
+// RegisterImageMetadata names the uploaded image with the given hash, see
+// HashFile, replacing the metadata registered for it before
+func (rpc *CCClient) RegisterImageMetadata(hash, name string, tags []string, description string) (*ImageMetadata, error) {
+	meta := new(ImageMetadata)
+	if err := rpc.callInto(context.Background(), meta, "imagemanager_registerImageMetadata", hash, name, tags, description); err != nil {
+		return nil, err
+	}
+	return meta, nil
+}
+
+// UnregisterImageMetadata removes the metadata of an image, leaving the
+// image itself in place
+func (rpc *CCClient) UnregisterImageMetadata(hash string) error {
+	_, err := rpc.call("imagemanager_unregisterImageMetadata", hash)
+	return err
+}
+
+// GetImageMetadata returns the metadata registered for the image with the
+// given hash
+func (rpc *CCClient) GetImageMetadata(hash string) (*ImageMetadata, error) {
+	meta := new(ImageMetadata)
+	if err := rpc.callInto(context.Background(), meta, "imagemanager_getImageMetadata", hash); err != nil {
+		return nil, err
+	}
+	return meta, nil
+}
+
+// ListImageMetadata returns the metadata of the registered images matching filter
+func (rpc *CCClient) ListImageMetadata(filter ImageMetadataFilter) ([]ImageMetadata, error) {
+	var metas []ImageMetadata
+	if err := rpc.callInto(context.Background(), &metas, "imagemanager_listImageMetadata", filter); err != nil {
+		return nil, err
+	}
+	return metas, nil
+}
+
+// ResolveImageName returns the hash of the image registered under name,
+// the most recently registered one if several are
+func (rpc *CCClient) ResolveImageName(name string) (string, error) {
+	metas, err := rpc.ListImageMetadata(ImageMetadataFilter{Name: name})
+	if err != nil {
+		return "", err
+	}
+	var latest *ImageMetadata
+	for i := range metas {
+		if metas[i].Name != name {
+			continue
+		}
+		if latest == nil || metas[i].Updated.After(latest.Updated.Time) {
+			latest = &metas[i]
+		}
+	}
+	if latest == nil {
+		return "", fmt.Errorf("%w: %s", ErrImageNotFound, name)
+	}
+	return latest.Hash, nil
+}
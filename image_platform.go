@@ -0,0 +1,281 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"strings"
+)
+
+// maxImageMetadata bounds the size of the archive entries read for the
+// platform of an image, its manifests and config are far smaller
+const maxImageMetadata = 1 << 20
+
+// ErrPlatformMismatch is returned when pushing an image to a worker of
+// another platform, see PushOptions.Platform
+var ErrPlatformMismatch = errors.New("image platform doesn't match the worker")
+
+// ImagePlatform is the operating system and CPU architecture an image is
+// built for or a worker runs. Empty fields match any platform.
+type ImagePlatform struct {
+	OS           string `json:"os,omitempty"`
+	Architecture string `json:"architecture,omitempty"`
+	// Variant is the CPU variant, e.g. "v7" for arm
+	Variant string `json:"variant,omitempty"`
+}
+
+// String formats the platform as os/arch[/variant]
+func (p ImagePlatform) String() string {
+	s := p.OS + "/" + p.Architecture
+	if p.Variant != "" {
+		s += "/" + p.Variant
+	}
+	return s
+}
+
+// IsZero reports whether the platform is unknown
+func (p ImagePlatform) IsZero() bool {
+	return p.OS == "" && p.Architecture == ""
+}
+
+// normalizeArch maps the names kernels report for architectures to the ones
+// images are built for
+func normalizeArch(arch string) string {
+	switch arch = strings.ToLower(arch); arch {
+	case "x86_64", "x86-64":
+		return "amd64"
+	case "aarch64":
+		return "arm64"
+	case "armhf", "armv7l":
+		return "arm"
+	case "i386", "i686":
+		return "386"
+	}
+	return arch
+}
+
+// RunsOn reports whether an image built for p runs on a worker of platform
+// node. Unknown fields of either are taken to match.
+func (p ImagePlatform) RunsOn(node ImagePlatform) bool {
+	if p.OS != "" && node.OS != "" && !strings.EqualFold(p.OS, node.OS) {
+		return false
+	}
+	if p.Architecture != "" && node.Architecture != "" && normalizeArch(p.Architecture) != normalizeArch(node.Architecture) {
+		return false
+	}
+	return p.Variant == "" || node.Variant == "" || p.Variant == node.Variant
+}
+
+// PlatformError reports a worker whose platform an image doesn't run on
+type PlatformError struct {
+	NodeID string
+	Image  ImagePlatform
+	Node   ImagePlatform
+}
+
+func (e *PlatformError) Error() string {
+	return fmt.Sprintf("%v: image is %s, node %s is %s", ErrPlatformMismatch, e.Image, e.NodeID, e.Node)
+}
+
+func (e *PlatformError) Unwrap() error {
+	return ErrPlatformMismatch
+}
+
+// DetectImagePlatform returns the platform of an image tarball as written by
+// docker save, in docker or OCI layout and optionally gzipped. Archives of
+// several platforms return the zero platform, which matches any worker.
+func DetectImagePlatform(filename string) (ImagePlatform, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return ImagePlatform{}, err
+	}
+	defer f.Close()
+	var r io.Reader = bufio.NewReader(f)
+	if magic, _ := r.(*bufio.Reader).Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return ImagePlatform{}, err
+		}
+		defer gz.Close()
+		r = gz
+	}
+	entries, err := readImageMetadata(r)
+	if err != nil {
+		return ImagePlatform{}, fmt.Errorf("%s: %v", filename, err)
+	}
+	platform, err := entries.platform()
+	if err != nil {
+		return ImagePlatform{}, fmt.Errorf("%s: %v", filename, err)
+	}
+	return platform, nil
+}
+
+// imageArchive are the small entries of an image tarball by name
+type imageArchive map[string][]byte
+
+// readImageMetadata reads the entries of the tarball which may be manifests
+// or configs, skipping layers
+func readImageMetadata(r io.Reader) (imageArchive, error) {
+	entries := make(imageArchive)
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		name := path.Clean(strings.TrimPrefix(hdr.Name, "./"))
+		if hdr.Typeflag != tar.TypeReg || hdr.Size > maxImageMetadata ||
+			(!strings.HasSuffix(name, ".json") && !strings.HasPrefix(name, "blobs/")) {
+			continue
+		}
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		entries[name] = data
+	}
+}
+
+// blob returns the OCI blob with the given digest
+func (a imageArchive) blob(digest string) ([]byte, bool) {
+	data, ok := a[path.Join("blobs", strings.Replace(digest, ":", "/", 1))]
+	return data, ok
+}
+
+// config decodes the platform of an image config
+func (a imageArchive) config(data []byte) (ImagePlatform, error) {
+	var p ImagePlatform
+	if err := json.Unmarshal(data, &p); err != nil {
+		return ImagePlatform{}, fmt.Errorf("invalid image config: %v", err)
+	}
+	return p, nil
+}
+
+// platform reads the platform from the docker manifest.json, or otherwise
+// the OCI index.json of the archive
+func (a imageArchive) platform() (ImagePlatform, error) {
+	if data, ok := a["manifest.json"]; ok {
+		var manifests []struct {
+			Config string `json:"Config"`
+		}
+		if err := json.Unmarshal(data, &manifests); err != nil {
+			return ImagePlatform{}, fmt.Errorf("invalid manifest.json: %v", err)
+		}
+		return a.platforms(len(manifests), func(i int) (ImagePlatform, error) {
+			config, ok := a[path.Clean(manifests[i].Config)]
+			if !ok {
+				return ImagePlatform{}, fmt.Errorf("missing image config %s", manifests[i].Config)
+			}
+			return a.config(config)
+		})
+	}
+	data, ok := a["index.json"]
+	if !ok {
+		return ImagePlatform{}, errors.New("neither manifest.json nor index.json found, not an image archive")
+	}
+	var index struct {
+		Manifests []struct {
+			Digest   string         `json:"digest"`
+			Platform *ImagePlatform `json:"platform"`
+		} `json:"manifests"`
+	}
+	if err := json.Unmarshal(data, &index); err != nil {
+		return ImagePlatform{}, fmt.Errorf("invalid index.json: %v", err)
+	}
+	return a.platforms(len(index.Manifests), func(i int) (ImagePlatform, error) {
+		m := index.Manifests[i]
+		if m.Platform != nil {
+			return *m.Platform, nil
+		}
+		data, ok := a.blob(m.Digest)
+		if !ok {
+			return ImagePlatform{}, fmt.Errorf("missing manifest %s", m.Digest)
+		}
+		var manifest struct {
+			Config struct {
+				Digest string `json:"digest"`
+			} `json:"config"`
+		}
+		if err := json.Unmarshal(data, &manifest); err != nil {
+			return ImagePlatform{}, fmt.Errorf("invalid manifest %s: %v", m.Digest, err)
+		}
+		config, ok := a.blob(manifest.Config.Digest)
+		if !ok {
+			return ImagePlatform{}, fmt.Errorf("missing image config %s", manifest.Config.Digest)
+		}
+		return a.config(config)
+	})
+}
+
+// platforms returns the platform shared by the n images of the archive, or
+// the zero platform if they differ
+func (a imageArchive) platforms(n int, get func(i int) (ImagePlatform, error)) (ImagePlatform, error) {
+	if n == 0 {
+		return ImagePlatform{}, errors.New("archive holds no image")
+	}
+	first, err := get(0)
+	if err != nil {
+		return ImagePlatform{}, err
+	}
+	for i := 1; i < n; i++ {
+		p, err := get(i)
+		if err != nil {
+			return ImagePlatform{}, err
+		}
+		if p != first {
+			return ImagePlatform{}, nil
+		}
+	}
+	return first, nil
+}
+
+// checkPlatform fails pushes of images to workers they don't run on, per opts
+func (rpc *CCClient) checkPlatform(ctx context.Context, nodeID string, opts PushOptions) error {
+	if opts.Platform.IsZero() {
+		return nil
+	}
+	resources := new(NodeResources)
+	if err := rpc.callInto(ctx, resources, "node_getResources", nodeID); err != nil {
+		return fmt.Errorf("checking platform of %s: %w", nodeID, err)
+	}
+	node := resources.Platform()
+	if opts.Platform.RunsOn(node) {
+		return nil
+	}
+	err := &PlatformError{NodeID: nodeID, Image: opts.Platform, Node: node}
+	if !opts.AllowPlatformMismatch {
+		return err
+	}
+	if rpc.Debug {
+		log.Printf("pushing anyway: %v\n", err)
+	}
+	return nil
+}
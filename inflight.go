@@ -0,0 +1,61 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"context"
+	"sync"
+)
+
+// inflightLimiter bounds the number of requests a client has outstanding
+type inflightLimiter struct {
+	slots chan struct{}
+}
+
+func newInflightLimiter(max int) *inflightLimiter {
+	if max <= 0 {
+		return nil
+	}
+	return &inflightLimiter{slots: make(chan struct{}, max)}
+}
+
+// acquire waits for a free slot until ctx is done and returns the function
+// freeing it, which may be called more than once. Without limit it returns
+// right away.
+func (l *inflightLimiter) acquire(ctx context.Context) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+	select {
+	case l.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	var once sync.Once
+	return func() {
+		once.Do(func() { <-l.slots })
+	}, nil
+}
+
+// InFlight returns the number of requests the client and the clients
+// derived from it have outstanding, zero without WithMaxInFlight
+func (rpc *CCClient) InFlight() int {
+	if rpc.inflight == nil {
+		return 0
+	}
+	return len(rpc.inflight.slots)
+}
@@ -0,0 +1,197 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package integrationtest runs a CrowdCompute node in a docker container of
+// the local daemon, so that pipelines built on the SDK can be tested end to
+// end against a real node:
+//
+//	func TestPipeline(t *testing.T) {
+//		node := integrationtest.StartT(t, integrationtest.Options{})
+//		// use node.Client
+//	}
+package integrationtest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	ccgosdk "github.com/crowdcompute/cc-go-sdk"
+	"github.com/crowdcompute/cc-go-sdk/internal/docker"
+)
+
+const (
+	// DefaultImage is the node image started by default
+	DefaultImage = "crowdcompute/cc-node:latest"
+	// DefaultRPCPort is the container port the node serves RPC on
+	DefaultRPCPort = "8085/tcp"
+	// DefaultStartupTimeout bounds the wait for the node to become ready
+	DefaultStartupTimeout = 2 * time.Minute
+)
+
+// readinessInterval is how often a starting node is probed
+const readinessInterval = 500 * time.Millisecond
+
+// Options configures the node container
+type Options struct {
+	// Image is the node image, DefaultImage by default. It is pulled if the
+	// daemon doesn't have it.
+	Image string
+	// Cmd and Env override those of the image
+	Cmd []string
+	Env []string
+	// RPCPort is the container port of the RPC endpoint, DefaultRPCPort by default
+	RPCPort string
+	// UploadPath and DownloadPath are the paths of the file endpoints on the
+	// RPC port, /upload and /download by default
+	UploadPath   string
+	DownloadPath string
+	// StartupTimeout is DefaultStartupTimeout by default
+	StartupTimeout time.Duration
+	// ClientOptions configure Client
+	ClientOptions []ccgosdk.Option
+}
+
+// Node is a running node container
+type Node struct {
+	// URL is the RPC endpoint of the node
+	URL string
+	// Client is a client of the node with upload and download clients set
+	Client      *ccgosdk.CCClient
+	ContainerID string
+
+	docker *docker.Client
+}
+
+// Start starts a node container and waits until the node answers calls.
+// The container is removed if it doesn't become ready.
+func Start(ctx context.Context, opts Options) (*Node, error) {
+	if opts.Image == "" {
+		opts.Image = DefaultImage
+	}
+	if opts.RPCPort == "" {
+		opts.RPCPort = DefaultRPCPort
+	}
+	if opts.UploadPath == "" {
+		opts.UploadPath = "/upload"
+	}
+	if opts.DownloadPath == "" {
+		opts.DownloadPath = "/download"
+	}
+	if opts.StartupTimeout <= 0 {
+		opts.StartupTimeout = DefaultStartupTimeout
+	}
+	client, err := docker.NewClientFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if err := client.PullImage(ctx, opts.Image); err != nil {
+		return nil, fmt.Errorf("pulling %s: %v", opts.Image, err)
+	}
+	id, err := client.CreateContainer(ctx, docker.ContainerConfig{
+		Image:        opts.Image,
+		Cmd:          opts.Cmd,
+		Env:          opts.Env,
+		ExposedPorts: map[string]struct{}{opts.RPCPort: {}},
+		HostConfig:   &docker.HostConfig{PublishAllPorts: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+	node := &Node{ContainerID: id, docker: client}
+	if err := node.start(ctx, opts); err != nil {
+		if logs, lerr := node.Logs(20); lerr == nil && logs != "" {
+			err = fmt.Errorf("%v\nnode log:\n%s", err, logs)
+		}
+		node.Close()
+		return nil, err
+	}
+	return node, nil
+}
+
+func (n *Node) start(ctx context.Context, opts Options) error {
+	if err := n.docker.StartContainer(ctx, n.ContainerID); err != nil {
+		return err
+	}
+	addr, err := n.docker.PublishedPort(ctx, n.ContainerID, opts.RPCPort)
+	if err != nil {
+		return err
+	}
+	n.URL = "http://" + addr
+	clientOpts := append([]ccgosdk.Option{
+		ccgosdk.WithUploadClient(ccgosdk.NewUploadClient(n.URL + opts.UploadPath)),
+		ccgosdk.WithDownloadClient(ccgosdk.NewDownloadClient(n.URL + opts.DownloadPath)),
+	}, opts.ClientOptions...)
+	n.Client = ccgosdk.NewCCClient(n.URL, clientOpts...)
+	err = ccgosdk.Poll(ctx, readinessInterval, func(ctx context.Context) (bool, error) {
+		state, err := n.docker.InspectContainer(ctx, n.ContainerID)
+		if err != nil {
+			return false, err
+		}
+		if state.Status == "exited" || state.Status == "dead" {
+			return false, fmt.Errorf("node container exited with code %d", state.ExitCode)
+		}
+		_, err = n.Client.ListRPCModules()
+		return err == nil, nil
+	}, ccgosdk.PollMaxDuration(opts.StartupTimeout))
+	if err == ccgosdk.ErrPollTimeout {
+		return fmt.Errorf("node not ready after %v", opts.StartupTimeout)
+	}
+	return err
+}
+
+// Logs returns the last lines the node wrote
+func (n *Node) Logs(tail int) (string, error) {
+	return n.docker.ContainerLogs(context.Background(), n.ContainerID, tail)
+}
+
+// Close closes the client and removes the node container
+func (n *Node) Close() error {
+	if n.Client != nil {
+		n.Client.Close()
+	}
+	return n.docker.RemoveContainer(context.Background(), n.ContainerID)
+}
+
+// StartT starts a node for the test t, removed when the test finishes.
+// The test is skipped if no docker daemon is reachable and fails if the
+// node doesn't start.
+func StartT(t testing.TB, opts Options) *Node {
+	t.Helper()
+	node, err := Start(context.Background(), opts)
+	if err != nil {
+		if isDaemonUnreachable(err) {
+			t.Skipf("docker daemon unreachable: %v", err)
+		}
+		t.Fatalf("starting node: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := node.Close(); err != nil {
+			t.Logf("removing node container: %v", err)
+		}
+	})
+	return node
+}
+
+// isDaemonUnreachable reports whether err is a failure to connect to the daemon
+func isDaemonUnreachable(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "connect: no such file or directory") ||
+		strings.Contains(msg, "connect: connection refused") ||
+		strings.Contains(msg, "connect: permission denied")
+}
@@ -0,0 +1,169 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package docker is a minimal client of the Docker Engine API, covering what
+// the SDK needs to work with a local docker daemon.
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// DefaultHost is the daemon address used when DOCKER_HOST is not set
+const DefaultHost = "unix:///var/run/docker.sock"
+
+// Client talks to a docker daemon
+type Client struct {
+	client *http.Client
+	base   string
+	// hostname is the address of a remote daemon, empty for local ones
+	hostname string
+}
+
+// NewClient creates a client for the daemon at host, e.g.
+// unix:///var/run/docker.sock or tcp://127.0.0.1:2375
+func NewClient(host string) (*Client, error) {
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "unix":
+		socket := u.Path
+		transport := &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", socket)
+			},
+		}
+		return &Client{client: &http.Client{Transport: transport}, base: "http://docker"}, nil
+	case "tcp", "http":
+		return &Client{client: &http.Client{}, base: "http://" + u.Host, hostname: u.Hostname()}, nil
+	case "https":
+		return &Client{client: &http.Client{}, base: "https://" + u.Host, hostname: u.Hostname()}, nil
+	}
+	return nil, fmt.Errorf("unsupported docker host %q", host)
+}
+
+// NewClientFromEnv creates a client for the daemon set in DOCKER_HOST
+func NewClientFromEnv() (*Client, error) {
+	host := os.Getenv("DOCKER_HOST")
+	if host == "" {
+		host = DefaultHost
+	}
+	return NewClient(host)
+}
+
+// do sends a request to the daemon, turning error responses into errors
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body io.Reader, contentType string) (*http.Response, error) {
+	u := c.base + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequest(method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := c.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		data, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 4096))
+		var msg struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(data, &msg) != nil || msg.Message == "" {
+			msg.Message = strings.TrimSpace(string(data))
+		}
+		return nil, fmt.Errorf("docker %s %s: %s: %s", method, path, resp.Status, msg.Message)
+	}
+	return resp, nil
+}
+
+// BuildOptions configures BuildImage
+type BuildOptions struct {
+	// Dockerfile is the path of the Dockerfile inside the build context
+	Dockerfile string
+	Tag        string
+	BuildArgs  map[string]string
+}
+
+// BuildImage builds an image from the tar archived build context.
+// Output of the build steps is written to progress if it isn't nil.
+func (c *Client) BuildImage(ctx context.Context, buildContext io.Reader, opts BuildOptions, progress io.Writer) error {
+	query := url.Values{}
+	if opts.Dockerfile != "" {
+		query.Set("dockerfile", opts.Dockerfile)
+	}
+	if opts.Tag != "" {
+		query.Set("t", opts.Tag)
+	}
+	if len(opts.BuildArgs) > 0 {
+		args, err := json.Marshal(opts.BuildArgs)
+		if err != nil {
+			return err
+		}
+		query.Set("buildargs", string(args))
+	}
+	resp, err := c.do(ctx, "POST", "/build", query, buildContext, "application/x-tar")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var msg struct {
+			Stream string `json:"stream"`
+			Error  string `json:"error"`
+		}
+		if err := dec.Decode(&msg); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if msg.Error != "" {
+			return errors.New(strings.TrimSpace(msg.Error))
+		}
+		if progress != nil && msg.Stream != "" {
+			io.WriteString(progress, msg.Stream)
+		}
+	}
+}
+
+// SaveImage returns the docker save tarball of the named image.
+// The caller must close it.
+func (c *Client) SaveImage(ctx context.Context, name string) (io.ReadCloser, error) {
+	resp, err := c.do(ctx, "GET", "/images/"+url.PathEscape(name)+"/get", nil, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
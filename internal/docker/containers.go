@@ -0,0 +1,275 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package docker
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LoadImage loads a docker save tarball and returns the references of the
+// loaded images, tags or image ids
+func (c *Client) LoadImage(ctx context.Context, tarball io.Reader) ([]string, error) {
+	resp, err := c.do(ctx, "POST", "/images/load", url.Values{"quiet": {"1"}}, tarball, "application/x-tar")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var refs []string
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var msg struct {
+			Stream string `json:"stream"`
+			Error  string `json:"error"`
+		}
+		if err := dec.Decode(&msg); err == io.EOF {
+			return refs, nil
+		} else if err != nil {
+			return nil, err
+		}
+		if msg.Error != "" {
+			return nil, errors.New(strings.TrimSpace(msg.Error))
+		}
+		scanner := bufio.NewScanner(strings.NewReader(msg.Stream))
+		for scanner.Scan() {
+			line := scanner.Text()
+			for _, prefix := range []string{"Loaded image ID: ", "Loaded image: "} {
+				if strings.HasPrefix(line, prefix) {
+					refs = append(refs, strings.TrimSpace(strings.TrimPrefix(line, prefix)))
+				}
+			}
+		}
+	}
+}
+
+// ContainerConfig is the subset of a container's configuration the SDK sets
+type ContainerConfig struct {
+	Image string   `json:"Image"`
+	Cmd   []string `json:"Cmd,omitempty"`
+	Env   []string `json:"Env,omitempty"`
+	// ExposedPorts are keyed by port and protocol, e.g. "8080/tcp"
+	ExposedPorts map[string]struct{} `json:"ExposedPorts,omitempty"`
+	HostConfig   *HostConfig         `json:"HostConfig,omitempty"`
+}
+
+// HostConfig is the subset of a container's host configuration the SDK sets
+type HostConfig struct {
+	// PublishAllPorts publishes the exposed ports on random host ports
+	PublishAllPorts bool `json:"PublishAllPorts,omitempty"`
+}
+
+// PullImage pulls the image ref, e.g. "alpine:3", from its registry
+func (c *Client) PullImage(ctx context.Context, ref string) error {
+	resp, err := c.do(ctx, "POST", "/images/create", url.Values{"fromImage": {ref}}, nil, "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var msg struct {
+			Error string `json:"error"`
+		}
+		if err := dec.Decode(&msg); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if msg.Error != "" {
+			return errors.New(strings.TrimSpace(msg.Error))
+		}
+	}
+}
+
+// CreateContainer creates a container and returns its id
+func (c *Client) CreateContainer(ctx context.Context, cfg ContainerConfig) (string, error) {
+	body, err := json.Marshal(cfg)
+	if err != nil {
+		return "", err
+	}
+	resp, err := c.do(ctx, "POST", "/containers/create", nil, bytes.NewReader(body), "application/json")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	var created struct {
+		ID string `json:"Id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		return "", err
+	}
+	return created.ID, nil
+}
+
+// StartContainer starts a created container
+func (c *Client) StartContainer(ctx context.Context, id string) error {
+	return discard(c.do(ctx, "POST", "/containers/"+id+"/start", nil, nil, ""))
+}
+
+// StopContainer stops a running container
+func (c *Client) StopContainer(ctx context.Context, id string) error {
+	return discard(c.do(ctx, "POST", "/containers/"+id+"/stop", nil, nil, ""))
+}
+
+// RemoveContainer removes a container, killing it if it still runs
+func (c *Client) RemoveContainer(ctx context.Context, id string) error {
+	return discard(c.do(ctx, "DELETE", "/containers/"+id, url.Values{"force": {"1"}}, nil, ""))
+}
+
+// WaitContainer waits until the container exits and returns its exit code
+func (c *Client) WaitContainer(ctx context.Context, id string) (int, error) {
+	resp, err := c.do(ctx, "POST", "/containers/"+id+"/wait", nil, nil, "")
+	if err != nil {
+		return -1, err
+	}
+	defer resp.Body.Close()
+	var result struct {
+		StatusCode int `json:"StatusCode"`
+		Error      *struct {
+			Message string `json:"Message"`
+		} `json:"Error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return -1, err
+	}
+	if result.Error != nil && result.Error.Message != "" {
+		return result.StatusCode, errors.New(result.Error.Message)
+	}
+	return result.StatusCode, nil
+}
+
+// ContainerState is the state of a container
+type ContainerState struct {
+	Status     string    `json:"Status"`
+	ExitCode   int       `json:"ExitCode"`
+	Error      string    `json:"Error"`
+	StartedAt  time.Time `json:"StartedAt"`
+	FinishedAt time.Time `json:"FinishedAt"`
+}
+
+// InspectContainer returns the state of a container
+func (c *Client) InspectContainer(ctx context.Context, id string) (*ContainerState, error) {
+	resp, err := c.do(ctx, "GET", "/containers/"+id+"/json", nil, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var inspect struct {
+		State ContainerState `json:"State"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&inspect); err != nil {
+		return nil, err
+	}
+	return &inspect.State, nil
+}
+
+// PublishedPort returns the host address the container port, e.g.
+// "8080/tcp", is published on
+func (c *Client) PublishedPort(ctx context.Context, id, port string) (string, error) {
+	resp, err := c.do(ctx, "GET", "/containers/"+id+"/json", nil, nil, "")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	var inspect struct {
+		NetworkSettings struct {
+			Ports map[string][]struct {
+				HostIP   string `json:"HostIp"`
+				HostPort string `json:"HostPort"`
+			} `json:"Ports"`
+		} `json:"NetworkSettings"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&inspect); err != nil {
+		return "", err
+	}
+	for _, binding := range inspect.NetworkSettings.Ports[port] {
+		host := binding.HostIP
+		if host == "" || host == "0.0.0.0" || host == "::" {
+			// published on all interfaces of the daemon's host
+			host = c.hostname
+			if host == "" {
+				host = "127.0.0.1"
+			}
+		}
+		return net.JoinHostPort(host, binding.HostPort), nil
+	}
+	return "", fmt.Errorf("port %s of container %s is not published", port, id)
+}
+
+// ContainerLogs returns the last tail lines of the output of a container
+// without a tty, stdout and stderr interleaved
+func (c *Client) ContainerLogs(ctx context.Context, id string, tail int) (string, error) {
+	query := url.Values{"stdout": {"1"}, "stderr": {"1"}, "tail": {strconv.Itoa(tail)}}
+	resp, err := c.do(ctx, "GET", "/containers/"+id+"/logs", query, nil, "")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	// the output is multiplexed in frames of an 8 byte header, holding the
+	// stream and the payload size, followed by the payload
+	var out bytes.Buffer
+	header := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(resp.Body, header); err == io.EOF {
+			return out.String(), nil
+		} else if err != nil {
+			return "", err
+		}
+		size := int64(binary.BigEndian.Uint32(header[4:]))
+		if _, err := io.CopyN(&out, resp.Body, size); err != nil {
+			return "", err
+		}
+	}
+}
+
+// CopyToContainer extracts the tar archive into the directory dir of a container
+func (c *Client) CopyToContainer(ctx context.Context, id, dir string, archive io.Reader) error {
+	return discard(c.do(ctx, "PUT", "/containers/"+id+"/archive", url.Values{"path": {dir}}, archive, "application/x-tar"))
+}
+
+// CopyFromContainer returns a tar archive of the file or directory path of a
+// container. The caller must close it.
+func (c *Client) CopyFromContainer(ctx context.Context, id, path string) (io.ReadCloser, error) {
+	resp, err := c.do(ctx, "GET", "/containers/"+id+"/archive", url.Values{"path": {path}}, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// discard drains and closes the body of a response that carries no data
+func discard(resp *http.Response, err error) error {
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, err = io.Copy(ioutil.Discard, resp.Body)
+	return err
+}
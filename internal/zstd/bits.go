@@ -0,0 +1,143 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package zstd
+
+import "math/bits"
+
+// backwardReader reads a bitstream written forwards from its end, as FSE and
+// Huffman coded streams are. The last byte holds a marker bit above the first
+// bits read.
+type backwardReader struct {
+	data []byte
+	// pos is the number of bytes of data not loaded into cur yet
+	pos int
+	// cur holds n unread bits at its low end, the next one read is the highest
+	cur uint64
+	n   uint
+	// overread is the number of bits read past the start of the stream, which
+	// read as zeros
+	overread uint
+}
+
+func newBackwardReader(data []byte) (*backwardReader, error) {
+	if len(data) == 0 || data[len(data)-1] == 0 {
+		return nil, errCorrupt("bitstream without end marker")
+	}
+	last := data[len(data)-1]
+	return &backwardReader{
+		data: data,
+		pos:  len(data) - 1,
+		cur:  uint64(last),
+		n:    uint(7 - bits.LeadingZeros8(last)),
+	}, nil
+}
+
+func (r *backwardReader) fill() {
+	for r.n <= 56 && r.pos > 0 {
+		r.pos--
+		r.cur = r.cur<<8 | uint64(r.data[r.pos])
+		r.n += 8
+	}
+}
+
+// peek returns the next n bits without consuming them, n is at most 56
+func (r *backwardReader) peek(n uint) uint64 {
+	if r.n < n {
+		r.fill()
+		if r.n < n {
+			return (r.cur & (1<<r.n - 1)) << (n - r.n)
+		}
+	}
+	return (r.cur >> (r.n - n)) & (1<<n - 1)
+}
+
+// skip consumes n bits that were peeked
+func (r *backwardReader) skip(n uint) {
+	if r.n < n {
+		r.overread += n - r.n
+		r.n = 0
+		return
+	}
+	r.n -= n
+}
+
+// read consumes and returns the next n bits, n is at most 56
+func (r *backwardReader) read(n uint) uint64 {
+	v := r.peek(n)
+	r.skip(n)
+	return v
+}
+
+// remaining returns the number of bits left unread
+func (r *backwardReader) remaining() int {
+	return int(r.n) + 8*r.pos - int(r.overread)
+}
+
+// forwardReader reads a bitstream from its start, low bits first, as table
+// descriptions are written
+type forwardReader struct {
+	data []byte
+	// off is the number of bits read
+	off uint
+}
+
+func (r *forwardReader) read(n uint) (uint32, error) {
+	var v uint32
+	for i := uint(0); i < n; i++ {
+		byteOff := (r.off + i) >> 3
+		if int(byteOff) >= len(r.data) {
+			return 0, errCorrupt("truncated table description")
+		}
+		v |= uint32(r.data[byteOff]>>((r.off+i)&7)&1) << i
+	}
+	r.off += n
+	return v, nil
+}
+
+// bytes returns the number of bytes the bits read span
+func (r *forwardReader) bytes() int {
+	return int((r.off + 7) >> 3)
+}
+
+// bitWriter writes a bitstream forwards, low bits first. Closed streams end
+// in a marker bit so that they can be read backwards.
+type bitWriter struct {
+	out []byte
+	cur uint64
+	n   uint
+}
+
+// write appends the low n bits of v, n is at most 32
+func (w *bitWriter) write(v uint64, n uint) {
+	w.cur |= (v & (1<<n - 1)) << w.n
+	w.n += n
+	for w.n >= 8 {
+		w.out = append(w.out, byte(w.cur))
+		w.cur >>= 8
+		w.n -= 8
+	}
+}
+
+// close writes the end marker and pads the last byte
+func (w *bitWriter) close() []byte {
+	w.write(1, 1)
+	if w.n > 0 {
+		w.out = append(w.out, byte(w.cur))
+		w.cur, w.n = 0, 0
+	}
+	return w.out
+}
@@ -0,0 +1,316 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package zstd
+
+import "encoding/binary"
+
+// decompressBlock decodes a compressed block, appending its content of at
+// most max bytes to hist
+func (z *Reader) decompressBlock(data []byte, max int) error {
+	literals, n, err := z.readLiterals(data)
+	if err != nil {
+		return err
+	}
+	return z.execSequences(data[n:], literals, len(z.hist)+max)
+}
+
+// readLiterals reads the literals section of a block, returning the literals
+// and the size of the section
+func (z *Reader) readLiterals(data []byte) ([]byte, int, error) {
+	if len(data) == 0 {
+		return nil, 0, errCorrupt("missing literals section")
+	}
+	kind := data[0] & 3
+	format := data[0] >> 2 & 3
+	if kind < 2 {
+		// raw or RLE literals, the header holds the regenerated size
+		var size, n int
+		switch format {
+		case 0, 2:
+			size, n = int(data[0]>>3), 1
+		case 1:
+			if len(data) < 2 {
+				return nil, 0, errCorrupt("truncated literals header")
+			}
+			size, n = int(data[0]>>4)|int(data[1])<<4, 2
+		case 3:
+			if len(data) < 3 {
+				return nil, 0, errCorrupt("truncated literals header")
+			}
+			size, n = int(data[0]>>4)|int(data[1])<<4|int(data[2])<<12, 3
+		}
+		if size > maxBlockSize {
+			return nil, 0, errCorrupt("too many literals")
+		}
+		if kind == 0 {
+			if len(data) < n+size {
+				return nil, 0, errCorrupt("truncated literals")
+			}
+			return data[n : n+size], n + size, nil
+		}
+		if len(data) < n+1 {
+			return nil, 0, errCorrupt("truncated literals")
+		}
+		literals := z.literalsBuffer(size)
+		for i := range literals {
+			literals[i] = data[n]
+		}
+		return literals, n + 1, nil
+	}
+
+	// Huffman coded literals, in one stream or four
+	var size, compressed, n int
+	streams := 4
+	switch format {
+	case 0, 1:
+		if format == 0 {
+			streams = 1
+		}
+		if len(data) < 3 {
+			return nil, 0, errCorrupt("truncated literals header")
+		}
+		h := uint32(data[0]) | uint32(data[1])<<8 | uint32(data[2])<<16
+		size, compressed, n = int(h>>4&0x3ff), int(h>>14&0x3ff), 3
+	case 2:
+		if len(data) < 4 {
+			return nil, 0, errCorrupt("truncated literals header")
+		}
+		h := binary.LittleEndian.Uint32(data)
+		size, compressed, n = int(h>>4&0x3fff), int(h>>18&0x3fff), 4
+	case 3:
+		if len(data) < 5 {
+			return nil, 0, errCorrupt("truncated literals header")
+		}
+		h := uint64(binary.LittleEndian.Uint32(data)) | uint64(data[4])<<32
+		size, compressed, n = int(h>>4&0x3ffff), int(h>>22&0x3ffff), 5
+	}
+	if size > maxBlockSize {
+		return nil, 0, errCorrupt("too many literals")
+	}
+	if len(data) < n+compressed {
+		return nil, 0, errCorrupt("truncated literals")
+	}
+	in := data[n : n+compressed]
+	if kind == 2 {
+		t, tn, err := readHuffTable(in)
+		if err != nil {
+			return nil, 0, err
+		}
+		z.huff = t
+		in = in[tn:]
+	} else if z.huff == nil {
+		return nil, 0, errCorrupt("literals repeat a missing Huffman table")
+	}
+	literals := z.literalsBuffer(size)
+	if streams == 1 {
+		if err := z.huff.decode(literals, in); err != nil {
+			return nil, 0, err
+		}
+		return literals, n + compressed, nil
+	}
+	if len(in) < 6 {
+		return nil, 0, errCorrupt("truncated literals jump table")
+	}
+	sizes := [4]int{int(binary.LittleEndian.Uint16(in)), int(binary.LittleEndian.Uint16(in[2:])), int(binary.LittleEndian.Uint16(in[4:]))}
+	in = in[6:]
+	sizes[3] = len(in) - sizes[0] - sizes[1] - sizes[2]
+	if sizes[3] < 0 {
+		return nil, 0, errCorrupt("literals jump table out of range")
+	}
+	quarter := (size + 3) / 4
+	if 3*quarter > size {
+		return nil, 0, errCorrupt("too few literals for four streams")
+	}
+	out := literals
+	for i, s := range sizes {
+		regenerated := quarter
+		if i == 3 {
+			regenerated = len(out)
+		}
+		if err := z.huff.decode(out[:regenerated], in[:s]); err != nil {
+			return nil, 0, err
+		}
+		out, in = out[regenerated:], in[s:]
+	}
+	return literals, n + compressed, nil
+}
+
+func (z *Reader) literalsBuffer(size int) []byte {
+	if cap(z.literals) < size {
+		z.literals = make([]byte, size)
+	}
+	return z.literals[:size]
+}
+
+// execSequences reads the sequences section of a block and appends the
+// content they describe to hist, up to a length of limit
+func (z *Reader) execSequences(data, literals []byte, limit int) error {
+	if len(data) == 0 {
+		return errCorrupt("missing sequences section")
+	}
+	count := int(data[0])
+	n := 1
+	switch {
+	case count == 0:
+		if len(z.hist)+len(literals) > limit {
+			return errCorrupt("block content too large")
+		}
+		copy(z.grow(len(literals)), literals)
+		return nil
+	case count == 255:
+		if len(data) < 3 {
+			return errCorrupt("truncated sequences header")
+		}
+		count, n = int(binary.LittleEndian.Uint16(data[1:]))+0x7f00, 3
+	case count >= 128:
+		if len(data) < 2 {
+			return errCorrupt("truncated sequences header")
+		}
+		count, n = (count-128)<<8|int(data[1]), 2
+	}
+	if len(data) < n+1 {
+		return errCorrupt("truncated sequences header")
+	}
+	modes := data[n]
+	n++
+	if modes&3 != 0 {
+		return errCorrupt("reserved sequence modes set")
+	}
+	var err error
+	var read int
+	if z.llTable, read, err = readSeqTable(data[n:], modes>>6, z.llTable, literalLengthTable, len(literalLengthCodes)-1, maxLiteralLengthLog); err != nil {
+		return err
+	}
+	n += read
+	if z.ofTable, read, err = readSeqTable(data[n:], modes>>4&3, z.ofTable, offsetTable, maxOffsetCode, maxOffsetLog); err != nil {
+		return err
+	}
+	n += read
+	if z.mlTable, read, err = readSeqTable(data[n:], modes>>2&3, z.mlTable, matchLengthTable, len(matchLengthCodes)-1, maxMatchLengthLog); err != nil {
+		return err
+	}
+	n += read
+
+	r, err := newBackwardReader(data[n:])
+	if err != nil {
+		return err
+	}
+	var ll, of, ml fseDecoder
+	ll.init(z.llTable, r)
+	of.init(z.ofTable, r)
+	ml.init(z.mlTable, r)
+	for i := 0; i < count; i++ {
+		ofCode, mlCode, llCode := of.symbol(), ml.symbol(), ll.symbol()
+		if ofCode > maxOffsetCode || int(mlCode) >= len(matchLengthCodes) || int(llCode) >= len(literalLengthCodes) {
+			return errCorrupt("invalid sequence code")
+		}
+		offset := uint32(1)<<ofCode + uint32(r.read(uint(ofCode)))
+		mc, lc := matchLengthCodes[mlCode], literalLengthCodes[llCode]
+		matchLen := mc.base + uint32(r.read(uint(mc.extra)))
+		litLen := lc.base + uint32(r.read(uint(lc.extra)))
+		if i < count-1 {
+			ll.update(r)
+			ml.update(r)
+			of.update(r)
+		}
+		if r.overread > 0 {
+			return errCorrupt("sequences read past their end")
+		}
+
+		offset = z.resolveOffset(offset, litLen)
+		if int(litLen) > len(literals) {
+			return errCorrupt("sequence uses missing literals")
+		}
+		if len(z.hist)+int(litLen)+int(matchLen) > limit {
+			return errCorrupt("block content too large")
+		}
+		copy(z.grow(int(litLen)), literals[:litLen])
+		literals = literals[litLen:]
+		if offset == 0 || int(offset) > len(z.hist) {
+			return errCorrupt("match offset out of range")
+		}
+		// the match may overlap the content it produces, which repeats
+		// with a period of offset
+		src := len(z.hist) - int(offset)
+		for left := int(matchLen); left > 0; {
+			n := len(z.hist) - src
+			if n > left {
+				n = left
+			}
+			z.hist = append(z.hist, z.hist[src:src+n]...)
+			left -= n
+		}
+	}
+	if r.remaining() != 0 {
+		return errCorrupt("sequences not read to their end")
+	}
+	if len(z.hist)+len(literals) > limit {
+		return errCorrupt("block content too large")
+	}
+	copy(z.grow(len(literals)), literals)
+	return nil
+}
+
+// resolveOffset turns an offset value into an offset, updating the repeated
+// offsets
+func (z *Reader) resolveOffset(value, litLen uint32) uint32 {
+	if value > 3 {
+		z.reps[2], z.reps[1], z.reps[0] = z.reps[1], z.reps[0], value-3
+		return z.reps[0]
+	}
+	// without literals the repeated offsets are shifted by one
+	i := value - 1
+	if litLen == 0 {
+		i++
+	}
+	switch i {
+	case 0:
+		return z.reps[0]
+	case 1:
+		z.reps[1], z.reps[0] = z.reps[0], z.reps[1]
+	case 2:
+		z.reps[2], z.reps[1], z.reps[0] = z.reps[1], z.reps[0], z.reps[2]
+	case 3:
+		z.reps[2], z.reps[1], z.reps[0] = z.reps[1], z.reps[0], z.reps[0]-1
+	}
+	return z.reps[0]
+}
+
+// readSeqTable reads the table of a kind of symbol in the given mode,
+// returning it and the number of bytes read
+func readSeqTable(data []byte, mode uint8, prev, predefined *fseTable, maxSymbol int, maxLog uint) (*fseTable, int, error) {
+	switch mode {
+	case 0:
+		return predefined, 0, nil
+	case 1:
+		if len(data) == 0 || int(data[0]) > maxSymbol {
+			return nil, 0, errCorrupt("invalid RLE sequence table")
+		}
+		return rleTable(data[0]), 1, nil
+	case 2:
+		log, counts, n, err := readFSECounts(data, maxSymbol, maxLog)
+		if err != nil {
+			return nil, 0, err
+		}
+		t, err := newFSETable(log, counts)
+		return t, n, err
+	}
+	if prev == nil {
+		return nil, 0, errCorrupt("sequences repeat a missing table")
+	}
+	return prev, 0, nil
+}
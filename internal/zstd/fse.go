@@ -0,0 +1,262 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package zstd
+
+import "math/bits"
+
+// fseEntry is a state of an FSE decoding table
+type fseEntry struct {
+	symbol uint8
+	// nbBits are read and added to base to get the next state
+	nbBits uint8
+	base   uint16
+}
+
+// fseTable is an FSE decoding table of 1<<log states
+type fseTable struct {
+	log     uint8
+	entries []fseEntry
+}
+
+// readFSECounts reads a table description, the normalized counts of the
+// symbols up to maxSymbol, where -1 stands for a "less than one" count. It
+// returns the accuracy log, the counts and the number of bytes read.
+func readFSECounts(data []byte, maxSymbol int, maxLog uint) (uint, []int16, int, error) {
+	r := &forwardReader{data: data}
+	low, err := r.read(4)
+	if err != nil {
+		return 0, nil, 0, err
+	}
+	log := uint(low) + 5
+	if log > maxLog {
+		return 0, nil, 0, errCorrupt("FSE accuracy log too large")
+	}
+	counts := make([]int16, 0, maxSymbol+1)
+	remaining := int32(1<<log) + 1
+	threshold := int32(1 << log)
+	nbBits := log + 1
+	for remaining > 1 {
+		if len(counts) > maxSymbol {
+			return 0, nil, 0, errCorrupt("FSE table has too many symbols")
+		}
+		max := 2*threshold - 1 - remaining
+		v, err := r.read(nbBits - 1)
+		if err != nil {
+			return 0, nil, 0, err
+		}
+		count := int32(v)
+		if count >= max {
+			high, err := r.read(1)
+			if err != nil {
+				return 0, nil, 0, err
+			}
+			count |= int32(high) << (nbBits - 1)
+			if count >= threshold {
+				count -= max
+			}
+		}
+		count--
+		if count < 0 {
+			remaining += count
+		} else {
+			remaining -= count
+		}
+		if remaining < 1 {
+			return 0, nil, 0, errCorrupt("FSE counts exceed the table")
+		}
+		counts = append(counts, int16(count))
+		if count == 0 {
+			// followed by the number of further zero counts, 2 bits at a time
+			for {
+				repeat, err := r.read(2)
+				if err != nil {
+					return 0, nil, 0, err
+				}
+				for i := uint32(0); i < repeat; i++ {
+					counts = append(counts, 0)
+				}
+				if repeat != 3 {
+					break
+				}
+			}
+		}
+		for remaining < threshold {
+			nbBits--
+			threshold >>= 1
+		}
+	}
+	if remaining != 1 || len(counts) > maxSymbol+1 {
+		return 0, nil, 0, errCorrupt("invalid FSE table description")
+	}
+	return log, counts, r.bytes(), nil
+}
+
+// spreadSymbols distributes the symbols over the states of a table, symbols
+// with a "less than one" count take the last states
+func spreadSymbols(log uint, counts []int16) []uint8 {
+	size := 1 << log
+	symbols := make([]uint8, size)
+	high := size - 1
+	for s, c := range counts {
+		if c == -1 {
+			symbols[high] = uint8(s)
+			high--
+		}
+	}
+	step := size>>1 + size>>3 + 3
+	pos := 0
+	for s, c := range counts {
+		for i := 0; i < int(c); i++ {
+			symbols[pos] = uint8(s)
+			for pos = (pos + step) & (size - 1); pos > high; pos = (pos + step) & (size - 1) {
+			}
+		}
+	}
+	return symbols
+}
+
+// newFSETable builds the decoding table for the given counts
+func newFSETable(log uint, counts []int16) (*fseTable, error) {
+	size := 1 << log
+	total := 0
+	for _, c := range counts {
+		if c == -1 {
+			total++
+		} else {
+			total += int(c)
+		}
+	}
+	if total != size {
+		return nil, errCorrupt("FSE counts don't add up")
+	}
+	symbols := spreadSymbols(log, counts)
+	next := make([]uint16, len(counts))
+	for s, c := range counts {
+		if c == -1 {
+			next[s] = 1
+		} else {
+			next[s] = uint16(c)
+		}
+	}
+	t := &fseTable{log: uint8(log), entries: make([]fseEntry, size)}
+	for u, s := range symbols {
+		state := next[s]
+		next[s]++
+		nbBits := log - uint(bits.Len16(state)-1)
+		t.entries[u] = fseEntry{symbol: s, nbBits: uint8(nbBits), base: state<<nbBits - uint16(size)}
+	}
+	return t, nil
+}
+
+// rleTable is the table of a single symbol
+func rleTable(symbol uint8) *fseTable {
+	return &fseTable{entries: []fseEntry{{symbol: symbol}}}
+}
+
+// fseDecoder is a state walking an fseTable
+type fseDecoder struct {
+	t     *fseTable
+	state uint16
+}
+
+func (d *fseDecoder) init(t *fseTable, r *backwardReader) {
+	d.t = t
+	d.state = uint16(r.read(uint(t.log)))
+}
+
+func (d *fseDecoder) symbol() uint8 {
+	return d.t.entries[d.state].symbol
+}
+
+func (d *fseDecoder) update(r *backwardReader) {
+	e := d.t.entries[d.state]
+	d.state = e.base + uint16(r.read(uint(e.nbBits)))
+}
+
+// fseSymbolTransform is how an FSE encoder moves on from a state
+type fseSymbolTransform struct {
+	deltaNbBits    uint32
+	deltaFindState int32
+}
+
+// fseEncTable is an FSE encoding table
+type fseEncTable struct {
+	log        uint8
+	stateTable []uint16
+	transforms []fseSymbolTransform
+}
+
+// newFSEEncTable builds the encoding table for the given counts
+func newFSEEncTable(log uint, counts []int16) *fseEncTable {
+	size := 1 << log
+	symbols := spreadSymbols(log, counts)
+	cumul := make([]int, len(counts)+1)
+	for s, c := range counts {
+		if c == -1 {
+			c = 1
+		}
+		cumul[s+1] = cumul[s] + int(c)
+	}
+	t := &fseEncTable{log: uint8(log), stateTable: make([]uint16, size), transforms: make([]fseSymbolTransform, len(counts))}
+	for u, s := range symbols {
+		t.stateTable[cumul[s]] = uint16(size + u)
+		cumul[s]++
+	}
+	total := int32(0)
+	for s, c := range counts {
+		switch c {
+		case 0:
+			t.transforms[s].deltaNbBits = uint32(log+1)<<16 - uint32(size)
+		case -1, 1:
+			t.transforms[s] = fseSymbolTransform{deltaNbBits: uint32(log)<<16 - uint32(size), deltaFindState: total - 1}
+			total++
+		default:
+			maxBitsOut := log - uint(bits.Len16(uint16(c-1))-1)
+			minStatePlus := uint32(c) << maxBitsOut
+			t.transforms[s] = fseSymbolTransform{deltaNbBits: uint32(maxBitsOut)<<16 - minStatePlus, deltaFindState: total - int32(c)}
+			total += int32(c)
+		}
+	}
+	return t
+}
+
+// fseEncoder is a state walking an fseEncTable
+type fseEncoder struct {
+	t     *fseEncTable
+	state uint32
+}
+
+// init starts in a state of the first symbol encoded, the last decoded
+func (e *fseEncoder) init(t *fseEncTable, symbol uint8) {
+	e.t = t
+	tr := t.transforms[symbol]
+	nbBitsOut := (tr.deltaNbBits + 1<<15) >> 16
+	value := nbBitsOut<<16 - tr.deltaNbBits
+	e.state = uint32(t.stateTable[int32(value>>nbBitsOut)+tr.deltaFindState])
+}
+
+func (e *fseEncoder) encode(w *bitWriter, symbol uint8) {
+	tr := e.t.transforms[symbol]
+	nbBitsOut := (e.state + tr.deltaNbBits) >> 16
+	w.write(uint64(e.state), uint(nbBitsOut))
+	e.state = uint32(e.t.stateTable[int32(e.state>>nbBitsOut)+tr.deltaFindState])
+}
+
+// flush writes the final state, the first the decoder reads
+func (e *fseEncoder) flush(w *bitWriter) {
+	w.write(uint64(e.state), uint(e.t.log))
+}
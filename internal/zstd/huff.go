@@ -0,0 +1,187 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package zstd
+
+import "math/bits"
+
+// maxHuffmanBits bounds the length of Huffman codes
+const maxHuffmanBits = 11
+
+// huffEntry decodes the code that a table index starts with
+type huffEntry struct {
+	symbol uint8
+	nbBits uint8
+}
+
+// huffTable is indexed by the next maxBits bits of a stream
+type huffTable struct {
+	maxBits uint
+	entries []huffEntry
+}
+
+// readHuffTable reads a Huffman tree description, returning the table and
+// the number of bytes read
+func readHuffTable(data []byte) (*huffTable, int, error) {
+	if len(data) == 0 {
+		return nil, 0, errCorrupt("missing Huffman tree description")
+	}
+	var weights []uint8
+	header := int(data[0])
+	var n int
+	if header >= 128 {
+		// the weights follow, 4 bits each
+		count := header - 127
+		n = 1 + (count+1)/2
+		if len(data) < n {
+			return nil, 0, errCorrupt("truncated Huffman weights")
+		}
+		weights = make([]uint8, count)
+		for i := range weights {
+			b := data[1+i/2]
+			if i%2 == 0 {
+				weights[i] = b >> 4
+			} else {
+				weights[i] = b & 0xf
+			}
+		}
+	} else {
+		n = 1 + header
+		if len(data) < n {
+			return nil, 0, errCorrupt("truncated Huffman weights")
+		}
+		var err error
+		if weights, err = readHuffWeights(data[1:n]); err != nil {
+			return nil, 0, err
+		}
+	}
+	t, err := newHuffTable(weights)
+	return t, n, err
+}
+
+// readHuffWeights decodes FSE compressed Huffman weights
+func readHuffWeights(data []byte) ([]uint8, error) {
+	log, counts, n, err := readFSECounts(data, 255, 6)
+	if err != nil {
+		return nil, err
+	}
+	t, err := newFSETable(log, counts)
+	if err != nil {
+		return nil, err
+	}
+	r, err := newBackwardReader(data[n:])
+	if err != nil {
+		return nil, err
+	}
+	// two interleaved states, decoding until the stream is read past its end
+	var s1, s2 fseDecoder
+	s1.init(t, r)
+	s2.init(t, r)
+	var weights []uint8
+	for {
+		if len(weights) > 253 {
+			return nil, errCorrupt("too many Huffman weights")
+		}
+		weights = append(weights, s1.symbol())
+		s1.update(r)
+		if r.overread > 0 {
+			weights = append(weights, s2.symbol())
+			break
+		}
+		weights = append(weights, s2.symbol())
+		s2.update(r)
+		if r.overread > 0 {
+			weights = append(weights, s1.symbol())
+			break
+		}
+	}
+	return weights, nil
+}
+
+// newHuffTable builds the decoding table of the given weights, that of the
+// last symbol is implied
+func newHuffTable(weights []uint8) (*huffTable, error) {
+	if len(weights) > 255 {
+		return nil, errCorrupt("too many Huffman weights")
+	}
+	var total uint32
+	for _, w := range weights {
+		if w > maxHuffmanBits {
+			return nil, errCorrupt("Huffman weight too large")
+		}
+		if w > 0 {
+			total += 1 << (w - 1)
+		}
+	}
+	if total == 0 {
+		return nil, errCorrupt("Huffman weights are all zero")
+	}
+	maxBits := uint(bits.Len32(total))
+	if maxBits > maxHuffmanBits {
+		return nil, errCorrupt("Huffman codes too long")
+	}
+	// the last weight fills the table up to the next power of two
+	rest := uint32(1)<<maxBits - total
+	if rest&(rest-1) != 0 {
+		return nil, errCorrupt("Huffman weights don't add up")
+	}
+	weights = append(weights[:len(weights):len(weights)], uint8(bits.Len32(rest)))
+
+	// codes are assigned from the lowest weight, the longest codes, and then
+	// by symbol
+	var rankStart [maxHuffmanBits + 2]uint32
+	for _, w := range weights {
+		if w > 0 {
+			rankStart[w] += 1 << (w - 1)
+		}
+	}
+	next := uint32(0)
+	for w := 1; w < len(rankStart); w++ {
+		count := rankStart[w]
+		rankStart[w] = next
+		next += count
+	}
+	t := &huffTable{maxBits: maxBits, entries: make([]huffEntry, 1<<maxBits)}
+	for s, w := range weights {
+		if w == 0 {
+			continue
+		}
+		length := uint32(1) << (w - 1)
+		e := huffEntry{symbol: uint8(s), nbBits: uint8(maxBits + 1 - uint(w))}
+		for i := rankStart[w]; i < rankStart[w]+length; i++ {
+			t.entries[i] = e
+		}
+		rankStart[w] += length
+	}
+	return t, nil
+}
+
+// decode decodes len(out) symbols from a stream, which must be read exactly
+func (t *huffTable) decode(out, stream []byte) error {
+	r, err := newBackwardReader(stream)
+	if err != nil {
+		return err
+	}
+	for i := range out {
+		e := t.entries[r.peek(t.maxBits)]
+		out[i] = e.symbol
+		r.skip(uint(e.nbBits))
+	}
+	if r.remaining() != 0 {
+		return errCorrupt("Huffman stream not read to its end")
+	}
+	return nil
+}
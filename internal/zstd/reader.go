@@ -0,0 +1,267 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package zstd
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+)
+
+// Reader decompresses a stream of zstd frames
+type Reader struct {
+	r   io.Reader
+	err error
+	// inFrame is set between the header and the last block of a frame
+	inFrame bool
+	frame   frameHeader
+	// hist holds the content decoded since the frame started, at least
+	// the window of it, out is the part not read yet
+	hist []byte
+	out  []byte
+	// size is the length of the frame's content decoded so far
+	size     uint64
+	checksum xxhash
+
+	block    []byte
+	literals []byte
+	// the tables of the previous block, which blocks may repeat
+	huff             *huffTable
+	llTable, ofTable *fseTable
+	mlTable          *fseTable
+	reps             [3]uint32
+	scratch          [18]byte
+}
+
+// frameHeader describes a frame
+type frameHeader struct {
+	windowSize  int
+	contentSize uint64
+	// hasSize and hasChecksum report which optional fields are there
+	hasSize     bool
+	hasChecksum bool
+}
+
+// NewReader returns a Reader decompressing r
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: r}
+}
+
+// Read reads decompressed data
+func (z *Reader) Read(p []byte) (int, error) {
+	for len(z.out) == 0 {
+		if z.err != nil {
+			return 0, z.err
+		}
+		z.err = z.next()
+	}
+	n := copy(p, z.out)
+	z.out = z.out[n:]
+	return n, nil
+}
+
+// next decodes the next frame header or block
+func (z *Reader) next() error {
+	if !z.inFrame {
+		return z.readFrameHeader()
+	}
+	if _, err := io.ReadFull(z.r, z.scratch[:3]); err != nil {
+		return unexpected(err)
+	}
+	header := uint32(z.scratch[0]) | uint32(z.scratch[1])<<8 | uint32(z.scratch[2])<<16
+	last := header&1 != 0
+	size := int(header >> 3)
+	blockMax := maxBlockSize
+	if z.frame.windowSize < blockMax {
+		blockMax = z.frame.windowSize
+	}
+	if size > blockMax {
+		return errCorrupt("block too large")
+	}
+
+	// only the window is kept of earlier blocks
+	if len(z.hist) > 2*z.frame.windowSize {
+		keep := z.hist[len(z.hist)-z.frame.windowSize:]
+		z.hist = z.hist[:copy(z.hist, keep)]
+	}
+	start := len(z.hist)
+	switch header >> 1 & 3 {
+	case 0:
+		if _, err := io.ReadFull(z.r, z.grow(size)); err != nil {
+			return unexpected(err)
+		}
+	case 1:
+		if _, err := io.ReadFull(z.r, z.scratch[:1]); err != nil {
+			return unexpected(err)
+		}
+		b := z.grow(size)
+		for i := range b {
+			b[i] = z.scratch[0]
+		}
+	case 2:
+		if cap(z.block) < size {
+			z.block = make([]byte, size)
+		}
+		z.block = z.block[:size]
+		if _, err := io.ReadFull(z.r, z.block); err != nil {
+			return unexpected(err)
+		}
+		if err := z.decompressBlock(z.block, blockMax); err != nil {
+			return err
+		}
+	default:
+		return errCorrupt("reserved block type")
+	}
+	z.out = z.hist[start:]
+	z.size += uint64(len(z.out))
+	if z.frame.hasChecksum {
+		z.checksum.write(z.out)
+	}
+	if !last {
+		return nil
+	}
+	z.inFrame = false
+	if z.frame.hasSize && z.size != z.frame.contentSize {
+		return errCorrupt("content size mismatch")
+	}
+	if z.frame.hasChecksum {
+		if _, err := io.ReadFull(z.r, z.scratch[:4]); err != nil {
+			return unexpected(err)
+		}
+		if binary.LittleEndian.Uint32(z.scratch[:4]) != uint32(z.checksum.sum64()) {
+			return errCorrupt("checksum mismatch")
+		}
+	}
+	return nil
+}
+
+// grow extends hist by n bytes and returns them
+func (z *Reader) grow(n int) []byte {
+	start := len(z.hist)
+	if cap(z.hist)-start < n {
+		hist := make([]byte, start, 2*cap(z.hist)+n)
+		copy(hist, z.hist)
+		z.hist = hist
+	}
+	z.hist = z.hist[:start+n]
+	return z.hist[start:]
+}
+
+// readFrameHeader reads the header of the next frame, skipping skippable
+// frames, and resets the state of the frame
+func (z *Reader) readFrameHeader() error {
+	for {
+		if _, err := io.ReadFull(z.r, z.scratch[:4]); err != nil {
+			if err == io.EOF {
+				return io.EOF
+			}
+			return unexpected(err)
+		}
+		m := binary.LittleEndian.Uint32(z.scratch[:4])
+		if m == magic {
+			break
+		}
+		if m&skippableMask != skippableMagic {
+			return errCorrupt("not a zstd frame")
+		}
+		if _, err := io.ReadFull(z.r, z.scratch[:4]); err != nil {
+			return unexpected(err)
+		}
+		n := int64(binary.LittleEndian.Uint32(z.scratch[:4]))
+		if _, err := io.CopyN(ioutil.Discard, z.r, n); err != nil {
+			return unexpected(err)
+		}
+	}
+
+	if _, err := io.ReadFull(z.r, z.scratch[:1]); err != nil {
+		return unexpected(err)
+	}
+	desc := z.scratch[0]
+	if desc&0x08 != 0 {
+		return errCorrupt("reserved frame header bit set")
+	}
+	singleSegment := desc&0x20 != 0
+	sizeBytes := [4]int{0, 2, 4, 8}[desc>>6]
+	if singleSegment && sizeBytes == 0 {
+		sizeBytes = 1
+	}
+	dictBytes := [4]int{0, 1, 2, 4}[desc&3]
+	n := sizeBytes + dictBytes
+	if !singleSegment {
+		n++
+	}
+	fields := z.scratch[:n]
+	if _, err := io.ReadFull(z.r, fields); err != nil {
+		return unexpected(err)
+	}
+	var frame frameHeader
+	if !singleSegment {
+		exp, mantissa := uint(fields[0]>>3), uint64(fields[0]&7)
+		base := uint64(1) << (10 + exp)
+		size := base + base/8*mantissa
+		if size > maxWindowSize {
+			return errors.New("zstd: window size exceeds limit")
+		}
+		frame.windowSize = int(size)
+		fields = fields[1:]
+	}
+	var dict uint32
+	for i := dictBytes - 1; i >= 0; i-- {
+		dict = dict<<8 | uint32(fields[i])
+	}
+	if dict != 0 {
+		return errors.New("zstd: dictionaries are not supported")
+	}
+	fields = fields[dictBytes:]
+	switch sizeBytes {
+	case 1:
+		frame.contentSize = uint64(fields[0])
+	case 2:
+		frame.contentSize = uint64(binary.LittleEndian.Uint16(fields)) + 256
+	case 4:
+		frame.contentSize = uint64(binary.LittleEndian.Uint32(fields))
+	case 8:
+		frame.contentSize = binary.LittleEndian.Uint64(fields)
+	}
+	frame.hasSize = sizeBytes > 0
+	if singleSegment {
+		if frame.contentSize > maxWindowSize {
+			return errors.New("zstd: window size exceeds limit")
+		}
+		frame.windowSize = int(frame.contentSize)
+	}
+	frame.hasChecksum = desc&0x04 != 0
+
+	z.frame = frame
+	z.inFrame = true
+	z.hist = z.hist[:0]
+	z.size = 0
+	z.checksum.reset()
+	z.huff = nil
+	z.llTable, z.ofTable, z.mlTable = nil, nil, nil
+	z.reps = [3]uint32{1, 4, 8}
+	return nil
+}
+
+// unexpected reports the end of the input within a frame as such
+func unexpected(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,132 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package zstd
+
+import "math/bits"
+
+// the symbols of sequences are codes for a baseline and a number of extra
+// bits, read after the symbols and added to the baseline
+type seqCode struct {
+	base  uint32
+	extra uint8
+}
+
+var literalLengthCodes = [...]seqCode{
+	{0, 0}, {1, 0}, {2, 0}, {3, 0}, {4, 0}, {5, 0}, {6, 0}, {7, 0},
+	{8, 0}, {9, 0}, {10, 0}, {11, 0}, {12, 0}, {13, 0}, {14, 0}, {15, 0},
+	{16, 1}, {18, 1}, {20, 1}, {22, 1}, {24, 2}, {28, 2}, {32, 3}, {40, 3},
+	{48, 4}, {64, 6}, {128, 7}, {256, 8}, {512, 9}, {1024, 10}, {2048, 11}, {4096, 12},
+	{8192, 13}, {16384, 14}, {32768, 15}, {65536, 16},
+}
+
+var matchLengthCodes = [...]seqCode{
+	{3, 0}, {4, 0}, {5, 0}, {6, 0}, {7, 0}, {8, 0}, {9, 0}, {10, 0},
+	{11, 0}, {12, 0}, {13, 0}, {14, 0}, {15, 0}, {16, 0}, {17, 0}, {18, 0},
+	{19, 0}, {20, 0}, {21, 0}, {22, 0}, {23, 0}, {24, 0}, {25, 0}, {26, 0},
+	{27, 0}, {28, 0}, {29, 0}, {30, 0}, {31, 0}, {32, 0}, {33, 0}, {34, 0},
+	{35, 1}, {37, 1}, {39, 1}, {41, 1}, {43, 2}, {47, 2}, {51, 3}, {59, 3},
+	{67, 4}, {83, 4}, {99, 5}, {131, 7}, {259, 8}, {515, 9}, {1027, 10}, {2051, 11},
+	{4099, 12}, {8195, 13}, {16387, 14}, {32771, 15}, {65539, 16},
+}
+
+// maxOffsetCode is the largest offset code, its offsets take that many bits
+const maxOffsetCode = 31
+
+// the accuracy logs of the tables of each kind of symbol
+const (
+	maxLiteralLengthLog = 9
+	maxMatchLengthLog   = 9
+	maxOffsetLog        = 8
+)
+
+// the distributions of the predefined tables
+var (
+	literalLengthCounts = []int16{
+		4, 3, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 1, 1, 1,
+		2, 2, 2, 2, 2, 2, 2, 2, 2, 3, 2, 1, 1, 1, 1, 1,
+		-1, -1, -1, -1,
+	}
+	matchLengthCounts = []int16{
+		1, 4, 3, 2, 2, 2, 2, 2, 2, 1, 1, 1, 1, 1, 1, 1,
+		1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1,
+		1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, -1, -1,
+		-1, -1, -1, -1, -1,
+	}
+	offsetCounts = []int16{
+		1, 1, 1, 1, 1, 1, 2, 2, 2, 1, 1, 1, 1, 1, 1, 1,
+		1, 1, 1, 1, 1, 1, 1, 1, -1, -1, -1, -1, -1,
+	}
+)
+
+// the predefined tables, for decoding and encoding
+var (
+	literalLengthTable = mustFSETable(6, literalLengthCounts)
+	matchLengthTable   = mustFSETable(6, matchLengthCounts)
+	offsetTable        = mustFSETable(5, offsetCounts)
+
+	literalLengthEncTable = newFSEEncTable(6, literalLengthCounts)
+	matchLengthEncTable   = newFSEEncTable(6, matchLengthCounts)
+	offsetEncTable        = newFSEEncTable(5, offsetCounts)
+)
+
+func mustFSETable(log uint, counts []int16) *fseTable {
+	t, err := newFSETable(log, counts)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// the codes of short lengths, longer ones are computed
+var (
+	shortLiteralLengthCodes [64]uint8
+	shortMatchLengthCodes   [131]uint8
+)
+
+func init() {
+	for n := range shortLiteralLengthCodes {
+		shortLiteralLengthCodes[n] = codeOf(literalLengthCodes[:], uint32(n))
+	}
+	for n := 3; n < len(shortMatchLengthCodes); n++ {
+		shortMatchLengthCodes[n] = codeOf(matchLengthCodes[:], uint32(n))
+	}
+}
+
+// literalLengthCode returns the code of a literal length
+func literalLengthCode(n uint32) uint8 {
+	if n < uint32(len(shortLiteralLengthCodes)) {
+		return shortLiteralLengthCodes[n]
+	}
+	return uint8(bits.Len32(n) - 1 + 19)
+}
+
+// matchLengthCode returns the code of a match length
+func matchLengthCode(n uint32) uint8 {
+	if n < uint32(len(shortMatchLengthCodes)) {
+		return shortMatchLengthCodes[n]
+	}
+	return uint8(bits.Len32(n-3) - 1 + 36)
+}
+
+// codeOf returns the last code whose baseline is at most n
+func codeOf(codes []seqCode, n uint32) uint8 {
+	i := len(codes) - 1
+	for codes[i].base > n {
+		i--
+	}
+	return uint8(i)
+}
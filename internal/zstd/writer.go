@@ -0,0 +1,282 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package zstd
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/bits"
+)
+
+// the Writer's window is 1 MiB, written as its exponent over 1 KiB
+const (
+	writerWindowLog  = 20
+	writerWindowSize = 1 << writerWindowLog
+)
+
+// the Writer finds matches of at least minMatch bytes by hashing
+const (
+	minMatch = 4
+	hashLog  = 16
+)
+
+// Writer compresses to a zstd frame
+type Writer struct {
+	w   io.Writer
+	err error
+	// hist holds the window before the pending block, which starts at
+	// pending
+	hist    []byte
+	pending int
+	// table maps hashes to the position in hist they were seen at plus one
+	table       [1 << hashLog]int32
+	checksum    xxhash
+	wroteHeader bool
+
+	seqs     []sequence
+	literals []byte
+	out      []byte
+}
+
+// sequence copies litLen literals and then matchLen bytes from offset back
+type sequence struct {
+	litLen, matchLen, offset uint32
+}
+
+// NewWriter returns a Writer compressing to w, which must be closed to
+// complete the frame
+func NewWriter(w io.Writer) *Writer {
+	z := &Writer{w: w}
+	z.checksum.reset()
+	return z
+}
+
+// Write compresses p
+func (z *Writer) Write(p []byte) (int, error) {
+	if z.err != nil {
+		return 0, z.err
+	}
+	n := len(p)
+	for len(p) > 0 {
+		room := maxBlockSize - (len(z.hist) - z.pending)
+		if room > len(p) {
+			room = len(p)
+		}
+		z.hist = append(z.hist, p[:room]...)
+		p = p[room:]
+		if len(z.hist)-z.pending == maxBlockSize {
+			if z.err = z.writeBlock(false); z.err != nil {
+				return n - len(p), z.err
+			}
+		}
+	}
+	return n, nil
+}
+
+// Close writes the pending data and the end of the frame. It doesn't close
+// the underlying writer.
+func (z *Writer) Close() error {
+	if z.err != nil {
+		if z.err == errClosed {
+			return nil
+		}
+		return z.err
+	}
+	if z.err = z.writeBlock(true); z.err != nil {
+		return z.err
+	}
+	var sum [4]byte
+	binary.LittleEndian.PutUint32(sum[:], uint32(z.checksum.sum64()))
+	if _, z.err = z.w.Write(sum[:]); z.err != nil {
+		return z.err
+	}
+	z.err = errClosed
+	return nil
+}
+
+var errClosed = errors.New("zstd: write to closed writer")
+
+// writeBlock encodes the pending data as a block, as the frame's last one
+// if last is set
+func (z *Writer) writeBlock(last bool) error {
+	out := z.out[:0]
+	if !z.wroteHeader {
+		// no content size nor dictionary, a checksum and the window size
+		out = append(out, 0x28, 0xB5, 0x2F, 0xFD, 0x04, (writerWindowLog-10)<<3)
+		z.wroteHeader = true
+	}
+	block := z.hist[z.pending:]
+	z.checksum.write(block)
+	header := uint32(len(block)) << 3
+	if last {
+		header |= 1
+	}
+	switch {
+	case len(block) > 1 && allEqual(block):
+		header |= 1 << 1
+		out = append(out, byte(header), byte(header>>8), byte(header>>16), block[0])
+	default:
+		start := len(out)
+		out = append(out, 0, 0, 0)
+		out = z.compressBlock(out)
+		if size := len(out) - start - 3; size < len(block) {
+			header = uint32(size)<<3 | 2<<1 | header&1
+			out[start], out[start+1], out[start+2] = byte(header), byte(header>>8), byte(header>>16)
+			break
+		}
+		out = append(out[:start], byte(header), byte(header>>8), byte(header>>16))
+		out = append(out, block...)
+	}
+	z.out = out
+	if _, err := z.w.Write(out); err != nil {
+		return err
+	}
+
+	// only the window is kept of earlier blocks
+	z.pending = len(z.hist)
+	if z.pending >= 2*writerWindowSize {
+		shift := z.pending - writerWindowSize
+		z.hist = z.hist[:copy(z.hist, z.hist[shift:])]
+		z.pending = len(z.hist)
+		for i, pos := range z.table {
+			if pos -= int32(shift); pos < 0 {
+				pos = 0
+			}
+			z.table[i] = pos
+		}
+	}
+	return nil
+}
+
+func allEqual(b []byte) bool {
+	for _, c := range b[1:] {
+		if c != b[0] {
+			return false
+		}
+	}
+	return true
+}
+
+// matchLen returns the length of the common prefix of a and b, b is at most
+// as long as a
+func matchLen(a, b []byte) int {
+	n := 0
+	for len(b) >= 8 {
+		if x := binary.LittleEndian.Uint64(a) ^ binary.LittleEndian.Uint64(b); x != 0 {
+			return n + bits.TrailingZeros64(x)>>3
+		}
+		a, b, n = a[8:], b[8:], n+8
+	}
+	for i := range b {
+		if a[i] != b[i] {
+			break
+		}
+		n++
+	}
+	return n
+}
+
+func hash4(v uint32) uint32 {
+	return v * 2654435761 >> (32 - hashLog)
+}
+
+// compressBlock appends the pending data encoded as a compressed block
+func (z *Writer) compressBlock(out []byte) []byte {
+	hist := z.hist
+	seqs, literals := z.seqs[:0], z.literals[:0]
+	anchor := z.pending
+	for i := anchor; i+minMatch <= len(hist); {
+		cur := binary.LittleEndian.Uint32(hist[i:])
+		h := hash4(cur)
+		cand := int(z.table[h]) - 1
+		z.table[h] = int32(i + 1)
+		if cand < 0 || i-cand > writerWindowSize || binary.LittleEndian.Uint32(hist[cand:]) != cur {
+			// skip faster through data that doesn't repeat
+			i += 1 + (i-anchor)>>6
+			continue
+		}
+		n := minMatch + matchLen(hist[cand+minMatch:], hist[i+minMatch:])
+		for i > anchor && cand > 0 && hist[i-1] == hist[cand-1] {
+			i--
+			cand--
+			n++
+		}
+		literals = append(literals, hist[anchor:i]...)
+		seqs = append(seqs, sequence{litLen: uint32(i - anchor), matchLen: uint32(n), offset: uint32(i - cand)})
+		i += n
+		anchor = i
+	}
+	literals = append(literals, hist[anchor:]...)
+	z.seqs, z.literals = seqs, literals
+
+	// raw literals
+	switch n := len(literals); {
+	case n < 1<<5:
+		out = append(out, byte(n<<3))
+	case n < 1<<12:
+		out = append(out, byte(n<<4|1<<2), byte(n>>4))
+	default:
+		out = append(out, byte(n<<4|3<<2), byte(n>>4), byte(n>>12))
+	}
+	out = append(out, literals...)
+
+	switch n := len(seqs); {
+	case n < 128:
+		out = append(out, byte(n))
+	case n < 0x7f00:
+		out = append(out, byte(n>>8+128), byte(n))
+	default:
+		out = append(out, 255, byte(n-0x7f00), byte((n-0x7f00)>>8))
+	}
+	if len(seqs) == 0 {
+		return out
+	}
+	// the predefined tables for all symbols
+	out = append(out, 0)
+	return encodeSequences(out, seqs)
+}
+
+// encodeSequences appends the bitstream of seqs, which is written from the
+// last sequence to the first so that the decoder reads them in order
+func encodeSequences(out []byte, seqs []sequence) []byte {
+	w := &bitWriter{out: out}
+	var ll, ml, of fseEncoder
+	for i := len(seqs) - 1; i >= 0; i-- {
+		s := seqs[i]
+		offset := s.offset + 3
+		ofCode := uint8(bits.Len32(offset) - 1)
+		mlCode := matchLengthCode(s.matchLen)
+		llCode := literalLengthCode(s.litLen)
+		if i == len(seqs)-1 {
+			ml.init(matchLengthEncTable, mlCode)
+			of.init(offsetEncTable, ofCode)
+			ll.init(literalLengthEncTable, llCode)
+		} else {
+			of.encode(w, ofCode)
+			ml.encode(w, mlCode)
+			ll.encode(w, llCode)
+		}
+		w.write(uint64(s.litLen), uint(literalLengthCodes[llCode].extra))
+		w.write(uint64(s.matchLen-3), uint(matchLengthCodes[mlCode].extra))
+		w.write(uint64(offset), uint(ofCode))
+	}
+	ml.flush(w)
+	of.flush(w)
+	ll.flush(w)
+	return w.close()
+}
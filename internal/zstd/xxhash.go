@@ -0,0 +1,121 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package zstd
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// xxhash64 primes
+const (
+	prime1 uint64 = 11400714785074694791
+	prime2 uint64 = 14029467366897019727
+	prime3 uint64 = 1609587929392839161
+	prime4 uint64 = 9650029242287828579
+	prime5 uint64 = 2870177450012600261
+)
+
+// xxhash is the streaming XXH64 hash with seed 0 that frames are
+// checksummed with
+type xxhash struct {
+	v     [4]uint64
+	total uint64
+	buf   [32]byte
+	n     int
+}
+
+func (h *xxhash) reset() {
+	// the lanes start from wrapping sums, which constants can't express
+	p1 := prime1
+	h.v = [4]uint64{p1 + prime2, prime2, 0, -p1}
+	h.total = 0
+	h.n = 0
+}
+
+func xxround(acc, input uint64) uint64 {
+	acc += input * prime2
+	return bits.RotateLeft64(acc, 31) * prime1
+}
+
+func xxmerge(acc, v uint64) uint64 {
+	acc ^= xxround(0, v)
+	return acc*prime1 + prime4
+}
+
+func (h *xxhash) write(p []byte) {
+	h.total += uint64(len(p))
+	if h.n > 0 {
+		n := copy(h.buf[h.n:], p)
+		h.n += n
+		p = p[n:]
+		if h.n < len(h.buf) {
+			return
+		}
+		h.stripes(h.buf[:])
+		h.n = 0
+	}
+	if len(p) >= len(h.buf) {
+		n := len(p) &^ (len(h.buf) - 1)
+		h.stripes(p[:n])
+		p = p[n:]
+	}
+	h.n = copy(h.buf[:], p)
+}
+
+// stripes hashes p, whose length is a multiple of 32
+func (h *xxhash) stripes(p []byte) {
+	for ; len(p) >= 32; p = p[32:] {
+		h.v[0] = xxround(h.v[0], binary.LittleEndian.Uint64(p))
+		h.v[1] = xxround(h.v[1], binary.LittleEndian.Uint64(p[8:]))
+		h.v[2] = xxround(h.v[2], binary.LittleEndian.Uint64(p[16:]))
+		h.v[3] = xxround(h.v[3], binary.LittleEndian.Uint64(p[24:]))
+	}
+}
+
+func (h *xxhash) sum64() uint64 {
+	var sum uint64
+	if h.total >= 32 {
+		sum = bits.RotateLeft64(h.v[0], 1) + bits.RotateLeft64(h.v[1], 7) + bits.RotateLeft64(h.v[2], 12) + bits.RotateLeft64(h.v[3], 18)
+		for _, v := range h.v {
+			sum = xxmerge(sum, v)
+		}
+	} else {
+		sum = prime5
+	}
+	sum += h.total
+	p := h.buf[:h.n]
+	for ; len(p) >= 8; p = p[8:] {
+		sum ^= xxround(0, binary.LittleEndian.Uint64(p))
+		sum = bits.RotateLeft64(sum, 27)*prime1 + prime4
+	}
+	if len(p) >= 4 {
+		sum ^= uint64(binary.LittleEndian.Uint32(p)) * prime1
+		sum = bits.RotateLeft64(sum, 23)*prime2 + prime3
+		p = p[4:]
+	}
+	for _, b := range p {
+		sum ^= uint64(b) * prime5
+		sum = bits.RotateLeft64(sum, 11) * prime1
+	}
+	sum ^= sum >> 33
+	sum *= prime2
+	sum ^= sum >> 29
+	sum *= prime3
+	sum ^= sum >> 32
+	return sum
+}
@@ -0,0 +1,55 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package zstd is a minimal implementation of the Zstandard compression
+// format (RFC 8878), covering what the SDK needs to compress transfers.
+// The Reader decodes any frame without a dictionary. The Writer favours
+// speed: it finds repeated data, long runs in particular, but leaves
+// literals uncompressed.
+package zstd
+
+import "errors"
+
+// magic starts every zstd frame
+const magic = 0xFD2FB528
+
+// skippable frames start with magic numbers 0x184D2A50 to 0x184D2A5F
+const (
+	skippableMagic = 0x184D2A50
+	skippableMask  = 0xFFFFFFF0
+)
+
+// maxBlockSize bounds the content of a block
+const maxBlockSize = 128 << 10
+
+// maxWindowSize is the largest window the Reader allocates, frames asking
+// for more are refused. It matches the default limit of the reference
+// implementation.
+const maxWindowSize = 1 << 27
+
+// ErrCorrupt is wrapped by the errors reading invalid compressed data
+var ErrCorrupt = errors.New("zstd: corrupt input")
+
+// corruptError describes what is invalid about the compressed data
+type corruptError string
+
+func (e corruptError) Error() string { return "zstd: corrupt input: " + string(e) }
+
+func (e corruptError) Unwrap() error { return ErrCorrupt }
+
+func errCorrupt(msg string) error {
+	return corruptError(msg)
+}
@@ -0,0 +1,215 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package zstd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"path/filepath"
+	"testing"
+)
+
+// the content of the frames in testdata, compressed by the reference
+// implementation at levels 1, 19 and 22, the last without checksum
+const wordsSHA256 = "7a4f67829c132d200de0ea1faae30d72e4c1c7b86cfbe3c43202edcacc38a383"
+
+func TestReaderReference(t *testing.T) {
+	files, err := filepath.Glob("testdata/*.zst")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no frames in testdata: %v", err)
+	}
+	for _, name := range files {
+		data, err := ioutil.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := ioutil.ReadAll(NewReader(bytes.NewReader(data)))
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if sum := sha256.Sum256(content); hex.EncodeToString(sum[:]) != wordsSHA256 {
+			t.Errorf("%s: decoded %d bytes of different content", name, len(content))
+		}
+	}
+}
+
+func compress(t *testing.T, content []byte) []byte {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	// in uneven writes, across blocks
+	for p := content; len(p) > 0; {
+		n := 1 + rand.Intn(100000)
+		if n > len(p) {
+			n = len(p)
+		}
+		if _, err := w.Write(p[:n]); err != nil {
+			t.Fatal(err)
+		}
+		p = p[n:]
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestRoundTrip(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	random := func(n int) []byte {
+		b := make([]byte, n)
+		rnd.Read(b)
+		return b
+	}
+	words, err := ioutil.ReadAll(NewReader(mustOpen(t, "testdata/words.19.zst")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// zeros with some data every 100 KiB, like a sparse disk image
+	sparse := make([]byte, 3<<20)
+	for i := 0; i < len(sparse); i += 100 << 10 {
+		copy(sparse[i:], random(5000))
+	}
+	// repeats further back than the window
+	far := append(random(1<<20), make([]byte, 1<<20)...)
+	far = append(far, far[:1<<20]...)
+	tests := []struct {
+		name    string
+		content []byte
+		// max is the largest acceptable compressed size
+		max int
+	}{
+		{"empty", nil, 13},
+		{"byte", []byte{'x'}, 14},
+		{"short", []byte("hello, hello, hello world"), 40},
+		{"words", words, len(words) / 2},
+		{"zeros", make([]byte, 1<<20), 100},
+		{"sparse", sparse, len(sparse) / 15},
+		{"random", random(300 << 10), 300<<10 + 100},
+		{"far", far, 2<<20 + 1000},
+	}
+	for _, tt := range tests {
+		compressed := compress(t, tt.content)
+		if len(compressed) > tt.max {
+			t.Errorf("%s: compressed %d bytes to %d, want at most %d", tt.name, len(tt.content), len(compressed), tt.max)
+		}
+		content, err := ioutil.ReadAll(NewReader(bytes.NewReader(compressed)))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !bytes.Equal(content, tt.content) {
+			t.Errorf("%s: decoded %d bytes of different content", tt.name, len(content))
+		}
+	}
+}
+
+func mustOpen(t *testing.T, name string) io.Reader {
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bytes.NewReader(data)
+}
+
+func TestReaderFrames(t *testing.T) {
+	skippable := make([]byte, 8, 13)
+	binary.LittleEndian.PutUint32(skippable, skippableMagic+3)
+	binary.LittleEndian.PutUint32(skippable[4:], 5)
+	skippable = append(skippable, "skip!"...)
+	var stream []byte
+	stream = append(stream, compress(t, []byte("first frame, "))...)
+	stream = append(stream, skippable...)
+	stream = append(stream, compress(t, nil)...)
+	stream = append(stream, compress(t, []byte("last frame"))...)
+	content, err := ioutil.ReadAll(NewReader(bytes.NewReader(stream)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "first frame, last frame" {
+		t.Errorf("decoded %q", content)
+	}
+}
+
+func TestReaderCorrupt(t *testing.T) {
+	frame := compress(t, bytes.Repeat([]byte("corrupt me "), 1000))
+	flip := func(i int) []byte {
+		data := append([]byte(nil), frame...)
+		data[i] ^= 0x10
+		return data
+	}
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"truncated", frame[:len(frame)-3], io.ErrUnexpectedEOF},
+		{"checksum", flip(len(frame) - 1), ErrCorrupt},
+		{"magic", flip(0), ErrCorrupt},
+		{"reserved bit", append(append([]byte(nil), frame[:4]...), frame[4]|0x08), ErrCorrupt},
+	}
+	for _, tt := range tests {
+		if _, err := ioutil.ReadAll(NewReader(bytes.NewReader(tt.data))); !errors.Is(err, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestXXHash(t *testing.T) {
+	tests := []struct {
+		in   string
+		want uint64
+	}{
+		{"", 0xef46db3751d8e999},
+		{"a", 0xd24ec4f1a98c6e5b},
+		{"abc", 0x44bc2cf5ad770999},
+	}
+	var h xxhash
+	for _, tt := range tests {
+		h.reset()
+		h.write([]byte(tt.in))
+		if got := h.sum64(); got != tt.want {
+			t.Errorf("xxhash(%q) = %#x, want %#x", tt.in, got, tt.want)
+		}
+	}
+}
+
+func FuzzReader(f *testing.F) {
+	files, _ := filepath.Glob("testdata/*.zst")
+	for _, name := range files {
+		data, err := ioutil.ReadFile(name)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(data[:200])
+	}
+	f.Add([]byte{0x28, 0xb5, 0x2f, 0xfd, 0x04, 0x50, 0x01, 0x00, 0x00})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// decoding must fail gracefully, with bounded output
+		io.CopyN(ioutil.Discard, NewReader(bytes.NewReader(data)), 8<<20)
+		content, err := ioutil.ReadAll(NewReader(bytes.NewReader(compress(t, data))))
+		if err != nil || !bytes.Equal(content, data) {
+			t.Errorf("round trip of %d bytes failed: %v", len(data), err)
+		}
+	})
+}
@@ -0,0 +1,261 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+)
+
+// DefaultIPFSAPI is the address of the HTTP RPC API of a local IPFS daemon
+const DefaultIPFSAPI = "http://127.0.0.1:5001"
+
+// ErrNoIPFSClient is returned when a method needs IPFS but none is configured
+var ErrNoIPFSClient = errors.New("no IPFS client configured")
+
+// IPFSClient adds and retrieves content with the HTTP RPC API of an IPFS
+// daemon, such as kubo
+type IPFSClient struct {
+	api    string
+	client *http.Client
+}
+
+// NewIPFSClient returns a client of the IPFS API at apiURL, DefaultIPFSAPI
+// if empty, sending requests with client, http.DefaultClient if nil
+func NewIPFSClient(apiURL string, client *http.Client) *IPFSClient {
+	if apiURL == "" {
+		apiURL = DefaultIPFSAPI
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &IPFSClient{api: strings.TrimSuffix(apiURL, "/"), client: client}
+}
+
+// IPFSError is returned when the IPFS daemon fails a request
+type IPFSError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *IPFSError) Error() string {
+	return fmt.Sprintf("ipfs (%d): %s", e.StatusCode, e.Message)
+}
+
+// do posts a request to the API command cmd, all commands are POSTs
+func (c *IPFSClient) do(ctx context.Context, cmd string, query url.Values, body io.Reader, contentType string) (*http.Response, error) {
+	req, err := http.NewRequest("POST", c.api+"/api/v0/"+cmd+"?"+query.Encode(), body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := c.client.Do(req.WithContext(ctx))
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		data, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 4096))
+		var payload struct {
+			Message string `json:"Message"`
+		}
+		msg := strings.TrimSpace(string(data))
+		if json.Unmarshal(data, &payload) == nil && payload.Message != "" {
+			msg = payload.Message
+		}
+		return nil, &IPFSError{StatusCode: resp.StatusCode, Message: msg}
+	}
+	return resp, nil
+}
+
+// Add adds the content of r as a file called name, pinning it, and returns
+// its CID
+func (c *IPFSClient) Add(ctx context.Context, name string, r io.Reader) (string, error) {
+	pr, pw := io.Pipe()
+	form := multipart.NewWriter(pw)
+	go func() {
+		part, err := form.CreateFormFile("file", name)
+		if err == nil {
+			_, err = io.Copy(part, r)
+		}
+		if err == nil {
+			err = form.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+	query := url.Values{"pin": {"true"}, "cid-version": {"1"}, "progress": {"false"}}
+	resp, err := c.do(ctx, "add", query, pr, form.FormDataContentType())
+	pr.CloseWithError(err)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	// the answer is a JSON object per added file
+	var added struct {
+		Hash string `json:"Hash"`
+	}
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var entry struct {
+			Hash string `json:"Hash"`
+		}
+		if err := dec.Decode(&entry); err == io.EOF {
+			break
+		} else if err != nil {
+			return "", fmt.Errorf("ipfs add: invalid answer: %v", err)
+		}
+		if entry.Hash != "" {
+			added = entry
+		}
+	}
+	if added.Hash == "" {
+		return "", errors.New("ipfs add: no CID returned")
+	}
+	return added.Hash, nil
+}
+
+// AddFile adds the file to IPFS, pinning it, and returns its CID
+func (c *IPFSClient) AddFile(ctx context.Context, filename string) (string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return c.Add(ctx, filepath.Base(filename), f)
+}
+
+// Cat writes the content with the given CID to w
+func (c *IPFSClient) Cat(ctx context.Context, cid string, w io.Writer) error {
+	resp, err := c.do(ctx, "cat", url.Values{"arg": {cid}}, nil, "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// ipfsDistribution distributes uploads over IPFS, see WithIPFS
+type ipfsDistribution struct {
+	client *IPFSClient
+	// unsupported is set once the node turned out not to fetch from IPFS
+	unsupported int32
+}
+
+// fetchIPFS has the node fetch the content with the given CID from IPFS and
+// store it, reporting false if the node can't
+func (rpc *CCClient) fetchIPFS(ctx context.Context, cid string) (*UploadResult, bool, error) {
+	if rpc.ipfs != nil && atomic.LoadInt32(&rpc.ipfs.unsupported) != 0 {
+		return nil, false, nil
+	}
+	result := new(UploadResult)
+	err := rpc.callInto(ctx, result, "imagemanager_fetchIPFS", cid)
+	if isMethodNotFound(err) {
+		if rpc.ipfs != nil {
+			atomic.StoreInt32(&rpc.ipfs.unsupported, 1)
+		}
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	result.CID = cid
+	return result, true, nil
+}
+
+// uploadViaIPFS publishes the file to IPFS and has the node fetch it from
+// there, reporting false with the CID if the node can't
+func (rpc *CCClient) uploadViaIPFS(ctx context.Context, filename, hash string) (*UploadResult, string, bool, error) {
+	cid, err := rpc.ipfs.client.AddFile(ctx, filename)
+	if err != nil {
+		return nil, "", false, fmt.Errorf("publishing %s to IPFS: %w", filename, err)
+	}
+	result, ok, err := rpc.fetchIPFS(ctx, cid)
+	if err != nil || !ok {
+		return nil, cid, false, err
+	}
+	if hash == "" {
+		if hash, err = HashFile(filename); err != nil {
+			return nil, cid, false, err
+		}
+	}
+	// content fetched by CID is verified like direct uploads
+	if !sameHash(hash, result.Hash) {
+		return nil, cid, false, &ChecksumError{Name: filename, Expected: hash, Actual: result.Hash}
+	}
+	if result.Filename == "" {
+		result.Filename = filepath.Base(filename)
+	}
+	return result, cid, true, nil
+}
+
+// UploadFromIPFS stores the content with the given CID on the node and
+// returns the hash it is stored under. Nodes able to fetch from IPFS do so
+// themselves, to others the content is relayed through the IPFS client of
+// WithIPFS and the upload client.
+func (rpc *CCClient) UploadFromIPFS(ctx context.Context, cid string) (*UploadResult, error) {
+	result, ok, err := rpc.fetchIPFS(ctx, cid)
+	if err != nil || ok {
+		return result, err
+	}
+	if rpc.ipfs == nil {
+		return nil, ErrNoIPFSClient
+	}
+	if rpc.uploader == nil {
+		return nil, ErrNoUploadClient
+	}
+	token, err := rpc.authToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(rpc.ipfs.client.Cat(ctx, cid, pw))
+	}()
+	result, err = rpc.uploader.UploadReaderContext(ctx, cid, pr, -1, token)
+	pr.CloseWithError(err)
+	if err != nil {
+		return nil, err
+	}
+	result.CID = cid
+	return result, nil
+}
+
+// PublishToIPFS adds the file to the IPFS client of WithIPFS and returns its
+// CID, e.g. to hand to other clients or nodes
+func (rpc *CCClient) PublishToIPFS(ctx context.Context, filename string) (string, error) {
+	if rpc.ipfs == nil {
+		return "", ErrNoIPFSClient
+	}
+	return rpc.ipfs.client.AddFile(ctx, filename)
+}
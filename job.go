@@ -0,0 +1,338 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// jobCandidateNodes is the number of workers discovered to place a job on
+const jobCandidateNodes = 10
+
+// ErrNoNodes is returned when no worker node is available for a job
+var ErrNoNodes = errors.New("no worker nodes available")
+
+// JobInput is a local file or directory made available to a job's container
+type JobInput struct {
+	// Source is the local path, uploaded when the job runs
+	Source string
+	// Path is the container directory Source is placed in
+	Path string
+}
+
+// JobSpec describes a job: an uploaded image run to completion on a worker node
+type JobSpec struct {
+	// ImageHash is the hash of the uploaded image tarball
+	ImageHash string
+	// NodeID pins the job to a worker, otherwise one matching Filter is picked
+	NodeID string
+	Filter NodeFilter
+	Inputs []JobInput
+	// OutputPath is a file or directory copied out of the container after it
+	// exited into the local directory OutputDir
+	OutputPath string
+	OutputDir  string
+	Options    RunOptions
+	// MaxRedispatches is how often the job is moved to another discovered
+	// worker after a node failure, see IsNodeFailure
+	MaxRedispatches int
+	// DryRun only places the job and validates it on the worker, nothing is
+	// uploaded or started. The result reports the validation in Plan.
+	DryRun bool
+	// OnStateChange is called whenever the job moves to another state, from
+	// the goroutine running it
+	OnStateChange func(JobStateChange)
+	// RemoveImageOnCancel removes the pushed image from the worker when the
+	// job is cancelled, besides its container
+	RemoveImageOnCancel bool
+	// AutoHeal restarts the job when its container crashes or its worker
+	// fails, regardless of MaxRedispatches
+	AutoHeal *AutoHeal
+}
+
+// JobAttempt records one attempt at running a job on a worker node
+type JobAttempt struct {
+	NodeID      string `json:"nodeID"`
+	ContainerID string `json:"containerID,omitempty"`
+	ExitCode    int    `json:"exitCode"`
+	Error       string `json:"error,omitempty"`
+}
+
+// Artifact is a file produced by a job
+type Artifact struct {
+	// Name is the path of the file relative to the job's output directory
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+	// Hash is the hex encoded sha256 of the file
+	Hash string `json:"hash"`
+	// URL is where the file can be downloaded from
+	URL string `json:"url"`
+}
+
+// JobResult is the outcome of a job
+type JobResult struct {
+	NodeID      string `json:"nodeID"`
+	ImageID     string `json:"imageID"`
+	ContainerID string `json:"containerID"`
+	ExitCode    int    `json:"exitCode"`
+	// State is the final state of the job
+	State JobState `json:"state"`
+
+	StartedAt  time.Time     `json:"startedAt"`
+	FinishedAt time.Time     `json:"finishedAt"`
+	Duration   time.Duration `json:"duration"`
+	// LogTail holds the last lines the container wrote
+	LogTail []string `json:"logTail,omitempty"`
+
+	// OutputDir holds the job's outputs, if it had any
+	OutputDir string `json:"outputDir,omitempty"`
+	// OutputArchive is the tar archive of the outputs stored on the node
+	OutputArchive *Artifact `json:"outputArchive,omitempty"`
+	// Artifacts lists the files in OutputDir, with file:// urls
+	Artifacts []Artifact `json:"artifacts,omitempty"`
+
+	// Attempts lists every attempt, the last one produced the result
+	Attempts []JobAttempt `json:"attempts"`
+	// Plan is the validation of a dry run
+	Plan *RunPlan `json:"plan,omitempty"`
+}
+
+// ExitError is returned for jobs whose container exited with a non-zero code
+type ExitError struct {
+	ExitCode int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("container exited with code %d", e.ExitCode)
+}
+
+// NodeError is returned when a job failed because of its worker node rather
+// than its own code: the node was unreachable or docker failed to run it
+type NodeError struct {
+	NodeID string
+	Err    error
+}
+
+func (e *NodeError) Error() string {
+	return fmt.Sprintf("node %s: %v", e.NodeID, e.Err)
+}
+
+func (e *NodeError) Unwrap() error {
+	return e.Err
+}
+
+// IsNodeFailure reports whether err is a job failure caused by the worker node
+func IsNodeFailure(err error) bool {
+	var nodeErr *NodeError
+	return errors.As(err, &nodeErr)
+}
+
+// jobLogTailLines is the number of log lines kept in a JobResult
+const jobLogTailLines = 50
+
+// dockerRunFailure is the exit code docker reports when it failed to run a container
+const dockerRunFailure = 125
+
+// RunJob places the job on a worker node, pushes its image and inputs, runs it
+// and waits for it to exit, then collects its outputs. The container is stopped
+// if ctx is done first. After a node failure the job is moved to another worker
+// up to spec.MaxRedispatches times. Once a worker was tried the result is
+// returned even on failure, so that the attempts and outputs can be inspected.
+// Crashed jobs are restarted according to spec.AutoHeal. The job's state
+// changes are reported to spec.OnStateChange.
+func (rpc *CCClient) RunJob(ctx context.Context, spec JobSpec) (*JobResult, error) {
+	if spec.DryRun {
+		return rpc.dryRunJob(ctx, spec)
+	}
+	tracker := &jobTracker{nodeID: spec.NodeID, onChange: spec.OnStateChange}
+	tracker.set(JobQueued, nil)
+	opts := spec.Options
+	opts.Inputs = append([]RunInput(nil), opts.Inputs...)
+	for _, in := range spec.Inputs {
+		tracker.set(JobTransferring, nil)
+		hash, err := rpc.uploadTar(ctx, in.Source)
+		if err != nil {
+			tracker.finish(ctx, err)
+			return nil, err
+		}
+		opts.Inputs = append(opts.Inputs, RunInput{Hash: hash, Path: in.Path})
+	}
+
+	var attempts []JobAttempt
+	tried := make(map[string]bool)
+	nodeID := spec.NodeID
+	restarts := 0
+	for {
+		if nodeID == "" {
+			var err error
+			if nodeID, err = rpc.pickNode(ctx, spec.Filter, tried); err != nil {
+				tracker.finish(ctx, err)
+				if len(attempts) == 0 {
+					return nil, err
+				}
+				return &JobResult{Attempts: attempts, State: tracker.state}, err
+			}
+		}
+		tried[nodeID] = true
+		tracker.nodeID = nodeID
+		tracker.set(JobQueued, nil)
+		result, err := rpc.runJobOn(ctx, spec, opts, nodeID, tracker)
+		healing := spec.AutoHeal.heals(ctx, err, restarts)
+		if healing {
+			tracker.set(JobRestarting, err)
+		} else {
+			tracker.finish(ctx, err)
+		}
+		attempt := JobAttempt{NodeID: nodeID, ContainerID: result.ContainerID, ExitCode: result.ExitCode}
+		if err != nil {
+			attempt.Error = err.Error()
+		}
+		attempts = append(attempts, attempt)
+		result.Attempts = attempts
+		result.State = tracker.state
+		if healing {
+			restarts++
+			if !spec.AutoHeal.wait(ctx, restarts) {
+				tracker.set(JobCancelled, ctx.Err())
+				result.State = tracker.state
+				return result, ctx.Err()
+			}
+			if spec.AutoHeal.Relocate || IsNodeFailure(err) {
+				// only avoid the failed worker, services may outlive the pool
+				tried = map[string]bool{nodeID: true}
+				nodeID = ""
+			}
+			continue
+		}
+		if err == nil || !IsNodeFailure(err) || len(attempts) > spec.MaxRedispatches || ctx.Err() != nil {
+			return result, err
+		}
+		nodeID = ""
+	}
+}
+
+// runJobOn runs the job once on the given worker node
+func (rpc *CCClient) runJobOn(ctx context.Context, spec JobSpec, opts RunOptions, nodeID string, tracker *jobTracker) (*JobResult, error) {
+	result := &JobResult{NodeID: nodeID}
+	nodeErr := func(err error) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return &NodeError{NodeID: nodeID, Err: err}
+	}
+	var err error
+	tracker.set(JobTransferring, nil)
+	if result.ImageID, err = rpc.pushImage(ctx, nodeID, spec.ImageHash); err != nil {
+		return result, nodeErr(err)
+	}
+	if result.ContainerID, err = rpc.executeImageWithOptions(ctx, nodeID, result.ImageID, opts); err != nil {
+		return result, nodeErr(err)
+	}
+	tracker.set(JobRunning, nil)
+	state, err := rpc.WaitForContainer(ctx, nodeID, result.ContainerID)
+	if err != nil {
+		if ctx.Err() != nil {
+			rpc.CancelContainer(nodeID, result.ContainerID)
+			if spec.RemoveImageOnCancel {
+				rpc.RemoveImage(nodeID, result.ImageID)
+			}
+		}
+		return result, nodeErr(err)
+	}
+	result.ExitCode = state.ExitCode
+	result.StartedAt = state.StartedAt
+	result.FinishedAt = state.FinishedAt
+	if result.FinishedAt.After(result.StartedAt) {
+		result.Duration = result.FinishedAt.Sub(result.StartedAt)
+	}
+	if logs, err := rpc.ContainerLogs(nodeID, result.ContainerID, jobLogTailLines); err == nil && logs != "" {
+		result.LogTail = strings.Split(strings.TrimRight(logs, "\n"), "\n")
+	}
+	if state.JobState() == JobLost {
+		return result, nodeErr(fmt.Errorf("container failed: %s", state.Error))
+	}
+	if spec.OutputPath != "" {
+		result.OutputDir = spec.OutputDir
+		if result.OutputArchive, err = rpc.copyFromContainer(nodeID, result.ContainerID, spec.OutputPath, spec.OutputDir); err != nil {
+			return result, nodeErr(err)
+		}
+		if result.Artifacts, err = listArtifacts(spec.OutputDir); err != nil {
+			return result, err
+		}
+	}
+	if result.ExitCode != 0 {
+		return result, &ExitError{ExitCode: result.ExitCode}
+	}
+	return result, nil
+}
+
+// pickNode picks a random worker node matching filter, other than the excluded
+// and denied ones
+func (rpc *CCClient) pickNode(ctx context.Context, filter NodeFilter, exclude map[string]bool) (string, error) {
+	nodes, err := rpc.discoverWorkers(ctx, jobCandidateNodes+len(exclude), filter)
+	if err != nil {
+		return "", err
+	}
+	candidates := nodes[:0]
+	for _, n := range nodes {
+		if !exclude[n.ID] {
+			candidates = append(candidates, n)
+		}
+	}
+	if len(candidates) == 0 {
+		return "", ErrNoNodes
+	}
+	return candidates[rand.Intn(len(candidates))].ID, nil
+}
+
+// listArtifacts describes the files below dir
+func listArtifacts(dir string) ([]Artifact, error) {
+	var artifacts []Artifact
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.Mode().IsRegular() {
+			return err
+		}
+		name, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		hash, err := HashFile(path)
+		if err != nil {
+			return err
+		}
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return err
+		}
+		artifacts = append(artifacts, Artifact{
+			Name: filepath.ToSlash(name),
+			Size: info.Size(),
+			Hash: hash,
+			URL:  (&url.URL{Scheme: "file", Path: filepath.ToSlash(abs)}).String(),
+		})
+		return nil
+	})
+	return artifacts, err
+}
@@ -0,0 +1,126 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRunJobOnCancel(t *testing.T) {
+	tests := []struct {
+		// block is the call still running when the job is cancelled
+		block string
+		// stopped reports whether the container must be stopped
+		stopped bool
+	}{
+		{"imagemanager_pushImage", false},
+		{"imagemanager_runImageWithOptions", false},
+		{"imagemanager_inspectContainer", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.block, func(t *testing.T) {
+			var mu sync.Mutex
+			called := make(map[string]bool)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var req rpcRequest
+				json.NewDecoder(r.Body).Decode(&req)
+				mu.Lock()
+				called[req.Method] = true
+				mu.Unlock()
+				result := `true`
+				switch req.Method {
+				case tt.block:
+					if req.Method != "imagemanager_inspectContainer" {
+						// answer only once the client gave up
+						select {
+						case <-r.Context().Done():
+						case <-time.After(10 * time.Second):
+						}
+						return
+					}
+					result = `"{\"State\":{\"Status\":\"running\",\"Running\":true}}"`
+				case "imagemanager_pushImage":
+					result = `"i1"`
+				case "imagemanager_runImageWithOptions":
+					result = `"c1"`
+				}
+				w.Header().Set("Content-Type", "application/json")
+				fmt.Fprintf(w, `{"jsonrpc":"2.0","id":1,"result":%s}`, result)
+			}))
+			defer srv.Close()
+
+			rpc := NewCCClient(srv.URL, WithThrottleRetries(0, 0))
+			ctx, cancel := context.WithCancel(context.Background())
+			time.AfterFunc(100*time.Millisecond, cancel)
+			start := time.Now()
+			_, err := rpc.runJobOn(ctx, JobSpec{NodeID: "n1", ImageHash: "h1"}, RunOptions{}, "n1", &jobTracker{nodeID: "n1"})
+			if err != context.Canceled {
+				t.Errorf("runJobOn returned %v, want %v", err, context.Canceled)
+			}
+			if elapsed := time.Since(start); elapsed > 2*time.Second {
+				t.Errorf("runJobOn took %v to notice the cancellation", elapsed)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if called["imagemanager_stopContainer"] != tt.stopped {
+				t.Errorf("container stopped: %v, want %v", called["imagemanager_stopContainer"], tt.stopped)
+			}
+		})
+	}
+}
+
+func TestDryRunJobCancel(t *testing.T) {
+	tests := []struct {
+		name string
+		spec JobSpec
+	}{
+		{"placing", JobSpec{ImageHash: "h1", DryRun: true}},
+		{"validating", JobSpec{NodeID: "n1", ImageHash: "h1", DryRun: true}},
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ioutil.ReadAll(r.Body)
+		select {
+		case <-r.Context().Done():
+		case <-time.After(10 * time.Second):
+		}
+	}))
+	defer srv.Close()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rpc := NewCCClient(srv.URL, WithThrottleRetries(0, 0))
+			ctx, cancel := context.WithCancel(context.Background())
+			time.AfterFunc(100*time.Millisecond, cancel)
+			start := time.Now()
+			if _, err := rpc.RunJob(ctx, tt.spec); !errors.Is(err, context.Canceled) {
+				t.Errorf("RunJob returned %v, want %v", err, context.Canceled)
+			}
+			if elapsed := time.Since(start); elapsed > 2*time.Second {
+				t.Errorf("RunJob took %v to notice the cancellation", elapsed)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"context"
+	"time"
+)
+
+// JobState is the stage a job is in
+type JobState string
+
+const (
+	// JobQueued jobs wait to be placed on a worker node
+	JobQueued JobState = "queued"
+	// JobTransferring jobs have their inputs uploaded and image pushed
+	JobTransferring JobState = "transferring"
+	JobRunning      JobState = "running"
+	JobSucceeded    JobState = "succeeded"
+	// JobFailed jobs exited with a non-zero code or failed to start
+	JobFailed    JobState = "failed"
+	JobCancelled JobState = "cancelled"
+	// JobLost jobs failed because of their worker node, they are queued
+	// again while redispatches are left
+	JobLost JobState = "lost"
+	// JobRestarting jobs crashed and are queued again by JobSpec.AutoHeal
+	JobRestarting JobState = "restarting"
+)
+
+// jobTransitions are the states each state can move on to
+var jobTransitions = map[JobState][]JobState{
+	"":              {JobQueued},
+	JobQueued:       {JobTransferring, JobCancelled, JobFailed},
+	JobTransferring: {JobRunning, JobFailed, JobCancelled, JobLost, JobRestarting},
+	JobRunning:      {JobSucceeded, JobFailed, JobCancelled, JobLost, JobRestarting},
+	JobLost:         {JobQueued},
+	JobRestarting:   {JobQueued, JobCancelled},
+}
+
+// Terminal reports whether the job can't change its state anymore. Lost
+// jobs that aren't redispatched stay lost.
+func (s JobState) Terminal() bool {
+	return s == JobSucceeded || s == JobFailed || s == JobCancelled || s == JobLost
+}
+
+// CanTransition reports whether a job in state s can move to state to
+func (s JobState) CanTransition(to JobState) bool {
+	for _, next := range jobTransitions[s] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// JobStateChange is passed to JobSpec.OnStateChange
+type JobStateChange struct {
+	From   JobState
+	To     JobState
+	NodeID string
+	Time   time.Time
+	// Err is the failure causing a change to JobFailed or JobLost
+	Err error
+}
+
+// Exited reports whether the container ran and stopped
+func (s *ContainerState) Exited() bool {
+	return !s.Running && !s.Restarting && s.Status != "created"
+}
+
+// JobState maps the state of a job's container to the job's state
+func (s *ContainerState) JobState() JobState {
+	switch {
+	case !s.Exited():
+		if s.Status == "created" {
+			return JobTransferring
+		}
+		return JobRunning
+	case s.Dead || s.Error != "" || s.ExitCode == dockerRunFailure:
+		return JobLost
+	case s.ExitCode != 0:
+		return JobFailed
+	}
+	return JobSucceeded
+}
+
+// jobTracker moves a job through its states, reporting the changes
+type jobTracker struct {
+	state    JobState
+	nodeID   string
+	onChange func(JobStateChange)
+}
+
+// set moves the job to state to, ignoring changes that aren't valid
+// transitions, e.g. a second failure
+func (t *jobTracker) set(to JobState, err error) {
+	if !t.state.CanTransition(to) {
+		return
+	}
+	change := JobStateChange{From: t.state, To: to, NodeID: t.nodeID, Time: time.Now(), Err: err}
+	t.state = to
+	if t.onChange != nil {
+		t.onChange(change)
+	}
+}
+
+// finish moves the job to the final state of an attempt ending with err
+func (t *jobTracker) finish(ctx context.Context, err error) {
+	switch {
+	case err == nil:
+		t.set(JobSucceeded, nil)
+	case ctx.Err() != nil:
+		t.set(JobCancelled, err)
+	case IsNodeFailure(err):
+		t.set(JobLost, err)
+	default:
+		t.set(JobFailed, err)
+	}
+}
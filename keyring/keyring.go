@@ -0,0 +1,97 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package keyring stores account passphrases and session tokens in the
+// keyring of the operating system: the macOS keychain, the Secret Service
+// on Linux and the Windows Credential Manager.
+package keyring
+
+import (
+	"errors"
+	"sync"
+)
+
+// Service is the service name secrets are stored under
+const Service = "cc-go-sdk"
+
+var (
+	// ErrNotFound is returned when no secret is stored for a key
+	ErrNotFound = errors.New("keyring: secret not found")
+	// ErrUnsupported is returned on platforms without a supported keyring
+	ErrUnsupported = errors.New("keyring: not supported on this platform")
+)
+
+// PassphraseKey is the key the passphrase of account is stored under
+func PassphraseKey(account string) string {
+	return "passphrase/" + account
+}
+
+// TokenKey is the key the session token of account is stored under
+func TokenKey(account string) string {
+	return "token/" + account
+}
+
+// Keyring stores secrets by key
+type Keyring interface {
+	Get(key string) (string, error)
+	Set(key, secret string) error
+	Delete(key string) error
+}
+
+// System returns the keyring of the operating system. Its operations fail
+// with ErrUnsupported where there is none.
+func System() Keyring {
+	return system{}
+}
+
+// memory is a Keyring held in memory
+type memory struct {
+	mu      sync.Mutex
+	secrets map[string]string
+}
+
+// NewMemory returns a keyring keeping secrets in memory only, e.g. for
+// tests and short-lived processes
+func NewMemory() Keyring {
+	return &memory{secrets: make(map[string]string)}
+}
+
+func (m *memory) Get(key string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	secret, ok := m.secrets[key]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return secret, nil
+}
+
+func (m *memory) Set(key, secret string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.secrets[key] = secret
+	return nil
+}
+
+func (m *memory) Delete(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.secrets[key]; !ok {
+		return ErrNotFound
+	}
+	delete(m.secrets, key)
+	return nil
+}
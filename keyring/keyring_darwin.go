@@ -0,0 +1,81 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package keyring
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+// system stores secrets as generic passwords in the login keychain through
+// the security tool
+type system struct{}
+
+// security runs the security tool. Its exit code 44 means the item wasn't found.
+func security(args ...string) (string, error) {
+	return run(args[0], nil, args...)
+}
+
+// securityStdin runs a command of the security tool read from stdin, keeping
+// its arguments out of the process list. The tool doesn't fail with a non-zero
+// exit code in this mode, so any diagnostic output counts as failure.
+func securityStdin(args ...string) error {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(arg) + `"`
+	}
+	_, err := run(args[0], strings.NewReader(strings.Join(quoted, " ")+"\n"), "-i")
+	return err
+}
+
+// run runs the security tool, reporting errors of the command name
+func run(name string, stdin io.Reader, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("security", args...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = stdin, &stdout, &stderr
+	err := cmd.Run()
+	if exit, ok := err.(*exec.ExitError); ok && exit.ExitCode() == 44 {
+		return "", ErrNotFound
+	}
+	if err == nil && stdin != nil && stderr.Len() > 0 {
+		err = errors.New("failed")
+	}
+	if err != nil {
+		return "", fmt.Errorf("keyring: security %s: %v: %s", name, err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSuffix(stdout.String(), "\n"), nil
+}
+
+func (system) Get(key string) (string, error) {
+	return security("find-generic-password", "-s", Service, "-a", key, "-w")
+}
+
+// Set passes the secret hex encoded on stdin rather than as an argument,
+// where other processes could read it
+func (system) Set(key, secret string) error {
+	return securityStdin("add-generic-password", "-U", "-s", Service, "-a", key, "-X", hex.EncodeToString([]byte(secret)))
+}
+
+func (system) Delete(key string) error {
+	_, err := security("delete-generic-password", "-s", Service, "-a", key)
+	return err
+}
@@ -0,0 +1,35 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+//go:build !darwin && !linux && !windows
+// +build !darwin,!linux,!windows
+
+package keyring
+
+// system is a placeholder for platforms without a supported keyring
+type system struct{}
+
+func (system) Get(key string) (string, error) {
+	return "", ErrUnsupported
+}
+
+func (system) Set(key, secret string) error {
+	return ErrUnsupported
+}
+
+func (system) Delete(key string) error {
+	return ErrUnsupported
+}
@@ -0,0 +1,249 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/crowdcompute/cc-go-sdk/internal/docker"
+)
+
+// LocalNodeID is the node id reported for jobs run by a LocalBackend
+const LocalNodeID = "local"
+
+// JobRunner runs jobs, on the network or locally
+type JobRunner interface {
+	RunJob(ctx context.Context, spec JobSpec) (*JobResult, error)
+}
+
+// Backend uploads images and runs jobs with them. It is implemented by
+// CCClient, running on the network, and LocalBackend, running on the local
+// docker daemon.
+type Backend interface {
+	JobRunner
+	// UploadImage uploads a docker save tarball and returns the hash to
+	// reference it in a JobSpec
+	UploadImage(ctx context.Context, filename string) (*UploadResult, error)
+}
+
+// NewBackend returns a LocalBackend if local is set and otherwise a client of
+// the node at url, so that pipelines switch between both with one flag
+func NewBackend(url string, local bool, opts ...Option) (Backend, error) {
+	if local {
+		return NewLocalBackend()
+	}
+	return NewCCClient(url, opts...), nil
+}
+
+// UploadImage uploads the docker save tarball filename to the node
+func (rpc *CCClient) UploadImage(ctx context.Context, filename string) (*UploadResult, error) {
+	if rpc.uploader == nil {
+		return nil, ErrNoUploadClient
+	}
+	token, err := rpc.authToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return rpc.uploadFile(ctx, filename, token)
+}
+
+// LocalBackend runs jobs on the docker daemon of the host, set by
+// DOCKER_HOST, without involving a CrowdCompute node. It is meant for
+// testing pipelines offline; placement, redispatching, auto-healing,
+// RunOptions.GPUs and RunOptions.Restart don't apply.
+type LocalBackend struct {
+	docker *docker.Client
+
+	mu sync.Mutex
+	// images maps the hashes of uploaded tarballs to the loaded images
+	images map[string]string
+}
+
+// NewLocalBackend creates a backend for the local docker daemon
+func NewLocalBackend() (*LocalBackend, error) {
+	client, err := docker.NewClientFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	return &LocalBackend{docker: client, images: make(map[string]string)}, nil
+}
+
+// UploadImage loads the docker save tarball filename into the local daemon.
+// The returned hash is the sha256 of the file, like the node would report.
+func (b *LocalBackend) UploadImage(ctx context.Context, filename string) (*UploadResult, error) {
+	hash, err := HashFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	fh, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+	info, err := fh.Stat()
+	if err != nil {
+		return nil, err
+	}
+	refs, err := b.docker.LoadImage(ctx, fh)
+	if err != nil {
+		return nil, err
+	}
+	if len(refs) == 0 {
+		return nil, fmt.Errorf("no image found in %s", filename)
+	}
+	b.mu.Lock()
+	b.images[hash] = refs[0]
+	b.mu.Unlock()
+	return &UploadResult{Hash: hash, Size: info.Size(), Filename: filepath.Base(filename)}, nil
+}
+
+// image resolves the hash of an uploaded image. Anything else is taken to
+// be the name or id of an image already known to the daemon.
+func (b *LocalBackend) image(imageHash string) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if ref, ok := b.images[imageHash]; ok {
+		return ref
+	}
+	return imageHash
+}
+
+// RunJob runs the job like CCClient.RunJob, in a container of the local
+// daemon. Inputs are copied into the container before it starts, it is
+// removed once its outputs were collected.
+func (b *LocalBackend) RunJob(ctx context.Context, spec JobSpec) (*JobResult, error) {
+	if len(spec.Options.Inputs) > 0 {
+		return nil, errors.New("local backend: inputs uploaded to a node are not supported, use JobSpec.Inputs")
+	}
+	image := b.image(spec.ImageHash)
+	if spec.DryRun {
+		return b.dryRunJob(spec, image), nil
+	}
+	if spec.Options.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, spec.Options.Timeout)
+		defer cancel()
+	}
+	tracker := &jobTracker{nodeID: LocalNodeID, onChange: spec.OnStateChange}
+	tracker.set(JobQueued, nil)
+	result := &JobResult{NodeID: LocalNodeID, ImageID: image}
+	err := b.runJob(ctx, spec, result, tracker)
+	tracker.finish(ctx, err)
+	result.State = tracker.state
+	attempt := JobAttempt{NodeID: LocalNodeID, ContainerID: result.ContainerID, ExitCode: result.ExitCode}
+	if err != nil {
+		attempt.Error = err.Error()
+	}
+	result.Attempts = []JobAttempt{attempt}
+	return result, err
+}
+
+func (b *LocalBackend) runJob(ctx context.Context, spec JobSpec, result *JobResult, tracker *jobTracker) error {
+	var err error
+	if result.ContainerID, err = b.docker.CreateContainer(ctx, docker.ContainerConfig{Image: result.ImageID}); err != nil {
+		return err
+	}
+	// the container is removed even if ctx is done already
+	defer b.docker.RemoveContainer(context.Background(), result.ContainerID)
+	tracker.set(JobTransferring, nil)
+	for _, in := range spec.Inputs {
+		if err := b.copyIn(ctx, result.ContainerID, in); err != nil {
+			return err
+		}
+	}
+	if err := b.docker.StartContainer(ctx, result.ContainerID); err != nil {
+		return err
+	}
+	tracker.set(JobRunning, nil)
+	if result.ExitCode, err = b.docker.WaitContainer(ctx, result.ContainerID); err != nil {
+		if ctx.Err() != nil {
+			b.docker.StopContainer(context.Background(), result.ContainerID)
+			return ctx.Err()
+		}
+		return err
+	}
+	if state, err := b.docker.InspectContainer(ctx, result.ContainerID); err == nil {
+		result.StartedAt = state.StartedAt
+		result.FinishedAt = state.FinishedAt
+		if result.FinishedAt.After(result.StartedAt) {
+			result.Duration = result.FinishedAt.Sub(result.StartedAt)
+		}
+	}
+	if logs, err := b.docker.ContainerLogs(ctx, result.ContainerID, jobLogTailLines); err == nil && logs != "" {
+		result.LogTail = strings.Split(strings.TrimRight(logs, "\n"), "\n")
+	}
+	if spec.OutputPath != "" {
+		result.OutputDir = spec.OutputDir
+		if err := b.copyOut(ctx, result.ContainerID, spec.OutputPath, spec.OutputDir); err != nil {
+			return err
+		}
+		if result.Artifacts, err = listArtifacts(spec.OutputDir); err != nil {
+			return err
+		}
+	}
+	if result.ExitCode != 0 {
+		return &ExitError{ExitCode: result.ExitCode}
+	}
+	return nil
+}
+
+// copyIn tars the input on the fly into its container directory
+func (b *LocalBackend) copyIn(ctx context.Context, containerID string, in JobInput) error {
+	src := filepath.Clean(in.Source)
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(writeTar(pw, src, filepath.Dir(src)))
+	}()
+	err := b.docker.CopyToContainer(ctx, containerID, in.Path, pr)
+	pr.CloseWithError(err)
+	return err
+}
+
+// copyOut extracts srcPath of the container into the local directory dstPath
+func (b *LocalBackend) copyOut(ctx context.Context, containerID, srcPath, dstPath string) error {
+	if err := os.MkdirAll(dstPath, 0755); err != nil {
+		return err
+	}
+	archive, err := b.docker.CopyFromContainer(ctx, containerID, srcPath)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+	return extractTar(archive, dstPath)
+}
+
+// dryRunJob checks that the inputs exist, the image isn't checked until it runs
+func (b *LocalBackend) dryRunJob(spec JobSpec, image string) *JobResult {
+	plan := &RunPlan{NodeID: LocalNodeID, ImageID: image, Valid: true}
+	for _, in := range spec.Inputs {
+		if _, err := os.Stat(in.Source); err != nil {
+			plan.Valid = false
+			plan.Problems = append(plan.Problems, err.Error())
+		}
+	}
+	if spec.Options.GPUs != nil {
+		plan.Warnings = append(plan.Warnings, "GPU requests are ignored by the local backend")
+	}
+	return &JobResult{NodeID: LocalNodeID, ImageID: image, Plan: plan}
+}
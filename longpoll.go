@@ -0,0 +1,112 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	// longPollWait is how long the node holds a poll open without events,
+	// short enough to pass proxies cutting idle requests
+	longPollWait = 25 * time.Second
+	// minLongPollInterval paces polls the node doesn't hold open
+	minLongPollInterval = time.Second
+	// longPollUnsubscribeTimeout bounds the unsubscribe call of close
+	longPollUnsubscribeTimeout = 5 * time.Second
+)
+
+// longPollSubscription is a subscription whose notifications are fetched
+// with namespace_poll calls, which the node holds open until some arrive
+type longPollSubscription struct {
+	rpc       *CCClient
+	ctx       context.Context
+	cancel    context.CancelFunc
+	namespace string
+	id        string
+	pending   []json.RawMessage
+	closeOnce sync.Once
+}
+
+// longPollSubscribe subscribes to the given namespace's events, calling
+// namespace_subscribe with params over plain calls
+func (rpc *CCClient) longPollSubscribe(ctx context.Context, namespace string, params ...interface{}) (*longPollSubscription, error) {
+	var id string
+	err := rpc.callInto(ctx, &id, namespace+"_subscribe", params...)
+	if isMethodNotFound(err) {
+		return nil, &transportError{transport: TransportLongPoll, err: err}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if id == "" {
+		return nil, fmt.Errorf("%s_subscribe returned no subscription id", namespace)
+	}
+	pollCtx, cancel := context.WithCancel(context.Background())
+	return &longPollSubscription{rpc: rpc, ctx: pollCtx, cancel: cancel, namespace: namespace, id: id}, nil
+}
+
+// next blocks until the next notification of the subscription arrives
+func (s *longPollSubscription) next() (json.RawMessage, error) {
+	for len(s.pending) == 0 {
+		var events []json.RawMessage
+		start := time.Now()
+		err := s.rpc.callInto(s.ctx, &events, s.namespace+"_poll", s.id, int64(longPollWait/time.Second))
+		if err != nil {
+			if s.ctx.Err() != nil {
+				return nil, s.ctx.Err()
+			}
+			return nil, err
+		}
+		// nodes answering right away are polled at a gentler pace
+		if len(events) == 0 && time.Since(start) < minLongPollInterval {
+			select {
+			case <-time.After(minLongPollInterval):
+			case <-s.ctx.Done():
+				return nil, s.ctx.Err()
+			}
+		}
+		if s.rpc.Debug && len(events) > 0 {
+			log.Printf("%s subscription %s\nPolled %d notifications\n", s.namespace, s.id, len(events))
+		}
+		s.pending = events
+	}
+	raw := s.pending[0]
+	s.pending = s.pending[1:]
+	return raw, nil
+}
+
+// close unsubscribes, unblocking a pending next
+func (s *longPollSubscription) close() {
+	s.closeOnce.Do(func() {
+		s.cancel()
+		ctx, cancel := context.WithTimeout(context.Background(), longPollUnsubscribeTimeout)
+		defer cancel()
+		s.rpc.callContext(ctx, s.namespace+"_unsubscribe", s.id)
+	})
+}
+
+// lastEventID returns no id, polled subscriptions are kept by the node
+// between polls
+func (s *longPollSubscription) lastEventID() string {
+	return ""
+}
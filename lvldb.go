@@ -0,0 +1,378 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+// Record types stored in the node's database
+const (
+	ImageRecordType     = "image"
+	ContainerRecordType = "container"
+	AccountRecordType   = "account"
+)
+
+// Timestamp is a time the node encodes either as unix seconds or as RFC 3339
+type Timestamp struct {
+	time.Time
+}
+
+// UnmarshalJSON accepts unix seconds as well as RFC 3339 strings
+func (t *Timestamp) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	if len(data) > 0 && data[0] == '"' {
+		return t.Time.UnmarshalJSON(data)
+	}
+	secs, err := strconv.ParseInt(string(data), 10, 64)
+	if err != nil {
+		return err
+	}
+	t.Time = time.Unix(secs, 0)
+	return nil
+}
+
+// ImageRecord is an uploaded image stored in the node's database
+type ImageRecord struct {
+	ID        string    `json:"id"`
+	Hash      string    `json:"hash"`
+	Signature string    `json:"signature,omitempty"`
+	Account   string    `json:"account"`
+	Created   Timestamp `json:"createdTime"`
+}
+
+// ImageAccountRecord links an image hash to the account that uploaded it
+type ImageAccountRecord struct {
+	Hash    string `json:"hash"`
+	Account string `json:"account"`
+}
+
+// ContainerRecord is a container run by the node, stored in its database
+type ContainerRecord struct {
+	ID      string    `json:"id"`
+	ImageID string    `json:"imageID"`
+	NodeID  string    `json:"nodeID"`
+	Account string    `json:"account"`
+	Status  string    `json:"status"`
+	Created Timestamp `json:"createdTime"`
+}
+
+// AccountRecord is an account stored in the node's database
+type AccountRecord struct {
+	Address string    `json:"address"`
+	Created Timestamp `json:"createdTime"`
+}
+
+// RawRecord is a database record as the node returns it
+type RawRecord struct {
+	Type string          `json:"type"`
+	Key  string          `json:"key"`
+	Data json.RawMessage `json:"data"`
+}
+
+// LvlDBRecords are the records of a database query, grouped by type.
+// Records of types unknown to the SDK are kept in Other.
+type LvlDBRecords struct {
+	Images     []ImageRecord
+	Containers []ContainerRecord
+	Accounts   []AccountRecord
+	Other      []RawRecord
+}
+
+// IsEmpty reports whether no records were returned
+func (r *LvlDBRecords) IsEmpty() bool {
+	return len(r.Images) == 0 && len(r.Containers) == 0 && len(r.Accounts) == 0 && len(r.Other) == 0
+}
+
+// add decodes a raw record into the slice of its type
+func (r *LvlDBRecords) add(raw RawRecord) error {
+	switch raw.Type {
+	case ImageRecordType:
+		var rec ImageRecord
+		if err := json.Unmarshal(raw.Data, &rec); err != nil {
+			return err
+		}
+		r.Images = append(r.Images, rec)
+	case ContainerRecordType:
+		var rec ContainerRecord
+		if err := json.Unmarshal(raw.Data, &rec); err != nil {
+			return err
+		}
+		r.Containers = append(r.Containers, rec)
+	case AccountRecordType:
+		var rec AccountRecord
+		if err := json.Unmarshal(raw.Data, &rec); err != nil {
+			return err
+		}
+		r.Accounts = append(r.Accounts, rec)
+	default:
+		r.Other = append(r.Other, raw)
+	}
+	return nil
+}
+
+// LvlDBRawQuery calls the lvldb_<method> query and returns its undecoded
+// result, for queries and fields the typed methods don't cover
+func (rpc *CCClient) LvlDBRawQuery(method string, params ...interface{}) (json.RawMessage, error) {
+	return rpc.callContext(context.Background(), "lvldb_"+method, params...)
+}
+
+// lvldbQuery decodes the result of a database query into result. The node
+// returns query results as JSON encoded strings, which are unwrapped first.
+func (rpc *CCClient) lvldbQuery(ctx context.Context, result interface{}, method string, params ...interface{}) error {
+	res, err := rpc.callContext(ctx, "lvldb_"+method, params...)
+	if err != nil {
+		return err
+	}
+	if len(res) > 0 && res[0] == '"' {
+		var s string
+		if err := decodeJSON("lvldb_"+method, res, &s); err != nil {
+			return err
+		}
+		res = json.RawMessage(s)
+	}
+	return decodeInto(rpc.codec.Unmarshal, "lvldb_"+method, res, result)
+}
+
+// lvldbRecords runs a query returning a list of records
+func (rpc *CCClient) lvldbRecords(ctx context.Context, method string, params ...interface{}) (*LvlDBRecords, error) {
+	var raw []RawRecord
+	if err := rpc.lvldbQuery(ctx, &raw, method, params...); err != nil {
+		return nil, err
+	}
+	records := new(LvlDBRecords)
+	for _, r := range raw {
+		if err := records.add(r); err != nil {
+			return nil, err
+		}
+	}
+	return records, nil
+}
+
+// LvlDBSelectImage returns the stored record of an image
+func (rpc *CCClient) LvlDBSelectImage(imageID string) (*ImageRecord, error) {
+	image := new(ImageRecord)
+	if err := rpc.lvldbQuery(context.Background(), image, "selectImage", imageID); err != nil {
+		return nil, err
+	}
+	return image, nil
+}
+
+// LvlDBSelectImageAccount returns the account that uploaded the image with the given hash
+func (rpc *CCClient) LvlDBSelectImageAccount(imageHash string) (*ImageAccountRecord, error) {
+	record := new(ImageAccountRecord)
+	if err := rpc.lvldbQuery(context.Background(), record, "selectImageAccount", imageHash); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// LvlDBSelectType returns the records of the given type, e.g. ImageRecordType
+func (rpc *CCClient) LvlDBSelectType(typeName string) (*LvlDBRecords, error) {
+	return rpc.lvldbRecords(context.Background(), "selectType", typeName)
+}
+
+// LvlDBSelectAll returns all records of the database
+func (rpc *CCClient) LvlDBSelectAll() (*LvlDBRecords, error) {
+	return rpc.lvldbRecords(context.Background(), "selectAll")
+}
+
+// defaultLvlDBPageSize is the number of records fetched per page by an iterator
+const defaultLvlDBPageSize = 500
+
+// LvlDBPage is a page of the records of the database
+type LvlDBPage struct {
+	Records []RawRecord `json:"records"`
+	// Next is the cursor of the following page, empty on the last page
+	Next string `json:"next"`
+}
+
+// LvlDBSelectAllPage returns up to limit records starting at cursor, which is
+// empty for the first page and the Next cursor of the previous page afterwards
+func (rpc *CCClient) LvlDBSelectAllPage(cursor string, limit int) (*LvlDBPage, error) {
+	page := new(LvlDBPage)
+	if err := rpc.lvldbQuery(context.Background(), page, "selectAllPaged", cursor, limit); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// LvlDBIterator walks all records of the database page by page
+//
+//	it := client.LvlDBIterate(0)
+//	for it.Next() {
+//		if it.Record().Type == ccgosdk.ImageRecordType {
+//			var image ccgosdk.ImageRecord
+//			err := it.Scan(&image)
+//			...
+//		}
+//	}
+//	err := it.Err()
+type LvlDBIterator struct {
+	rpc      *CCClient
+	pageSize int
+	cursor   string
+	started  bool
+	page     []RawRecord
+	current  RawRecord
+	err      error
+}
+
+// LvlDBIterate returns an iterator over all records of the database fetching
+// pageSize records per call, 500 if pageSize isn't positive
+func (rpc *CCClient) LvlDBIterate(pageSize int) *LvlDBIterator {
+	if pageSize <= 0 {
+		pageSize = defaultLvlDBPageSize
+	}
+	return &LvlDBIterator{rpc: rpc, pageSize: pageSize}
+}
+
+// Next advances to the next record, returning false when there are no more
+// records or fetching a page failed
+func (it *LvlDBIterator) Next() bool {
+	for len(it.page) == 0 {
+		if it.err != nil || (it.started && it.cursor == "") {
+			return false
+		}
+		page, err := it.rpc.LvlDBSelectAllPage(it.cursor, it.pageSize)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		it.started = true
+		it.page, it.cursor = page.Records, page.Next
+	}
+	it.current, it.page = it.page[0], it.page[1:]
+	return true
+}
+
+// Record returns the current record
+func (it *LvlDBIterator) Record() RawRecord {
+	return it.current
+}
+
+// Scan decodes the data of the current record into dst, e.g. an *ImageRecord
+func (it *LvlDBIterator) Scan(dst interface{}) error {
+	return json.Unmarshal(it.current.Data, dst)
+}
+
+// Err returns the error that stopped the iteration, if any
+func (it *LvlDBIterator) Err() error {
+	return it.err
+}
+
+// LvlDBFilter selects database records on the node. Zero fields don't filter.
+type LvlDBFilter struct {
+	// Type is a record type such as ImageRecordType
+	Type    string `json:"type,omitempty"`
+	Account string `json:"account,omitempty"`
+	// Status matches the status of container records
+	Status string `json:"status,omitempty"`
+	// Since and Until bound the creation time of the records
+	Since time.Time `json:"-"`
+	Until time.Time `json:"-"`
+}
+
+// MarshalJSON encodes the filter the way the node expects it, with unix times
+func (f LvlDBFilter) MarshalJSON() ([]byte, error) {
+	type filter LvlDBFilter
+	out := struct {
+		filter
+		Since int64 `json:"since,omitempty"`
+		Until int64 `json:"until,omitempty"`
+	}{filter: filter(f)}
+	if !f.Since.IsZero() {
+		out.Since = f.Since.Unix()
+	}
+	if !f.Until.IsZero() {
+		out.Until = f.Until.Unix()
+	}
+	return json.Marshal(out)
+}
+
+// LvlDBSelect returns the records matching filter, filtered by the node
+func (rpc *CCClient) LvlDBSelect(filter LvlDBFilter) (*LvlDBRecords, error) {
+	return rpc.lvldbRecords(context.Background(), "select", filter)
+}
+
+// LvlDBSelectImagesByAccount returns the images uploaded by account
+func (rpc *CCClient) LvlDBSelectImagesByAccount(account string) ([]ImageRecord, error) {
+	records, err := rpc.LvlDBSelect(LvlDBFilter{Type: ImageRecordType, Account: account})
+	if err != nil {
+		return nil, err
+	}
+	return records.Images, nil
+}
+
+// LvlDBSelectContainersByStatus returns the containers with the given status, e.g. "exited"
+func (rpc *CCClient) LvlDBSelectContainersByStatus(status string) ([]ContainerRecord, error) {
+	records, err := rpc.LvlDBSelect(LvlDBFilter{Type: ContainerRecordType, Status: status})
+	if err != nil {
+		return nil, err
+	}
+	return records.Containers, nil
+}
+
+// LvlDBSelectSince returns the records created at or after t
+func (rpc *CCClient) LvlDBSelectSince(t time.Time) (*LvlDBRecords, error) {
+	return rpc.LvlDBSelect(LvlDBFilter{Since: t})
+}
+
+// LvlDBEventType is the kind of change of a database key
+type LvlDBEventType string
+
+const (
+	LvlDBPut    LvlDBEventType = "put"
+	LvlDBDelete LvlDBEventType = "delete"
+)
+
+// LvlDBEvent is a change of a database key
+type LvlDBEvent struct {
+	Type LvlDBEventType `json:"type"`
+	Key  string         `json:"key"`
+	// Value is the new value of the key, empty for deletes
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// LvlDBWatch delivers the puts and deletes of the keys starting with prefix,
+// as the node pushes them
+func (rpc *CCClient) LvlDBWatch(ctx context.Context, prefix string) (<-chan LvlDBEvent, *Subscription, error) {
+	ch := make(chan LvlDBEvent)
+	deliver := func(ctx context.Context, raw json.RawMessage) error {
+		var ev LvlDBEvent
+		if err := json.Unmarshal(raw, &ev); err != nil {
+			return err
+		}
+		select {
+		case ch <- ev:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	sub, err := rpc.subscribeStream(ctx, deliver, func() { close(ch) }, "lvldb", "watch", prefix)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ch, sub, nil
+}
@@ -0,0 +1,168 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"time"
+)
+
+// LvlDBExportFormat is the encoding of a database export
+type LvlDBExportFormat int
+
+const (
+	// LvlDBExportJSONLines writes one JSON encoded RawRecord per line
+	LvlDBExportJSONLines LvlDBExportFormat = iota
+	// LvlDBExportArchive writes a gzipped tar with the data of each record
+	// at <type>/<key>.json and a manifest.json describing the export
+	LvlDBExportArchive
+)
+
+// LvlDBManifest describes the contents of an export archive
+type LvlDBManifest struct {
+	ExportedAt time.Time      `json:"exportedAt"`
+	Records    int            `json:"records"`
+	Types      map[string]int `json:"types"`
+	Filter     *LvlDBFilter   `json:"filter,omitempty"`
+}
+
+// recordWriter writes the records of an export in one format
+type recordWriter interface {
+	write(RawRecord) error
+	close(*LvlDBManifest) error
+}
+
+type jsonLinesWriter struct {
+	enc *json.Encoder
+}
+
+func (w *jsonLinesWriter) write(r RawRecord) error {
+	return w.enc.Encode(r)
+}
+
+func (w *jsonLinesWriter) close(*LvlDBManifest) error {
+	return nil
+}
+
+type archiveWriter struct {
+	gz  *gzip.Writer
+	tar *tar.Writer
+}
+
+func (w *archiveWriter) writeFile(name string, data []byte) error {
+	hdr := &tar.Header{
+		Name:     name,
+		Mode:     0644,
+		Size:     int64(len(data)),
+		ModTime:  time.Now(),
+		Typeflag: tar.TypeReg,
+	}
+	if err := w.tar.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := w.tar.Write(data)
+	return err
+}
+
+func (w *archiveWriter) write(r RawRecord) error {
+	typ := r.Type
+	if typ == "" {
+		typ = "unknown"
+	}
+	return w.writeFile(path.Join(url.PathEscape(typ), url.PathEscape(r.Key)+".json"), r.Data)
+}
+
+func (w *archiveWriter) close(manifest *LvlDBManifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := w.writeFile("manifest.json", data); err != nil {
+		return err
+	}
+	if err := w.tar.Close(); err != nil {
+		return err
+	}
+	return w.gz.Close()
+}
+
+func newRecordWriter(w io.Writer, format LvlDBExportFormat) (recordWriter, error) {
+	switch format {
+	case LvlDBExportJSONLines:
+		return &jsonLinesWriter{enc: json.NewEncoder(w)}, nil
+	case LvlDBExportArchive:
+		gz := gzip.NewWriter(w)
+		return &archiveWriter{gz: gz, tar: tar.NewWriter(gz)}, nil
+	}
+	return nil, fmt.Errorf("unknown export format %d", format)
+}
+
+// LvlDBExport streams all records of the node's database to w, for backups
+// and offline analysis. Records are fetched page by page so the database
+// is never held in memory.
+func (rpc *CCClient) LvlDBExport(w io.Writer, format LvlDBExportFormat) error {
+	out, err := newRecordWriter(w, format)
+	if err != nil {
+		return err
+	}
+	manifest := &LvlDBManifest{ExportedAt: time.Now(), Types: make(map[string]int)}
+	it := rpc.LvlDBIterate(0)
+	for it.Next() {
+		if err := exportRecord(out, manifest, it.Record()); err != nil {
+			return err
+		}
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+	return out.close(manifest)
+}
+
+// LvlDBExportFilter streams the records matching filter to w
+func (rpc *CCClient) LvlDBExportFilter(w io.Writer, format LvlDBExportFormat, filter LvlDBFilter) error {
+	out, err := newRecordWriter(w, format)
+	if err != nil {
+		return err
+	}
+	var records []RawRecord
+	if err := rpc.lvldbQuery(context.Background(), &records, "select", filter); err != nil {
+		return err
+	}
+	manifest := &LvlDBManifest{ExportedAt: time.Now(), Types: make(map[string]int), Filter: &filter}
+	for _, r := range records {
+		if err := exportRecord(out, manifest, r); err != nil {
+			return err
+		}
+	}
+	return out.close(manifest)
+}
+
+func exportRecord(out recordWriter, manifest *LvlDBManifest, r RawRecord) error {
+	if err := out.write(r); err != nil {
+		return err
+	}
+	manifest.Records++
+	manifest.Types[r.Type]++
+	return nil
+}
@@ -0,0 +1,134 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"bufio"
+	"context"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// megabyte is the unit leveldb reports sizes in
+const megabyte = 1 << 20
+
+var (
+	// keysLine matches the per type key counts the node appends, e.g. "Keys(image): 12"
+	keysLine = regexp.MustCompile(`^Keys\(([^)]+)\):\s*(\d+)$`)
+	// ioLine matches leveldb's io stats, e.g. "Read(MB):1.50000 Write(MB):3.25000"
+	ioLine = regexp.MustCompile(`^Read\(MB\):\s*([\d.]+)\s+Write\(MB\):\s*([\d.]+)$`)
+)
+
+// LevelStats are the compaction stats of one level of the database
+type LevelStats struct {
+	Level  int
+	Tables int
+	// Size, Read and Write are in bytes
+	Size           int64
+	CompactionTime time.Duration
+	Read           int64
+	Write          int64
+}
+
+// DBStats are the parsed statistics of the node's database
+type DBStats struct {
+	// CollectedAt is when the client received the stats
+	CollectedAt time.Time
+	// Keys counts the keys by record type
+	Keys   map[string]int64
+	Levels []LevelStats
+	// DiskUsage is the total size of all tables in bytes
+	DiskUsage int64
+	// TotalRead and TotalWrite are the bytes read and written since the database opened
+	TotalRead  int64
+	TotalWrite int64
+	// Raw is the stats text as returned by the node
+	Raw string
+}
+
+// Throughput returns the read and write rates in bytes per second between
+// an earlier sample prev and s
+func (s *DBStats) Throughput(prev *DBStats) (read, write float64) {
+	secs := s.CollectedAt.Sub(prev.CollectedAt).Seconds()
+	if secs <= 0 {
+		return 0, 0
+	}
+	return float64(s.TotalRead-prev.TotalRead) / secs, float64(s.TotalWrite-prev.TotalWrite) / secs
+}
+
+// ParseDBStats parses the stats text of the node's database: leveldb's
+// compaction table and io stats, and the per type key counts
+func ParseDBStats(raw string) *DBStats {
+	stats := &DBStats{Keys: make(map[string]int64), Raw: raw}
+	scanner := bufio.NewScanner(strings.NewReader(raw))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if m := keysLine.FindStringSubmatch(line); m != nil {
+			stats.Keys[m[1]], _ = strconv.ParseInt(m[2], 10, 64)
+			continue
+		}
+		if m := ioLine.FindStringSubmatch(line); m != nil {
+			stats.TotalRead = parseMB(m[1])
+			stats.TotalWrite = parseMB(m[2])
+			continue
+		}
+		cols := strings.Split(line, "|")
+		if len(cols) != 6 {
+			continue
+		}
+		for i := range cols {
+			cols[i] = strings.TrimSpace(cols[i])
+		}
+		level, err := strconv.Atoi(cols[0])
+		if err != nil {
+			// header, separator and total rows
+			continue
+		}
+		tables, _ := strconv.Atoi(cols[1])
+		secs, _ := strconv.ParseFloat(cols[3], 64)
+		l := LevelStats{
+			Level:          level,
+			Tables:         tables,
+			Size:           parseMB(cols[2]),
+			CompactionTime: time.Duration(secs * float64(time.Second)),
+			Read:           parseMB(cols[4]),
+			Write:          parseMB(cols[5]),
+		}
+		stats.Levels = append(stats.Levels, l)
+		stats.DiskUsage += l.Size
+	}
+	return stats
+}
+
+func parseMB(s string) int64 {
+	mb, _ := strconv.ParseFloat(s, 64)
+	return int64(mb * megabyte)
+}
+
+// LvlDBStats returns the parsed statistics of the node's database, sample it
+// periodically and use Throughput to get read and write rates
+func (rpc *CCClient) LvlDBStats() (*DBStats, error) {
+	var raw string
+	if err := rpc.callInto(context.Background(), &raw, "lvldb_getDBStats"); err != nil {
+		return nil, err
+	}
+	stats := ParseDBStats(raw)
+	stats.CollectedAt = time.Now()
+	return stats, nil
+}
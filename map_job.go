@@ -0,0 +1,122 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const (
+	defaultMapConcurrency = 8
+	defaultMapInputPath   = "/input"
+)
+
+// MapOptions configures MapJob
+type MapOptions struct {
+	// Concurrency bounds the number of shards running at once, 8 by default
+	Concurrency int
+	// InputPath is the container directory a shard's input is placed in,
+	// "/input" by default
+	InputPath string
+	// OutputPath is copied out of each shard's container into OutputDir/<shard index>
+	OutputPath string
+	OutputDir  string
+	// Filter restricts the worker nodes shards are spread across
+	Filter  NodeFilter
+	Options RunOptions
+}
+
+// MapError reports the shards of a MapJob that failed
+type MapError struct {
+	// Errors holds the error of every shard, nil for the successful ones
+	Errors []error
+}
+
+func (e *MapError) Error() string {
+	var msgs []string
+	for i, err := range e.Errors {
+		if err != nil {
+			msgs = append(msgs, fmt.Sprintf("shard %d: %v", i, err))
+		}
+	}
+	return fmt.Sprintf("%d of %d shards failed: %s", len(msgs), len(e.Errors), strings.Join(msgs, "; "))
+}
+
+// MapJob runs the image once per input file, spreading the shards across the
+// discovered worker nodes, and returns their results in input order.
+// If any shard fails a *MapError is returned along with the results, which
+// are nil for the failed shards.
+func (rpc *CCClient) MapJob(ctx context.Context, imageHash string, inputs []string, opts MapOptions) ([]*JobResult, error) {
+	nodes, err := rpc.discoverWorkers(ctx, len(inputs), opts.Filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(nodes) == 0 {
+		return nil, ErrNoNodes
+	}
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultMapConcurrency
+	}
+	inputPath := opts.InputPath
+	if inputPath == "" {
+		inputPath = defaultMapInputPath
+	}
+
+	results := make([]*JobResult, len(inputs))
+	errs := make([]error, len(inputs))
+	failed := false
+	var mu sync.Mutex
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, input := range inputs {
+		spec := JobSpec{
+			ImageHash:  imageHash,
+			NodeID:     nodes[i%len(nodes)].ID,
+			Inputs:     []JobInput{{Source: input, Path: inputPath}},
+			OutputPath: opts.OutputPath,
+			Options:    opts.Options,
+		}
+		if opts.OutputPath != "" {
+			spec.OutputDir = filepath.Join(opts.OutputDir, fmt.Sprint(i))
+		}
+		wg.Add(1)
+		go func(i int, spec JobSpec) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			r, err := rpc.RunJob(ctx, spec)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs[i] = err
+				failed = true
+				return
+			}
+			results[i] = r
+		}(i, spec)
+	}
+	wg.Wait()
+	if failed {
+		return results, &MapError{Errors: errs}
+	}
+	return results, nil
+}
@@ -0,0 +1,63 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+// moduleSet caches the RPC modules of the node, shared by derived clients
+type moduleSet struct {
+	mu      sync.Mutex
+	modules map[string]string
+}
+
+// ListRPCModules returns the RPC modules the node serves, e.g. "imagemanager",
+// with their versions
+func (rpc *CCClient) ListRPCModules() (map[string]string, error) {
+	var modules map[string]string
+	if err := rpc.callInto(context.Background(), &modules, "rpc_modules"); err != nil {
+		return nil, err
+	}
+	rpc.modules.mu.Lock()
+	rpc.modules.modules = modules
+	rpc.modules.mu.Unlock()
+	return modules, nil
+}
+
+// Supports reports whether the node serves the module of method, e.g.
+// "imagemanager_runImage", so that applications can degrade gracefully
+// against older nodes. The modules are fetched on first use.
+func (rpc *CCClient) Supports(method string) (bool, error) {
+	rpc.modules.mu.Lock()
+	modules := rpc.modules.modules
+	rpc.modules.mu.Unlock()
+	if modules == nil {
+		var err error
+		if modules, err = rpc.ListRPCModules(); err != nil {
+			return false, err
+		}
+	}
+	module := method
+	if i := strings.Index(method, "_"); i >= 0 {
+		module = method[:i]
+	}
+	_, ok := modules[module]
+	return ok, nil
+}
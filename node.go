@@ -0,0 +1,180 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// benchmarkPings is the number of pings BenchmarkNode averages latency over
+const benchmarkPings = 3
+
+// GPU describes a graphics card installed on a worker node
+type GPU struct {
+	Model string `json:"model"`
+	// Memory is the video memory in bytes
+	Memory uint64 `json:"memory,omitempty"`
+	// CUDACapability is the CUDA compute capability, e.g. "7.5"
+	CUDACapability string `json:"cudaCapability,omitempty"`
+}
+
+// GPURequest asks for GPUs to be made available to a container
+type GPURequest struct {
+	// Count is the number of GPUs, -1 for all of the node's GPUs
+	Count int `json:"count"`
+	// Models restricts the GPUs to the given models if set
+	Models []string `json:"models,omitempty"`
+	// MinCUDACapability is the lowest acceptable CUDA compute capability, e.g. "6.1"
+	MinCUDACapability string `json:"minCudaCapability,omitempty"`
+}
+
+// accepts reports whether gpu satisfies the model and capability constraints
+func (r *GPURequest) accepts(gpu GPU) bool {
+	if len(r.Models) > 0 {
+		found := false
+		for _, m := range r.Models {
+			if strings.EqualFold(m, gpu.Model) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return r.MinCUDACapability == "" || compareCUDACapability(gpu.CUDACapability, r.MinCUDACapability) >= 0
+}
+
+// SatisfiedBy reports whether a node with the given GPUs can serve the request
+func (r *GPURequest) SatisfiedBy(gpus []GPU) bool {
+	matching := 0
+	for _, gpu := range gpus {
+		if r.accepts(gpu) {
+			matching++
+		}
+	}
+	if r.Count < 0 {
+		return matching > 0 && matching == len(gpus)
+	}
+	return matching >= r.Count
+}
+
+// compareCUDACapability compares two "major.minor" compute capabilities
+func compareCUDACapability(a, b string) int {
+	parse := func(v string) (int, int) {
+		parts := strings.SplitN(v, ".", 2)
+		major, _ := strconv.Atoi(parts[0])
+		minor := 0
+		if len(parts) == 2 {
+			minor, _ = strconv.Atoi(parts[1])
+		}
+		return major, minor
+	}
+	aMajor, aMinor := parse(a)
+	bMajor, bMinor := parse(b)
+	if aMajor != bMajor {
+		return aMajor - bMajor
+	}
+	return aMinor - bMinor
+}
+
+// NodeResources is the hardware and software inventory of a worker node.
+// Memory and disk sizes are in bytes.
+type NodeResources struct {
+	CPUCores      int    `json:"cpuCores"`
+	TotalMemory   uint64 `json:"totalMemory"`
+	FreeMemory    uint64 `json:"freeMemory"`
+	TotalDisk     uint64 `json:"totalDisk"`
+	FreeDisk      uint64 `json:"freeDisk"`
+	GPUs          []GPU  `json:"gpus"`
+	DockerVersion string `json:"dockerVersion"`
+	// OS and Arch are the platform of the worker, e.g. "linux" and "arm64"
+	OS   string `json:"os,omitempty"`
+	Arch string `json:"arch,omitempty"`
+}
+
+// Platform returns the platform of the worker
+func (r *NodeResources) Platform() ImagePlatform {
+	return ImagePlatform{OS: r.OS, Architecture: normalizeArch(r.Arch)}
+}
+
+// GPUModels returns the models of the node's GPUs
+func (r *NodeResources) GPUModels() []string {
+	models := make([]string, len(r.GPUs))
+	for i, gpu := range r.GPUs {
+		models[i] = gpu.Model
+	}
+	return models
+}
+
+// GetNodeResources returns the hardware inventory of the worker node with the given id
+func (rpc *CCClient) GetNodeResources(nodeID string) (*NodeResources, error) {
+	resources := new(NodeResources)
+	if err := rpc.callInto(context.Background(), resources, "node_getResources", nodeID); err != nil {
+		return nil, err
+	}
+	return resources, nil
+}
+
+// NodeScore summarizes how fast a worker node responds and computes.
+// Lower latency and higher benchmark scores are better.
+type NodeScore struct {
+	NodeID string
+	// Latency is the median round trip time of a ping to the worker
+	Latency time.Duration
+	// Benchmark is the score of the node's standard benchmark container,
+	// zero when it wasn't run
+	Benchmark float64
+}
+
+// PingNode measures the round trip time of a request relayed to the worker node
+func (rpc *CCClient) PingNode(nodeID string) (time.Duration, error) {
+	start := time.Now()
+	if _, err := rpc.call("node_ping", nodeID); err != nil {
+		return 0, err
+	}
+	return time.Since(start), nil
+}
+
+// BenchmarkNode pings the worker node a few times and, if runContainer is set,
+// has it run the standard benchmark container to score its compute speed
+func (rpc *CCClient) BenchmarkNode(nodeID string, runContainer bool) (*NodeScore, error) {
+	latencies := make([]time.Duration, 0, benchmarkPings)
+	for i := 0; i < benchmarkPings; i++ {
+		latency, err := rpc.PingNode(nodeID)
+		if err != nil {
+			return nil, err
+		}
+		latencies = append(latencies, latency)
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	score := &NodeScore{NodeID: nodeID, Latency: latencies[len(latencies)/2]}
+	if runContainer {
+		var result struct {
+			Score float64 `json:"score"`
+		}
+		if err := rpc.callInto(context.Background(), &result, "node_benchmark", nodeID); err != nil {
+			return nil, err
+		}
+		score.Benchmark = result.Score
+	}
+	return score, nil
+}
@@ -0,0 +1,304 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Option configures a CCClient
+type Option func(*CCClient)
+
+// WithWebSocketURL sets the endpoint used for subscriptions.
+// By default it is derived from the rpc url by switching to the ws/wss scheme.
+func WithWebSocketURL(url string) Option {
+	return func(rpc *CCClient) {
+		rpc.wsURL = url
+	}
+}
+
+// WithHTTPClient sets the http client calls are sent with, e.g. to configure
+// TLS or to record calls with the vcr package
+func WithHTTPClient(client *http.Client) Option {
+	return func(rpc *CCClient) {
+		rpc.client = client
+	}
+}
+
+// WithUserAgent sets the User-Agent of all requests, cc-go-sdk/<version> by default
+func WithUserAgent(userAgent string) Option {
+	return func(rpc *CCClient) {
+		rpc.userAgent = userAgent
+	}
+}
+
+// WithHeader adds a header sent with all requests, e.g. a tenant identifier.
+// Headers for single calls are set with ContextWithHeader.
+func WithHeader(key, value string) Option {
+	return func(rpc *CCClient) {
+		if rpc.header == nil {
+			rpc.header = http.Header{}
+		}
+		rpc.header.Add(key, value)
+	}
+}
+
+// WithCache caches the results of read-only methods in memory for the given
+// TTLs by method name, DefaultCacheTTLs if ttls is nil. Cached results are
+// dropped with InvalidateCache.
+func WithCache(ttls map[string]time.Duration) Option {
+	return func(rpc *CCClient) {
+		rpc.cache = newCallCache(ttls)
+	}
+}
+
+// WithMaxResponseSize makes calls fail with ErrResponseTooLarge when the
+// response exceeds size bytes, instead of decoding it
+func WithMaxResponseSize(size int64) Option {
+	return func(rpc *CCClient) {
+		rpc.maxResponseSize = size
+	}
+}
+
+// WithCodec sets the codec requests and responses are encoded with,
+// encoding/json by default
+func WithCodec(codec Codec) Option {
+	return func(rpc *CCClient) {
+		rpc.codec = codec
+	}
+}
+
+// WithBinaryCodec sends calls encoded with codec, e.g. MessagePack or CBOR,
+// to reduce bandwidth. The client falls back to JSON for good once the node
+// refuses the format, and accepts JSON responses at any time.
+func WithBinaryCodec(codec BinaryCodec) Option {
+	return func(rpc *CCClient) {
+		rpc.wire = &wireFormat{codec: codec}
+	}
+}
+
+// WithVersionNegotiation queries the node's version before the first call
+// and adapts calls to older node releases, failing those they can't serve
+// with ErrUnsupportedNodeVersion, so that one SDK version works across a
+// mixed-version network
+func WithVersionNegotiation() Option {
+	return func(rpc *CCClient) {
+		rpc.negotiation = new(negotiation)
+	}
+}
+
+// WithReauthenticate sets a function called for a new session token when
+// a call fails with ErrTokenExpired, after which the call is retried once
+func WithReauthenticate(fn ReauthenticateFunc) Option {
+	return func(rpc *CCClient) {
+		rpc.reauth = fn
+	}
+}
+
+// WithResolver sends calls to the node endpoints found by resolver instead
+// of the url the client was created with, which is only used while none are
+// resolved. Endpoints are resolved again after refresh, DefaultResolverRefresh
+// if zero, and the next endpoint is tried once one can't be reached.
+func WithResolver(resolver Resolver, refresh time.Duration) Option {
+	return func(rpc *CCClient) {
+		if refresh <= 0 {
+			refresh = DefaultResolverRefresh
+		}
+		rpc.endpoints = &endpointSet{resolver: resolver, refresh: refresh}
+	}
+}
+
+// WithAuditSink records every call that changes state on the node with sink,
+// with passphrases and session tokens redacted
+func WithAuditSink(sink AuditSink) Option {
+	return func(rpc *CCClient) {
+		rpc.auditSink = sink
+	}
+}
+
+// WithDeniedNodes keeps jobs from being placed on the given worker nodes,
+// see DenyNode
+func WithDeniedNodes(nodeIDs ...string) Option {
+	return func(rpc *CCClient) {
+		rpc.DenyNode(nodeIDs...)
+	}
+}
+
+// WithAuthProvider authenticates calls with the tokens of provider, which is
+// asked for a new one once the node rejects the current token. Tokens set
+// with WithToken or ContextWithToken take precedence.
+func WithAuthProvider(provider AuthProvider) Option {
+	return func(rpc *CCClient) {
+		rpc.auth = provider
+	}
+}
+
+// WithThrottleRetries retries calls the node throttles up to retries times,
+// after the delay it asks for with Retry-After if that is at most maxWait.
+// Calls fail with ErrThrottled otherwise, or right away if retries is zero.
+// DefaultThrottleRetries and DefaultMaxThrottleWait apply by default.
+func WithThrottleRetries(retries int, maxWait time.Duration) Option {
+	return func(rpc *CCClient) {
+		rpc.throttle = throttlePolicy{retries: retries, maxWait: maxWait}
+	}
+}
+
+// WithMaxInFlight bounds the calls and uploads the client and the clients
+// derived from it have outstanding to max, making further ones wait for a
+// free slot or for their context to be done. Every request of the upload
+// client takes a slot, e.g. every stream of a chunked upload.
+func WithMaxInFlight(max int) Option {
+	return func(rpc *CCClient) {
+		rpc.inflight = newInflightLimiter(max)
+	}
+}
+
+// WithSubscriptionTransport makes subscriptions use transport instead of
+// the first one found to reach the node, see TransportAuto
+func WithSubscriptionTransport(transport SubscriptionTransport) Option {
+	return func(rpc *CCClient) {
+		rpc.transports = &transportSelection{forced: transport}
+	}
+}
+
+// WithReconnect sets how subscriptions are re-established after losing
+// their connection, DefaultReconnectPolicy by default. A zero policy ends
+// subscriptions on the first disconnect.
+func WithReconnect(policy ReconnectPolicy) Option {
+	return func(rpc *CCClient) {
+		rpc.reconnect = policy
+	}
+}
+
+// WithEventBuffer buffers up to size notifications of every subscription
+// for a slow consumer, DefaultEventBuffer by default, and applies policy to
+// those arriving while the buffer is full. Discovery subscriptions always
+// block, as their events build on each other.
+func WithEventBuffer(size int, policy OverflowPolicy) Option {
+	return func(rpc *CCClient) {
+		if size < 0 {
+			size = 0
+		}
+		rpc.events = eventBuffer{size: size, policy: policy}
+	}
+}
+
+// WithArtifactCache skips uploads and image pushes the cache knows to have
+// been done already, see ArtifactCache
+func WithArtifactCache(cache *ArtifactCache) Option {
+	return func(rpc *CCClient) {
+		rpc.artifacts = cache
+	}
+}
+
+// WithIPFS publishes uploaded files to IPFS with client and has nodes able
+// to fetch from IPFS retrieve them by CID instead of receiving them from the
+// client. Other nodes are sent the files directly, with the CID still set in
+// the UploadResult.
+func WithIPFS(client *IPFSClient) Option {
+	return func(rpc *CCClient) {
+		rpc.ipfs = &ipfsDistribution{client: client}
+	}
+}
+
+// WithDebugJSON writes every call to w as a line of JSON, see DebugRecord,
+// for log pipelines. Unlike the free-text output of Debug, secrets are
+// redacted like in audit records and bodies are truncated.
+func WithDebugJSON(w io.Writer) Option {
+	return func(rpc *CCClient) {
+		rpc.debugLog = &debugLog{w: w}
+	}
+}
+
+// WithUploadClient sets the client used to upload files to the node,
+// e.g. when copying files into containers
+func WithUploadClient(c *UploadClient) Option {
+	return func(rpc *CCClient) {
+		rpc.uploader = c
+	}
+}
+
+// WithDownloadClient sets the client used to download files from the node,
+// e.g. when copying files out of containers
+func WithDownloadClient(c *DownloadClient) Option {
+	return func(rpc *CCClient) {
+		rpc.downloader = c
+	}
+}
+
+// UploadOption configures an UploadClient. Options are applied in order,
+// so WithUploadHTTPClient should come before the options refining it.
+type UploadOption func(*UploadClient)
+
+// WithUploadHTTPClient sets the http client uploads are sent with
+func WithUploadHTTPClient(client *http.Client) UploadOption {
+	return func(c *UploadClient) {
+		c.client = client
+	}
+}
+
+// WithUploadTimeout bounds the duration of a whole upload request
+func WithUploadTimeout(timeout time.Duration) UploadOption {
+	return func(c *UploadClient) {
+		client := *c.client
+		client.Timeout = timeout
+		c.client = &client
+	}
+}
+
+// WithUploadTLSConfig sets the TLS configuration used to connect to the node
+func WithUploadTLSConfig(cfg *tls.Config) UploadOption {
+	return func(c *UploadClient) {
+		c.transport().TLSClientConfig = cfg
+	}
+}
+
+// WithUploadProxy sets the proxy uploads are sent through, see http.ProxyURL
+func WithUploadProxy(proxy func(*http.Request) (*url.URL, error)) UploadOption {
+	return func(c *UploadClient) {
+		c.transport().Proxy = proxy
+	}
+}
+
+// WithUploadUserAgent sets the User-Agent of uploads, cc-go-sdk/<version> by default
+func WithUploadUserAgent(userAgent string) UploadOption {
+	return func(c *UploadClient) {
+		c.userAgent = userAgent
+	}
+}
+
+// WithUploadHeader adds a header sent with all uploads
+func WithUploadHeader(key, value string) UploadOption {
+	return func(c *UploadClient) {
+		if c.header == nil {
+			c.header = http.Header{}
+		}
+		c.header.Add(key, value)
+	}
+}
+
+// WithChunkedUploads uploads files of at least threshold bytes in chunks sent
+// concurrently, see UploadFileChunked
+func WithChunkedUploads(threshold int64, opts ChunkedUploadOptions) UploadOption {
+	return func(c *UploadClient) {
+		c.chunking = &chunking{threshold: threshold, opts: opts}
+	}
+}
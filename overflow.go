@@ -0,0 +1,105 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+)
+
+// DefaultEventBuffer is the number of notifications a subscription buffers
+// for a slow consumer by default
+const DefaultEventBuffer = 64
+
+// ErrEventOverflow ends subscriptions with the OverflowError policy whose
+// buffer is full
+var ErrEventOverflow = errors.New("subscription buffer overflow")
+
+// OverflowPolicy decides what happens to a notification arriving while the
+// buffer of its subscription is full
+type OverflowPolicy int
+
+const (
+	// OverflowBlock stops reading from the node until the consumer catches up
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropOldest drops the oldest buffered notification for it
+	OverflowDropOldest
+	// OverflowDropNewest drops the notification
+	OverflowDropNewest
+	// OverflowError ends the subscription with ErrEventOverflow
+	OverflowError
+)
+
+// eventBuffer bounds the notifications a subscription holds for its consumer
+type eventBuffer struct {
+	size   int
+	policy OverflowPolicy
+}
+
+// push queues raw on events according to the policy, counting dropped
+// notifications with sub
+func (b eventBuffer) push(ctx context.Context, events chan json.RawMessage, raw json.RawMessage, sub *Subscription) error {
+	policy := b.policy
+	if policy == OverflowDropOldest && cap(events) == 0 {
+		// without buffer the incoming notification is the oldest
+		policy = OverflowDropNewest
+	}
+	switch policy {
+	case OverflowDropOldest:
+		for {
+			select {
+			case events <- raw:
+				return nil
+			default:
+			}
+			select {
+			case <-events:
+				atomic.AddUint64(&sub.dropped, 1)
+			default:
+			}
+		}
+	case OverflowDropNewest:
+		select {
+		case events <- raw:
+		default:
+			atomic.AddUint64(&sub.dropped, 1)
+		}
+		return nil
+	case OverflowError:
+		select {
+		case events <- raw:
+			return nil
+		default:
+			return ErrEventOverflow
+		}
+	default:
+		select {
+		case events <- raw:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Dropped returns the number of notifications dropped because the consumer
+// didn't keep up, see OverflowPolicy
+func (s *Subscription) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
@@ -0,0 +1,96 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"context"
+	"time"
+)
+
+// NodePricing is what a worker node charges for compute
+type NodePricing struct {
+	NodeID   string  `json:"nodeId"`
+	Currency string  `json:"currency"`
+	CPUHour  float64 `json:"cpuHour"`
+	GPUHour  float64 `json:"gpuHour"`
+}
+
+// Cost returns the price of running with cpus cores and gpus graphics
+// cards for d
+func (p *NodePricing) Cost(cpus, gpus int, d time.Duration) float64 {
+	hours := d.Hours()
+	return hours * (float64(cpus)*p.CPUHour + float64(gpus)*p.GPUHour)
+}
+
+// CostEstimate is the node's estimate of the price of a run
+type CostEstimate struct {
+	NodeID   string  `json:"nodeId"`
+	Currency string  `json:"currency"`
+	Amount   float64 `json:"amount"`
+}
+
+// PaymentStatus is the state of a payment obligation
+type PaymentStatus string
+
+const (
+	PaymentPending PaymentStatus = "pending"
+	PaymentSettled PaymentStatus = "settled"
+	PaymentFailed  PaymentStatus = "failed"
+)
+
+// Payment is an obligation of an account to a worker node for a run, and its settlement
+type Payment struct {
+	ID          string        `json:"id"`
+	Account     string        `json:"account"`
+	NodeID      string        `json:"nodeId"`
+	ContainerID string        `json:"containerId"`
+	Currency    string        `json:"currency"`
+	Amount      float64       `json:"amount"`
+	Status      PaymentStatus `json:"status"`
+	CreatedAt   Timestamp     `json:"createdAt"`
+	SettledAt   Timestamp     `json:"settledAt"`
+}
+
+// GetNodePricing returns the prices of a worker node. Nodes of networks
+// without compensation return an rpc error.
+func (rpc *CCClient) GetNodePricing(nodeID string) (*NodePricing, error) {
+	pricing := new(NodePricing)
+	if err := rpc.callInto(context.Background(), pricing, "payment_getPricing", nodeID); err != nil {
+		return nil, err
+	}
+	return pricing, nil
+}
+
+// EstimateJobCost asks the node for the price of running the image with
+// opts on the worker for at most maxDuration, before submitting it
+func (rpc *CCClient) EstimateJobCost(nodeID, imageID string, opts RunOptions, maxDuration time.Duration) (*CostEstimate, error) {
+	estimate := new(CostEstimate)
+	err := rpc.callInto(context.Background(), estimate, "payment_estimateCost", nodeID, imageID, opts, int64(maxDuration/time.Second))
+	if err != nil {
+		return nil, err
+	}
+	return estimate, nil
+}
+
+// ListPayments returns the payment obligations of account and their settlements
+func (rpc *CCClient) ListPayments(account, token string) ([]Payment, error) {
+	var payments []Payment
+	if err := rpc.callInto(tokenContext(token), &payments, "payment_listPayments", account); err != nil {
+		return nil, err
+	}
+	return payments, nil
+}
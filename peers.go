@@ -0,0 +1,63 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// PeerEventType tells whether the node connected to or disconnected from a peer
+type PeerEventType string
+
+const (
+	PeerConnected    PeerEventType = "connected"
+	PeerDisconnected PeerEventType = "disconnected"
+)
+
+// PeerEvent is delivered by SubscribePeers when the node's peers change
+type PeerEvent struct {
+	Type   PeerEventType `json:"type"`
+	PeerID string        `json:"peerID"`
+	Addrs  []string      `json:"addrs,omitempty"`
+	Time   time.Time     `json:"time"`
+}
+
+// SubscribePeers delivers an event whenever the node connects to or
+// disconnects from a peer, as the node pushes them, e.g. to keep a view of
+// the network topology live
+func (rpc *CCClient) SubscribePeers(ctx context.Context) (<-chan PeerEvent, *Subscription, error) {
+	ch := make(chan PeerEvent)
+	deliver := func(ctx context.Context, raw json.RawMessage) error {
+		var ev PeerEvent
+		if err := json.Unmarshal(raw, &ev); err != nil {
+			return err
+		}
+		select {
+		case ch <- ev:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	sub, err := rpc.subscribeStream(ctx, deliver, func() { close(ch) }, "node", "peers")
+	if err != nil {
+		return nil, nil, err
+	}
+	return ch, sub, nil
+}
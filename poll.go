@@ -0,0 +1,156 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+)
+
+// ErrPollTimeout is returned by Poll when the condition wasn't met within
+// the duration set with PollMaxDuration
+var ErrPollTimeout = errors.New("poll: maximum duration exceeded")
+
+// defaultPollInterval is the interval Poll uses if the given one isn't positive
+const defaultPollInterval = time.Second
+
+// PollFunc checks a condition for Poll, reporting whether it is met
+type PollFunc func(ctx context.Context) (done bool, err error)
+
+// PollOption configures Poll
+type PollOption func(*poller)
+
+// PollBackoff multiplies the interval by factor after every check, up to max
+func PollBackoff(factor float64, max time.Duration) PollOption {
+	return func(p *poller) {
+		p.factor, p.max = factor, max
+	}
+}
+
+// PollJitter varies every interval randomly by up to the given fraction,
+// e.g. 0.1 for ±10%, so that many clients don't poll in lockstep
+func PollJitter(fraction float64) PollOption {
+	return func(p *poller) {
+		p.jitter = fraction
+	}
+}
+
+// PollMaxDuration makes Poll give up with ErrPollTimeout after d
+func PollMaxDuration(d time.Duration) PollOption {
+	return func(p *poller) {
+		p.maxDuration = d
+	}
+}
+
+// PollRetries makes Poll tolerate up to n consecutive errors of the check,
+// e.g. a node that is briefly unreachable, and only fail on the next one
+func PollRetries(n int) PollOption {
+	return func(p *poller) {
+		p.retries = n
+	}
+}
+
+// Poll calls fn right away and then every interval, 1s if interval isn't
+// positive, until it reports done, fails or ctx is done. The check gets a
+// context ending with the maximum duration, if set.
+func Poll(ctx context.Context, interval time.Duration, fn PollFunc, opts ...PollOption) error {
+	p := newPoller(interval, opts...)
+	pollCtx := ctx
+	if p.maxDuration > 0 {
+		var cancel context.CancelFunc
+		pollCtx, cancel = context.WithTimeout(ctx, p.maxDuration)
+		defer cancel()
+	}
+	failures := 0
+	for {
+		done, err := fn(pollCtx)
+		switch {
+		case err == nil && done:
+			return nil
+		case err == nil:
+			failures = 0
+		case pollCtx.Err() == nil && failures < p.retries:
+			failures++
+		default:
+			return p.timeout(ctx, pollCtx, err)
+		}
+		if err := p.wait(pollCtx); err != nil {
+			return p.timeout(ctx, pollCtx, err)
+		}
+	}
+}
+
+// poller spaces the checks of a polling loop
+type poller struct {
+	current     time.Duration
+	max         time.Duration
+	factor      float64
+	jitter      float64
+	maxDuration time.Duration
+	retries     int
+
+	initial time.Duration
+}
+
+func newPoller(interval time.Duration, opts ...PollOption) *poller {
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+	p := &poller{current: interval, initial: interval}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// timeout turns err into ErrPollTimeout if the maximum duration of pollCtx,
+// rather than ctx, ended polling
+func (p *poller) timeout(ctx, pollCtx context.Context, err error) error {
+	if ctx.Err() == nil && pollCtx.Err() == context.DeadlineExceeded {
+		return ErrPollTimeout
+	}
+	return err
+}
+
+// wait sleeps for the current interval and then backs off
+func (p *poller) wait(ctx context.Context) error {
+	d := p.current
+	if p.jitter > 0 {
+		d += time.Duration((rand.Float64()*2 - 1) * p.jitter * float64(d))
+	}
+	if p.factor > 1 {
+		p.current = time.Duration(float64(p.current) * p.factor)
+		if p.max > 0 && p.current > p.max {
+			p.current = p.max
+		}
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// reset returns to the initial interval, e.g. after the polled state changed
+func (p *poller) reset() {
+	p.current = p.initial
+}
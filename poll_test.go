@@ -0,0 +1,41 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPollNonPositiveInterval(t *testing.T) {
+	for _, interval := range []time.Duration{0, -time.Second} {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		checks := 0
+		err := Poll(ctx, interval, func(ctx context.Context) (bool, error) {
+			checks++
+			return false, nil
+		})
+		cancel()
+		if err != context.DeadlineExceeded {
+			t.Errorf("Poll(%v) returned %v, want %v", interval, err, context.DeadlineExceeded)
+		}
+		if checks != 1 {
+			t.Errorf("Poll(%v) checked %d times within 100ms, want 1", interval, checks)
+		}
+	}
+}
@@ -0,0 +1,360 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	defaultPushConcurrency = 4
+	defaultPushRetryDelay  = time.Second
+	// pushPollInterval is how often the state of a transfer is polled
+	pushPollInterval = time.Second
+)
+
+// PushState is the stage an image push to a single node is in
+type PushState string
+
+const (
+	PushQueued   PushState = "queued"
+	PushRunning  PushState = "pushing"
+	PushRetrying PushState = "retrying"
+	PushDone     PushState = "done"
+	PushFailed   PushState = "failed"
+)
+
+// PushProgress reports a state change of the push to one node, and while
+// pushing the bytes transferred so far
+type PushProgress struct {
+	NodeID  string
+	State   PushState
+	Attempt int
+	// Err is the error of the last attempt when retrying or failed
+	Err error
+	// TransferID identifies the transfer for ResumePush, BytesDone and
+	// BytesTotal its progress. They are zero for nodes pushing images in a
+	// single blocking call.
+	TransferID string
+	BytesDone  int64
+	BytesTotal int64
+}
+
+// Percent returns the share of the image transferred, 0 to 100
+func (p PushProgress) Percent() float64 {
+	if p.BytesTotal <= 0 {
+		return 0
+	}
+	return float64(p.BytesDone) * 100 / float64(p.BytesTotal)
+}
+
+// TransferState is the state of an image transfer on the node
+type TransferState string
+
+const (
+	TransferRunning TransferState = "running"
+	TransferDone    TransferState = "done"
+	TransferFailed  TransferState = "failed"
+)
+
+// PushTransfer is the state of the transfer of an image to a worker node
+type PushTransfer struct {
+	ID         string        `json:"id"`
+	NodeID     string        `json:"nodeID"`
+	ImageHash  string        `json:"imageHash"`
+	State      TransferState `json:"state"`
+	BytesDone  int64         `json:"bytesDone"`
+	BytesTotal int64         `json:"bytesTotal"`
+	// ImageID is set once the transfer is done
+	ImageID string `json:"imageID,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// PushOptions configures PushImageToNodes
+type PushOptions struct {
+	// Concurrency bounds the number of pushes in flight, 4 by default
+	Concurrency int
+	// Retries is the number of additional attempts for a failed push. Only
+	// failed transfers and transport errors are retried, errors returned by
+	// the node fail the push right away.
+	Retries int
+	// RetryDelay is the wait before the first retry, doubling with every
+	// further attempt, 1s by default
+	RetryDelay time.Duration
+	// OnProgress is called on every state change, never concurrently
+	OnProgress func(PushProgress)
+	// Platform is the platform of the image, see DetectImagePlatform. If set,
+	// pushes to workers of other platforms fail with a PlatformError unless
+	// AllowPlatformMismatch is set.
+	Platform              ImagePlatform
+	AllowPlatformMismatch bool
+}
+
+// PushResult is the outcome of pushing an image to one node
+type PushResult struct {
+	ImageID string
+	Err     error
+}
+
+func (rpc *CCClient) pushImage(ctx context.Context, nodeID, imageHash string) (string, error) {
+	if id, ok := rpc.cachedImage(nodeID, imageHash); ok {
+		return id, nil
+	}
+	var imgID string
+	if err := rpc.callInto(ctx, &imgID, "imagemanager_pushImage", nodeID, imageHash); err != nil {
+		return "", err
+	}
+	rpc.cacheImage(nodeID, imageHash, imgID)
+	return imgID, nil
+}
+
+// cachedImage returns the image loaded from imageHash onto the node earlier,
+// if the artifact cache knows it
+func (rpc *CCClient) cachedImage(nodeID, imageHash string) (string, bool) {
+	if rpc.artifacts == nil {
+		return "", false
+	}
+	return rpc.artifacts.Image(nodeID, imageHash)
+}
+
+func (rpc *CCClient) cacheImage(nodeID, imageHash, imageID string) {
+	if rpc.artifacts != nil && imageID != "" {
+		rpc.artifacts.AddImage(nodeID, imageHash, imageID)
+	}
+}
+
+// PushStatus returns the state of the transfer with the given id
+func (rpc *CCClient) PushStatus(transferID string) (*PushTransfer, error) {
+	return rpc.pushStatus(context.Background(), transferID)
+}
+
+func (rpc *CCClient) pushStatus(ctx context.Context, transferID string) (*PushTransfer, error) {
+	transfer := new(PushTransfer)
+	if err := rpc.callInto(ctx, transfer, "imagemanager_pushStatus", transferID); err != nil {
+		return nil, err
+	}
+	return transfer, nil
+}
+
+// PushImage pushes the uploaded image to the node like LoadImageToNode and
+// returns the image id, reporting the bytes transferred to opts.OnProgress.
+// A failed transfer is resumed where it stopped up to opts.Retries times.
+// Nodes without resumable transfers are sent a single blocking push.
+func (rpc *CCClient) PushImage(ctx context.Context, nodeID, imageHash string, opts PushOptions) (string, error) {
+	if id, ok := rpc.cachedImage(nodeID, imageHash); ok {
+		return id, nil
+	}
+	if err := rpc.checkPlatform(ctx, nodeID, opts); err != nil {
+		opts.report(PushProgress{NodeID: nodeID, State: PushFailed, Attempt: 1, Err: err})
+		return "", err
+	}
+	var transfer PushTransfer
+	attempt := 1
+	for {
+		err := rpc.callInto(ctx, &transfer, "imagemanager_startPush", nodeID, imageHash)
+		if err == nil {
+			break
+		}
+		if isMethodNotFound(err) {
+			return rpc.pushWithRetries(ctx, nodeID, imageHash, opts)
+		}
+		if err := opts.retry(ctx, PushProgress{NodeID: nodeID, Attempt: attempt, Err: err}); err != nil {
+			return "", err
+		}
+		attempt++
+	}
+	if transfer.NodeID == "" {
+		transfer.NodeID = nodeID
+	}
+	id, err := rpc.followPush(ctx, &transfer, attempt, opts)
+	if err == nil {
+		rpc.cacheImage(nodeID, imageHash, id)
+	}
+	return id, err
+}
+
+// ResumePush resumes a failed or interrupted transfer, e.g. one started by an
+// earlier process, and follows it like PushImage
+func (rpc *CCClient) ResumePush(ctx context.Context, transferID string, opts PushOptions) (string, error) {
+	transfer, err := rpc.pushStatus(ctx, transferID)
+	if err != nil {
+		return "", err
+	}
+	return rpc.followPush(ctx, transfer, 1, opts)
+}
+
+// report passes p on to OnProgress, if set
+func (opts PushOptions) report(p PushProgress) {
+	if opts.OnProgress != nil {
+		opts.OnProgress(p)
+	}
+}
+
+// retry reports the failed attempt p as retrying and waits for the next one,
+// doubling opts.RetryDelay with every attempt. Once opts.Retries are used up,
+// ctx is done or the error isn't one a retry can fix, like an error returned
+// by the node, it reports p as failed and returns the error.
+func (opts PushOptions) retry(ctx context.Context, p PushProgress) error {
+	if p.Attempt > opts.Retries || ctx.Err() != nil || !reconnectable(p.Err) {
+		p.State = PushFailed
+		opts.report(p)
+		return p.Err
+	}
+	p.State = PushRetrying
+	opts.report(p)
+	delay := opts.RetryDelay
+	if delay <= 0 {
+		delay = defaultPushRetryDelay
+	}
+	select {
+	case <-time.After(delay << uint(p.Attempt-1)):
+		return nil
+	case <-ctx.Done():
+		p.State, p.Err = PushFailed, ctx.Err()
+		opts.report(p)
+		return p.Err
+	}
+}
+
+// followPush polls the transfer until it is done, resuming it after failures.
+// Failed transfers and failed calls to the node count as attempts alike,
+// starting with the given one.
+func (rpc *CCClient) followPush(ctx context.Context, transfer *PushTransfer, attempt int, opts PushOptions) (string, error) {
+	progress := func(state PushState, err error) PushProgress {
+		return PushProgress{
+			NodeID:     transfer.NodeID,
+			State:      state,
+			Attempt:    attempt,
+			Err:        err,
+			TransferID: transfer.ID,
+			BytesDone:  transfer.BytesDone,
+			BytesTotal: transfer.BytesTotal,
+		}
+	}
+	last := int64(-1)
+	for {
+		switch transfer.State {
+		case TransferDone:
+			opts.report(progress(PushDone, nil))
+			return transfer.ImageID, nil
+		case TransferFailed:
+			err := fmt.Errorf("push to %s failed: %s", transfer.NodeID, transfer.Error)
+			if err := opts.retry(ctx, progress(PushRetrying, err)); err != nil {
+				return "", err
+			}
+			attempt++
+			for {
+				var resumed PushTransfer
+				err := rpc.callInto(ctx, &resumed, "imagemanager_resumePush", transfer.ID)
+				if err == nil {
+					*transfer = resumed
+					break
+				}
+				if err := opts.retry(ctx, progress(PushRetrying, err)); err != nil {
+					return "", err
+				}
+				attempt++
+			}
+			last = -1
+			continue
+		}
+		if transfer.BytesDone != last {
+			last = transfer.BytesDone
+			opts.report(progress(PushRunning, nil))
+		}
+		select {
+		case <-time.After(pushPollInterval):
+		case <-ctx.Done():
+			opts.report(progress(PushFailed, ctx.Err()))
+			return "", ctx.Err()
+		}
+		next, err := rpc.pushStatus(ctx, transfer.ID)
+		if err != nil {
+			if err := opts.retry(ctx, progress(PushRetrying, err)); err != nil {
+				return "", err
+			}
+			attempt++
+			continue
+		}
+		*transfer = *next
+	}
+}
+
+// pushWithRetries pushes the image in blocking calls, retrying failures
+func (rpc *CCClient) pushWithRetries(ctx context.Context, nodeID, imageHash string, opts PushOptions) (string, error) {
+	for attempt := 1; ; attempt++ {
+		opts.report(PushProgress{NodeID: nodeID, State: PushRunning, Attempt: attempt})
+		imageID, err := rpc.pushImage(ctx, nodeID, imageHash)
+		if err == nil {
+			opts.report(PushProgress{NodeID: nodeID, State: PushDone, Attempt: attempt})
+			return imageID, nil
+		}
+		if err := opts.retry(ctx, PushProgress{NodeID: nodeID, Attempt: attempt, Err: err}); err != nil {
+			return "", err
+		}
+	}
+}
+
+// PushImageToNodes pushes the uploaded image to all given nodes in parallel
+// and returns the resulting image id, or the error of the last attempt, per node
+func (rpc *CCClient) PushImageToNodes(nodeIDs []string, imageHash, token string, opts PushOptions) map[string]PushResult {
+	ctx := tokenContext(token)
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultPushConcurrency
+	}
+	var mu sync.Mutex
+	results := make(map[string]PushResult, len(nodeIDs))
+	report := func(p PushProgress) {
+		mu.Lock()
+		defer mu.Unlock()
+		if opts.OnProgress != nil {
+			opts.OnProgress(p)
+		}
+	}
+	for _, nodeID := range nodeIDs {
+		report(PushProgress{NodeID: nodeID, State: PushQueued})
+	}
+
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for _, nodeID := range nodeIDs {
+		wg.Add(1)
+		go func(nodeID string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			var result PushResult
+			result.ImageID, result.Err = rpc.PushImage(ctx, nodeID, imageHash, PushOptions{
+				Retries:               opts.Retries,
+				RetryDelay:            opts.RetryDelay,
+				OnProgress:            report,
+				Platform:              opts.Platform,
+				AllowPlatformMismatch: opts.AllowPlatformMismatch,
+			})
+			mu.Lock()
+			results[nodeID] = result
+			mu.Unlock()
+		}(nodeID)
+	}
+	wg.Wait()
+	return results
+}
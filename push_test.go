@@ -0,0 +1,125 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPushImageRetries(t *testing.T) {
+	const (
+		failed  = `{"jsonrpc":"2.0","id":1,"result":{"id":"t1","state":"failed","error":"disk full"}}`
+		done    = `{"jsonrpc":"2.0","id":1,"result":{"id":"t1","state":"done","imageID":"img"}}`
+		nodeErr = `{"jsonrpc":"2.0","id":1,"error":{"code":-32000,"message":"no such image"}}`
+		expired = `{"jsonrpc":"2.0","id":1,"error":{"code":-32000,"message":"token expired"}}`
+	)
+	// an empty response drops the connection
+	tests := []struct {
+		name      string
+		responses []string
+		attempts  int
+		wantErr   bool
+	}{
+		{"node error", []string{nodeErr, done}, 1, true},
+		{"expired token", []string{expired, done}, 1, true},
+		{"decode error", []string{`not json`, done}, 1, true},
+		{"transport error", []string{"", "", done}, 3, false},
+		{"transport errors exhaust retries", []string{"", "", "", done}, 3, true},
+		{"failed transfer", []string{failed, done}, 2, false},
+		{"failed transfer then node error", []string{failed, nodeErr, done}, 2, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var methods []string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var req rpcRequest
+				json.NewDecoder(r.Body).Decode(&req)
+				mu.Lock()
+				methods = append(methods, req.Method)
+				resp := tt.responses[len(methods)-1]
+				mu.Unlock()
+				if resp == "" {
+					panic(http.ErrAbortHandler)
+				}
+				w.Header().Set("Content-Type", "application/json")
+				fmt.Fprint(w, resp)
+			}))
+			defer srv.Close()
+
+			var attempts int
+			opts := PushOptions{Retries: 2, RetryDelay: time.Millisecond, OnProgress: func(p PushProgress) {
+				if p.State == PushRetrying || p.State == PushFailed || p.State == PushDone {
+					attempts++
+				}
+			}}
+			rpc := NewCCClient(srv.URL, WithThrottleRetries(0, 0))
+			id, err := rpc.PushImage(context.Background(), "node", "hash", opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("PushImage returned %q, %v, want an error: %v", id, err, tt.wantErr)
+			}
+			if err == nil && id != "img" {
+				t.Errorf("PushImage returned %q, want img", id)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if attempts != tt.attempts {
+				t.Errorf("made %d attempts (%v), want %d", attempts, methods, tt.attempts)
+			}
+			if len(methods) != tt.attempts {
+				t.Errorf("called %v, want %d calls", methods, tt.attempts)
+			}
+		})
+	}
+}
+
+func TestPushRetryClassification(t *testing.T) {
+	tests := []struct {
+		err   error
+		retry bool
+	}{
+		{errors.New("connection reset"), true},
+		{fmt.Errorf("push to node failed: disk full"), true},
+		{rpcError{Code: -32000, Message: "no such image"}, false},
+		{&CallError{Method: "imagemanager_startPush", Err: rpcError{Code: -32000}}, false},
+		{fmt.Errorf("%w: token expired", ErrTokenExpired), false},
+		{&DecodeError{Method: "imagemanager_pushStatus", Err: errors.New("bad")}, false},
+	}
+	for _, tt := range tests {
+		var states []PushState
+		opts := PushOptions{Retries: 1, RetryDelay: time.Millisecond, OnProgress: func(p PushProgress) { states = append(states, p.State) }}
+		err := opts.retry(context.Background(), PushProgress{Attempt: 1, Err: tt.err})
+		if (err == nil) != tt.retry {
+			t.Errorf("retry(%v) = %v, want a retry: %v", tt.err, err, tt.retry)
+		}
+		want := PushFailed
+		if tt.retry {
+			want = PushRetrying
+		}
+		if len(states) != 1 || states[0] != want {
+			t.Errorf("retry(%v) reported %v, want %v", tt.err, states, want)
+		}
+	}
+}
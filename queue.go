@@ -0,0 +1,332 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultQueueRefresh is how often a JobQueue discovers workers again
+	DefaultQueueRefresh = 10 * time.Second
+	defaultQueueNodes   = 16
+)
+
+// ErrQueueClosed fails the jobs still queued when their JobQueue is closed
+var ErrQueueClosed = errors.New("job queue closed")
+
+// QueueOptions configures a JobQueue
+type QueueOptions struct {
+	// Nodes is the number of worker nodes jobs are spread across, 16 by default
+	Nodes int
+	// SlotsPerNode is the number of jobs running on a worker node at once,
+	// 1 by default
+	SlotsPerNode int
+	// MaxRunning bounds the number of jobs running at once, unbounded if zero
+	MaxRunning int
+	// Filter restricts the worker nodes jobs are released to
+	Filter NodeFilter
+	// Refresh is how often workers are discovered again, DefaultQueueRefresh
+	// if zero
+	Refresh time.Duration
+}
+
+// JobQueue holds jobs on the client and releases them to worker nodes,
+// highest priority first, as discovered workers have free slots, so that
+// large batches can be submitted at once and are paced by the SDK
+type JobQueue struct {
+	rpc  *CCClient
+	opts QueueOptions
+	wake chan struct{}
+
+	closeOnce sync.Once
+	closed    chan struct{}
+
+	mu      sync.Mutex
+	pending jobHeap
+	running map[string]int
+	seq     uint64
+}
+
+// queuedJob is a job waiting in a JobQueue
+type queuedJob struct {
+	job      *Job
+	ctx      context.Context
+	spec     JobSpec
+	priority int
+	seq      uint64
+	// index is the position in the heap, -1 once the job left the queue
+	index int
+}
+
+// before reports whether a is released before b
+func (a *queuedJob) before(b *queuedJob) bool {
+	if a.priority != b.priority {
+		return a.priority > b.priority
+	}
+	return a.seq < b.seq
+}
+
+// jobHeap orders queued jobs by priority, and by submission among equals
+type jobHeap []*queuedJob
+
+func (h jobHeap) Len() int           { return len(h) }
+func (h jobHeap) Less(i, j int) bool { return h[i].before(h[j]) }
+
+func (h jobHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index, h[j].index = i, j
+}
+
+func (h *jobHeap) Push(x interface{}) {
+	item := x.(*queuedJob)
+	item.index = len(*h)
+	*h = append(*h, item)
+}
+
+func (h *jobHeap) Pop() interface{} {
+	old := *h
+	item := old[len(old)-1]
+	old[len(old)-1] = nil
+	item.index = -1
+	*h = old[:len(old)-1]
+	return item
+}
+
+// NewJobQueue returns a queue releasing jobs to the workers discovered
+// with the client. It is stopped with Close.
+func (rpc *CCClient) NewJobQueue(opts QueueOptions) *JobQueue {
+	if opts.Nodes <= 0 {
+		opts.Nodes = defaultQueueNodes
+	}
+	if opts.SlotsPerNode <= 0 {
+		opts.SlotsPerNode = 1
+	}
+	if opts.Refresh <= 0 {
+		opts.Refresh = DefaultQueueRefresh
+	}
+	q := &JobQueue{
+		rpc:     rpc,
+		opts:    opts,
+		wake:    make(chan struct{}, 1),
+		closed:  make(chan struct{}),
+		running: make(map[string]int),
+	}
+	go q.loop()
+	return q
+}
+
+// Submit queues the job with the given priority, higher ones are released
+// first and equal ones in submission order. Jobs placed on a given node
+// with JobSpec.NodeID wait for a slot on it, holding up the jobs after them.
+// The job is cancelled when ctx is done, also while still queued.
+func (q *JobQueue) Submit(ctx context.Context, spec JobSpec, priority int) *Job {
+	job, ctx, spec := newJob(ctx, spec)
+	item := &queuedJob{job: job, ctx: ctx, spec: spec, priority: priority}
+	q.mu.Lock()
+	select {
+	case <-q.closed:
+		q.mu.Unlock()
+		q.fail(item, ErrQueueClosed)
+		return job
+	default:
+	}
+	q.seq++
+	item.seq = q.seq
+	heap.Push(&q.pending, item)
+	q.mu.Unlock()
+	go func() {
+		<-ctx.Done()
+		q.drop(item, ctx.Err())
+	}()
+	q.signal()
+	return job
+}
+
+// Len returns the number of jobs waiting in the queue
+func (q *JobQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.pending.Len()
+}
+
+// Running returns the number of jobs released by the queue that are still
+// running
+func (q *JobQueue) Running() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	n := 0
+	for _, count := range q.running {
+		n += count
+	}
+	return n
+}
+
+// Position returns the number of queued jobs released before job, or -1
+// if job isn't waiting in the queue
+func (q *JobQueue) Position(job *Job) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var item *queuedJob
+	for _, it := range q.pending {
+		if it.job == job {
+			item = it
+			break
+		}
+	}
+	if item == nil {
+		return -1
+	}
+	pos := 0
+	for _, it := range q.pending {
+		if it.before(item) {
+			pos++
+		}
+	}
+	return pos
+}
+
+// Close stops releasing jobs and fails those still queued with
+// ErrQueueClosed. Released jobs keep running.
+func (q *JobQueue) Close() error {
+	q.closeOnce.Do(func() {
+		close(q.closed)
+	})
+	q.mu.Lock()
+	pending := q.pending
+	for _, item := range pending {
+		item.index = -1
+	}
+	q.pending = nil
+	q.mu.Unlock()
+	for _, item := range pending {
+		q.fail(item, ErrQueueClosed)
+	}
+	return nil
+}
+
+// signal wakes the dispatch loop
+func (q *JobQueue) signal() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (q *JobQueue) loop() {
+	ticker := time.NewTicker(q.opts.Refresh)
+	defer ticker.Stop()
+	var nodes []NodeInfo
+	for {
+		select {
+		case <-q.wake:
+		case <-ticker.C:
+			// workers are discovered again on the next release
+			nodes = nil
+		case <-q.closed:
+			return
+		}
+		if q.Len() == 0 {
+			continue
+		}
+		if nodes == nil {
+			var err error
+			if nodes, err = q.rpc.discoverWorkers(context.Background(), q.opts.Nodes, q.opts.Filter); err != nil && q.rpc.Debug {
+				log.Printf("job queue: discovering workers: %v\n", err)
+			}
+		}
+		q.release(nodes)
+	}
+}
+
+// release starts queued jobs while the nodes have free slots
+func (q *JobQueue) release(nodes []NodeInfo) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for q.pending.Len() > 0 {
+		total := 0
+		for _, count := range q.running {
+			total += count
+		}
+		if q.opts.MaxRunning > 0 && total >= q.opts.MaxRunning {
+			return
+		}
+		item := q.pending[0]
+		nodeID := item.spec.NodeID
+		if nodeID == "" {
+			nodeID = q.leastLoaded(nodes)
+		}
+		if nodeID == "" || q.running[nodeID] >= q.opts.SlotsPerNode {
+			return
+		}
+		heap.Pop(&q.pending)
+		q.running[nodeID]++
+		spec := item.spec
+		spec.NodeID = nodeID
+		go func() {
+			item.job.run(item.ctx, q.rpc, spec)
+			q.mu.Lock()
+			if q.running[nodeID]--; q.running[nodeID] == 0 {
+				delete(q.running, nodeID)
+			}
+			q.mu.Unlock()
+			q.signal()
+		}()
+	}
+}
+
+// leastLoaded returns the node running the fewest jobs among those with a
+// free slot, empty if there is none
+func (q *JobQueue) leastLoaded(nodes []NodeInfo) string {
+	best := ""
+	for _, n := range nodes {
+		count := q.running[n.ID]
+		if count < q.opts.SlotsPerNode && (best == "" || count < q.running[best]) {
+			best = n.ID
+		}
+	}
+	return best
+}
+
+// drop removes a job whose context is done from the queue, unless it was
+// released already
+func (q *JobQueue) drop(item *queuedJob, err error) {
+	q.mu.Lock()
+	if item.index < 0 {
+		q.mu.Unlock()
+		return
+	}
+	heap.Remove(&q.pending, item.index)
+	q.mu.Unlock()
+	q.fail(item, err)
+}
+
+// fail finishes a job that was never released
+func (q *JobQueue) fail(item *queuedJob, err error) {
+	to := JobFailed
+	if !errors.Is(err, ErrQueueClosed) {
+		to = JobCancelled
+	}
+	item.spec.OnStateChange(JobStateChange{From: JobQueued, To: to, NodeID: item.spec.NodeID, Time: time.Now(), Err: err})
+	item.job.finish(nil, err)
+	item.job.cancel()
+}
@@ -0,0 +1,181 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+)
+
+// lastEventIDHeader carries the id of the last event received when a
+// subscription is re-established, so the node can resume after it
+const lastEventIDHeader = "Last-Event-ID"
+
+// ReconnectPolicy configures how subscriptions recover from a lost connection
+type ReconnectPolicy struct {
+	// MaxAttempts is the number of consecutive reconnection attempts after
+	// which a subscription ends with the last error. Subscriptions end on
+	// the first disconnect if it is zero.
+	MaxAttempts int
+	// MinBackoff is the wait before the first attempt, doubling with every
+	// further one up to MaxBackoff
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// OnDisconnected is called with the namespace of a subscription and
+	// the error it lost its connection with
+	OnDisconnected func(namespace string, err error)
+	// OnConnected is called with the namespace of a subscription once it
+	// is re-established
+	OnConnected func(namespace string)
+}
+
+// DefaultReconnectPolicy is the policy of clients created without
+// WithReconnect
+var DefaultReconnectPolicy = ReconnectPolicy{
+	MaxAttempts: 10,
+	MinBackoff:  time.Second,
+	MaxBackoff:  30 * time.Second,
+}
+
+// reconnectingSource re-establishes a subscription whose connection was
+// lost, resuming after the last event received if the node supports it
+type reconnectingSource struct {
+	rpc       *CCClient
+	ctx       context.Context
+	cancel    context.CancelFunc
+	policy    ReconnectPolicy
+	namespace string
+	params    []interface{}
+	// resumed is called once the subscription is re-established, e.g. to
+	// catch up with events the node doesn't resume
+	resumed func(ctx context.Context) error
+	lastID  string
+
+	mu  sync.Mutex
+	src eventSource
+}
+
+// subscribeReconnecting is subscribe for subscriptions that survive losing
+// their connection, according to the client's ReconnectPolicy
+func (rpc *CCClient) subscribeReconnecting(ctx context.Context, resumed func(ctx context.Context) error, namespace string, params ...interface{}) (eventSource, error) {
+	src, err := rpc.subscribe(ctx, namespace, params...)
+	if err != nil || rpc.reconnect.MaxAttempts <= 0 {
+		return src, err
+	}
+	policy := rpc.reconnect
+	if policy.MinBackoff <= 0 {
+		policy.MinBackoff = DefaultReconnectPolicy.MinBackoff
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	return &reconnectingSource{
+		rpc:       rpc,
+		ctx:       ctx,
+		cancel:    cancel,
+		policy:    policy,
+		namespace: namespace,
+		params:    params,
+		resumed:   resumed,
+		src:       src,
+	}, nil
+}
+
+// reconnectable reports whether a subscription that failed with err may
+// succeed once re-established
+func reconnectable(err error) bool {
+	var rerr rpcError
+	var derr *DecodeError
+	return !errors.As(err, &rerr) && !errors.As(err, &derr) && !errors.Is(err, ErrTokenExpired)
+}
+
+func (s *reconnectingSource) next() (json.RawMessage, error) {
+	for {
+		s.mu.Lock()
+		src := s.src
+		s.mu.Unlock()
+		raw, err := src.next()
+		if err == nil {
+			if id := src.lastEventID(); id != "" {
+				s.lastID = id
+			}
+			return raw, nil
+		}
+		if s.ctx.Err() != nil || !reconnectable(err) {
+			return nil, err
+		}
+		src.close()
+		if fn := s.policy.OnDisconnected; fn != nil {
+			fn(s.namespace, err)
+		}
+		if err := s.reconnect(err); err != nil {
+			return nil, err
+		}
+		if fn := s.policy.OnConnected; fn != nil {
+			fn(s.namespace)
+		}
+	}
+}
+
+// reconnect subscribes again, backing off between failed attempts
+func (s *reconnectingSource) reconnect(cause error) error {
+	ctx := s.ctx
+	if s.lastID != "" {
+		ctx = ContextWithHeader(ctx, lastEventIDHeader, s.lastID)
+	}
+	backoff := newPoller(s.policy.MinBackoff, PollBackoff(2, s.policy.MaxBackoff), PollJitter(0.1))
+	err := cause
+	for attempt := 0; attempt < s.policy.MaxAttempts; attempt++ {
+		if werr := backoff.wait(s.ctx); werr != nil {
+			return werr
+		}
+		src, serr := s.rpc.subscribe(ctx, s.namespace, s.params...)
+		if serr != nil {
+			if s.ctx.Err() != nil || !reconnectable(serr) {
+				return serr
+			}
+			err = serr
+			continue
+		}
+		s.mu.Lock()
+		if s.ctx.Err() != nil {
+			s.mu.Unlock()
+			src.close()
+			return s.ctx.Err()
+		}
+		s.src = src
+		s.mu.Unlock()
+		if s.resumed != nil {
+			return s.resumed(s.ctx)
+		}
+		return nil
+	}
+	return err
+}
+
+func (s *reconnectingSource) lastEventID() string {
+	return s.lastID
+}
+
+func (s *reconnectingSource) close() {
+	s.cancel()
+	s.mu.Lock()
+	src := s.src
+	s.mu.Unlock()
+	src.close()
+}
@@ -0,0 +1,99 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// QuorumError is returned by UploadToNodes when fewer nodes than the quorum
+// could store the file
+type QuorumError struct {
+	Quorum int
+	// Holders are the nodes that did store the file
+	Holders []string
+	// Errors are the replication errors by node
+	Errors map[string]error
+}
+
+func (e *QuorumError) Error() string {
+	nodes := make([]string, 0, len(e.Errors))
+	for nodeID := range e.Errors {
+		nodes = append(nodes, nodeID)
+	}
+	sort.Strings(nodes)
+	msgs := make([]string, len(nodes))
+	for i, nodeID := range nodes {
+		msgs[i] = fmt.Sprintf("%s: %v", nodeID, e.Errors[nodeID])
+	}
+	return fmt.Sprintf("file stored on %d of %d required nodes: %s", len(e.Holders), e.Quorum, strings.Join(msgs, "; "))
+}
+
+func (rpc *CCClient) replicateFile(ctx context.Context, nodeID, hash string) error {
+	_, err := rpc.callContext(ctx, "imagemanager_replicateFile", nodeID, hash)
+	return err
+}
+
+// UploadToNodes uploads the file and has the given nodes replicate it
+// concurrently, returning the upload and the nodes holding the file as soon
+// as quorum of them do. Replications still running then are cancelled.
+// A *QuorumError is returned if the quorum can't be met.
+func (rpc *CCClient) UploadToNodes(file string, nodeIDs []string, quorum int, token string) (*UploadResult, []string, error) {
+	if rpc.uploader == nil {
+		return nil, nil, ErrNoUploadClient
+	}
+	if quorum <= 0 || quorum > len(nodeIDs) {
+		return nil, nil, fmt.Errorf("quorum %d is not between 1 and the %d nodes", quorum, len(nodeIDs))
+	}
+	ctx, cancel := context.WithCancel(tokenContext(token))
+	defer cancel()
+	upload, err := rpc.uploadFile(ctx, file, token)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	type outcome struct {
+		nodeID string
+		err    error
+	}
+	outcomes := make(chan outcome, len(nodeIDs))
+	for _, nodeID := range nodeIDs {
+		go func(nodeID string) {
+			outcomes <- outcome{nodeID, rpc.replicateFile(ctx, nodeID, upload.Hash)}
+		}(nodeID)
+	}
+
+	qerr := &QuorumError{Quorum: quorum, Errors: make(map[string]error)}
+	for range nodeIDs {
+		o := <-outcomes
+		if o.err != nil {
+			qerr.Errors[o.nodeID] = o.err
+			if len(nodeIDs)-len(qerr.Errors) < quorum {
+				return upload, qerr.Holders, qerr
+			}
+			continue
+		}
+		qerr.Holders = append(qerr.Holders, o.nodeID)
+		if len(qerr.Holders) == quorum {
+			return upload, qerr.Holders, nil
+		}
+	}
+	return upload, qerr.Holders, qerr
+}
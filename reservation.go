@@ -0,0 +1,84 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"context"
+	"time"
+)
+
+// ReservationResources is the capacity a reservation holds on a worker.
+// Memory and disk sizes are in bytes, zero fields reserve none.
+type ReservationResources struct {
+	CPUCores int         `json:"cpuCores,omitempty"`
+	Memory   uint64      `json:"memory,omitempty"`
+	Disk     uint64      `json:"disk,omitempty"`
+	GPUs     *GPURequest `json:"gpus,omitempty"`
+}
+
+// Reservation is capacity held on a worker node for the account, see ReserveNode
+type Reservation struct {
+	ID        string               `json:"id"`
+	NodeID    string               `json:"nodeID"`
+	Resources ReservationResources `json:"resources"`
+	Created   Timestamp            `json:"createdTime"`
+	Expires   Timestamp            `json:"expiresTime"`
+}
+
+// Expired reports whether the reservation ran out
+func (r *Reservation) Expired() bool {
+	return !r.Expires.IsZero() && !time.Now().Before(r.Expires.Time)
+}
+
+// ReserveNode holds resources on the given worker node for duration, so that
+// a multi-job workload can be pushed without other submitters taking the
+// capacity first. Containers run with RunOptions.Reservation set use the
+// held capacity. The node fails the call if it can't provide the resources.
+func (rpc *CCClient) ReserveNode(nodeID string, duration time.Duration, resources ReservationResources) (*Reservation, error) {
+	reservation := new(Reservation)
+	if err := rpc.callInto(context.Background(), reservation, "node_reserve", nodeID, int64(duration/time.Second), resources); err != nil {
+		return nil, err
+	}
+	return reservation, nil
+}
+
+// ExtendReservation extends the reservation with the given id to end
+// duration from now
+func (rpc *CCClient) ExtendReservation(id string, duration time.Duration) (*Reservation, error) {
+	reservation := new(Reservation)
+	if err := rpc.callInto(context.Background(), reservation, "node_extendReservation", id, int64(duration/time.Second)); err != nil {
+		return nil, err
+	}
+	return reservation, nil
+}
+
+// ReleaseReservation frees the capacity held by the reservation with the
+// given id before it expires
+func (rpc *CCClient) ReleaseReservation(id string) error {
+	_, err := rpc.call("node_releaseReservation", id)
+	return err
+}
+
+// ListReservations returns the reservations of the account that haven't
+// expired or been released
+func (rpc *CCClient) ListReservations() ([]Reservation, error) {
+	var reservations []Reservation
+	if err := rpc.callInto(context.Background(), &reservations, "node_listReservations"); err != nil {
+		return nil, err
+	}
+	return reservations, nil
+}
@@ -0,0 +1,280 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultResolverRefresh is how long resolved endpoints are used before
+// they are resolved again
+const DefaultResolverRefresh = 5 * time.Minute
+
+// ErrNoEndpoints is returned by resolvers that found no node endpoint
+var ErrNoEndpoints = errors.New("no node endpoints resolved")
+
+// Resolver derives the RPC endpoints of nodes, e.g. from DNS or a service
+// registry, see WithResolver
+type Resolver interface {
+	// Resolve returns the endpoint urls in order of preference
+	Resolve(ctx context.Context) ([]string, error)
+}
+
+// ResolverFunc is a function implementing Resolver
+type ResolverFunc func(ctx context.Context) ([]string, error)
+
+// Resolve calls f
+func (f ResolverFunc) Resolve(ctx context.Context) ([]string, error) {
+	return f(ctx)
+}
+
+// StaticResolver resolves to a fixed list of endpoints
+type StaticResolver []string
+
+// Resolve returns the endpoints
+func (r StaticResolver) Resolve(ctx context.Context) ([]string, error) {
+	if len(r) == 0 {
+		return nil, ErrNoEndpoints
+	}
+	return append([]string(nil), r...), nil
+}
+
+// DNSResolver resolves the SRV records _ccrpc._tcp.<Name> to endpoints,
+// ordered by priority, and falls back to the TXT records of Name, each
+// holding an endpoint url, optionally prefixed with "ccrpc=".
+type DNSResolver struct {
+	Name string
+	// Scheme is used for SRV targets, http by default
+	Scheme string
+}
+
+// Resolve looks up the records of r.Name
+func (r DNSResolver) Resolve(ctx context.Context) ([]string, error) {
+	scheme := r.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+	var urls []string
+	if _, srvs, err := net.DefaultResolver.LookupSRV(ctx, "ccrpc", "tcp", r.Name); err == nil {
+		for _, srv := range srvs {
+			host := strings.TrimSuffix(srv.Target, ".")
+			urls = append(urls, scheme+"://"+net.JoinHostPort(host, strconv.Itoa(int(srv.Port))))
+		}
+	}
+	if len(urls) > 0 {
+		return urls, nil
+	}
+	txts, err := net.DefaultResolver.LookupTXT(ctx, r.Name)
+	if err != nil {
+		return nil, err
+	}
+	for _, txt := range txts {
+		txt = strings.TrimPrefix(strings.TrimSpace(txt), "ccrpc=")
+		if u, err := url.Parse(txt); err == nil && u.Scheme != "" && u.Host != "" {
+			urls = append(urls, txt)
+		}
+	}
+	if len(urls) == 0 {
+		return nil, ErrNoEndpoints
+	}
+	return urls, nil
+}
+
+// ConsulResolver resolves the healthy instances of a service registered
+// with Consul through its HTTP API
+type ConsulResolver struct {
+	// Address is the url of the Consul agent, e.g. http://127.0.0.1:8500
+	Address string
+	Service string
+	// Scheme of the endpoints, http by default
+	Scheme string
+	Client *http.Client
+}
+
+// Resolve queries the agent for the passing instances of r.Service
+func (r ConsulResolver) Resolve(ctx context.Context) ([]string, error) {
+	scheme := r.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+	u := strings.TrimRight(r.Address, "/") + "/v1/health/service/" + url.PathEscape(r.Service) + "?passing=1"
+	var entries []struct {
+		Node struct {
+			Address string `json:"Address"`
+		} `json:"Node"`
+		Service struct {
+			Address string `json:"Address"`
+			Port    int    `json:"Port"`
+		} `json:"Service"`
+	}
+	if err := resolverRequest(ctx, r.Client, "GET", u, nil, &entries); err != nil {
+		return nil, err
+	}
+	var urls []string
+	for _, e := range entries {
+		host := e.Service.Address
+		if host == "" {
+			host = e.Node.Address
+		}
+		urls = append(urls, scheme+"://"+net.JoinHostPort(host, strconv.Itoa(e.Service.Port)))
+	}
+	if len(urls) == 0 {
+		return nil, ErrNoEndpoints
+	}
+	return urls, nil
+}
+
+// EtcdResolver resolves the endpoint urls stored as the values of the keys
+// below Prefix in etcd, through the JSON gateway of its v3 API
+type EtcdResolver struct {
+	// Endpoint is the url of an etcd member, e.g. http://127.0.0.1:2379
+	Endpoint string
+	Prefix   string
+	Client   *http.Client
+}
+
+// Resolve reads the keys below r.Prefix
+func (r EtcdResolver) Resolve(ctx context.Context) ([]string, error) {
+	// the range end of a prefix is the prefix with its last byte incremented
+	end := []byte(r.Prefix)
+	if len(end) > 0 {
+		end[len(end)-1]++
+	}
+	query := map[string]string{
+		"key":       base64.StdEncoding.EncodeToString([]byte(r.Prefix)),
+		"range_end": base64.StdEncoding.EncodeToString(end),
+	}
+	var resp struct {
+		Kvs []struct {
+			Value []byte `json:"value"`
+		} `json:"kvs"`
+	}
+	if err := resolverRequest(ctx, r.Client, "POST", strings.TrimRight(r.Endpoint, "/")+"/v3/kv/range", query, &resp); err != nil {
+		return nil, err
+	}
+	var urls []string
+	for _, kv := range resp.Kvs {
+		if v := strings.TrimSpace(string(kv.Value)); v != "" {
+			urls = append(urls, v)
+		}
+	}
+	if len(urls) == 0 {
+		return nil, ErrNoEndpoints
+	}
+	return urls, nil
+}
+
+// resolverRequest sends body as JSON, if not nil, and decodes the response into result
+func resolverRequest(ctx context.Context, client *http.Client, method, u string, body, result interface{}) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	var payload bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&payload).Encode(body); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequest(method, u, &payload)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("resolving endpoints: %s", resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+// endpointSet holds the endpoints resolved for a client, shared with the
+// clients derived from it. Calls go to the current endpoint, the next one
+// is used after it failed to connect.
+type endpointSet struct {
+	resolver Resolver
+	refresh  time.Duration
+
+	mu       sync.Mutex
+	urls     []string
+	current  int
+	resolved time.Time
+	// resolving is set while a caller resolves the endpoints
+	resolving bool
+}
+
+// get returns the current endpoint, resolving the endpoints again when they
+// are stale. Stale endpoints are kept if resolving fails, fallback is
+// returned when there are none. One caller resolves at a time without
+// holding the lock, the others get the stale endpoints meanwhile.
+func (e *endpointSet) get(ctx context.Context, fallback string) string {
+	e.mu.Lock()
+	stale := !e.resolving && (len(e.urls) == 0 || time.Since(e.resolved) >= e.refresh)
+	e.resolving = e.resolving || stale
+	e.mu.Unlock()
+	if stale {
+		urls, err := e.resolver.Resolve(ctx)
+		e.mu.Lock()
+		if err == nil && len(urls) > 0 {
+			e.urls, e.current = urls, 0
+		}
+		// resolve again after refresh even on failure, not on every call
+		e.resolved = time.Now()
+		e.resolving = false
+		e.mu.Unlock()
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(e.urls) == 0 {
+		return fallback
+	}
+	return e.urls[e.current]
+}
+
+// failed moves on to the next endpoint if u is the current one
+func (e *endpointSet) failed(u string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(e.urls) > 0 && e.urls[e.current] == u {
+		e.current = (e.current + 1) % len(e.urls)
+	}
+}
+
+// endpoint returns the url calls are sent to
+func (rpc *CCClient) endpoint(ctx context.Context) string {
+	if rpc.endpoints == nil {
+		return rpc.url
+	}
+	return rpc.endpoints.get(ctx, rpc.url)
+}
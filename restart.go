@@ -0,0 +1,163 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultHealBackoff is the wait before the first restart of a crashed job
+	defaultHealBackoff = time.Second
+	// maxHealBackoff bounds the wait between restarts
+	maxHealBackoff = time.Minute
+)
+
+// RestartPolicyName is the kind of a RestartPolicy
+type RestartPolicyName string
+
+const (
+	RestartNo        RestartPolicyName = "no"
+	RestartOnFailure RestartPolicyName = "on-failure"
+	RestartAlways    RestartPolicyName = "always"
+)
+
+// RestartPolicy tells the node when to restart a container that exited. The
+// zero policy leaves it to the node's default.
+type RestartPolicy struct {
+	Name RestartPolicyName
+	// MaxRetries bounds the restarts of RestartOnFailure, zero means no limit
+	MaxRetries int
+}
+
+// RestartOnFailureN returns the policy restarting containers exiting with a
+// non-zero code up to max times
+func RestartOnFailureN(max int) RestartPolicy {
+	return RestartPolicy{Name: RestartOnFailure, MaxRetries: max}
+}
+
+// String formats the policy the way docker does, e.g. "on-failure:3"
+func (p RestartPolicy) String() string {
+	if p.Name == RestartOnFailure && p.MaxRetries > 0 {
+		return fmt.Sprintf("%s:%d", p.Name, p.MaxRetries)
+	}
+	return string(p.Name)
+}
+
+// ParseRestartPolicy parses a policy formatted like docker's --restart flag,
+// "no", "on-failure[:N]" or "always"
+func ParseRestartPolicy(s string) (RestartPolicy, error) {
+	name, max := s, ""
+	if i := strings.IndexByte(s, ':'); i >= 0 {
+		name, max = s[:i], s[i+1:]
+	}
+	p := RestartPolicy{Name: RestartPolicyName(name)}
+	switch p.Name {
+	case RestartNo, RestartAlways:
+		if max != "" {
+			return RestartPolicy{}, fmt.Errorf("restart policy %q takes no retry count", name)
+		}
+	case RestartOnFailure:
+		if max != "" {
+			n, err := strconv.Atoi(max)
+			if err != nil || n < 0 {
+				return RestartPolicy{}, fmt.Errorf("invalid retry count in restart policy %q", s)
+			}
+			p.MaxRetries = n
+		}
+	default:
+		return RestartPolicy{}, fmt.Errorf("unknown restart policy %q", s)
+	}
+	return p, nil
+}
+
+// IsZero reports whether the policy is left to the node
+func (p RestartPolicy) IsZero() bool {
+	return p.Name == ""
+}
+
+// MarshalJSON encodes the policy as its string form
+func (p RestartPolicy) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.String())
+}
+
+// UnmarshalJSON decodes the string form of a policy
+func (p *RestartPolicy) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	if s == "" {
+		*p = RestartPolicy{}
+		return nil
+	}
+	parsed, err := ParseRestartPolicy(s)
+	if err != nil {
+		return err
+	}
+	*p = parsed
+	return nil
+}
+
+// AutoHeal restarts a job's container when it crashes, for long-running
+// services. Containers exiting with code 0 are done and not restarted.
+type AutoHeal struct {
+	// MaxRestarts bounds the restarts, zero means no limit
+	MaxRestarts int
+	// Backoff is the wait before the first restart, doubling with every
+	// further one up to a minute, 1s by default
+	Backoff time.Duration
+	// Relocate restarts crashed containers on another worker rather than
+	// the same one. Containers lost with their worker are always moved.
+	Relocate bool
+}
+
+// heals reports whether a job whose container ended with err after restarts
+// restarts is restarted
+func (h *AutoHeal) heals(ctx context.Context, err error, restarts int) bool {
+	if h == nil || err == nil || ctx.Err() != nil || (h.MaxRestarts > 0 && restarts >= h.MaxRestarts) {
+		return false
+	}
+	var exitErr *ExitError
+	return errors.As(err, &exitErr) || IsNodeFailure(err)
+}
+
+// wait waits before the given restart, reporting false if ctx is done first
+func (h *AutoHeal) wait(ctx context.Context, restart int) bool {
+	delay := h.Backoff
+	if delay <= 0 {
+		delay = defaultHealBackoff
+	}
+	for i := 1; i < restart && delay < maxHealBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxHealBackoff {
+		delay = maxHealBackoff
+	}
+	select {
+	case <-time.After(delay):
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
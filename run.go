@@ -0,0 +1,116 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"math"
+	"time"
+)
+
+// timeoutGrace is how long past its timeout a container may run before the
+// client stops it, leaving the node's own enforcement time to act first
+const timeoutGrace = 30 * time.Second
+
+// RunOptions configures how the node runs a container
+type RunOptions struct {
+	// Timeout is the maximum runtime, after which the node kills and removes
+	// the container. Zero means no limit.
+	Timeout time.Duration `json:"-"`
+	// GPUs requests graphics cards for the container, which fails to start
+	// on nodes that can't provide them
+	GPUs *GPURequest `json:"gpus,omitempty"`
+	// Inputs are tar archives uploaded to the node that are extracted into
+	// the container before it starts
+	Inputs []RunInput `json:"inputs,omitempty"`
+	// Restart tells the node when to restart the container, the node's
+	// default if zero
+	Restart RestartPolicy `json:"-"`
+	// Reservation is the id of a reservation of the node, see ReserveNode,
+	// whose capacity the container uses
+	Reservation string `json:"reservation,omitempty"`
+}
+
+// RunInput is an uploaded tar archive extracted into a container directory
+type RunInput struct {
+	Hash string `json:"hash"`
+	Path string `json:"path"`
+}
+
+// MarshalJSON encodes the options the way the node expects them
+func (o RunOptions) MarshalJSON() ([]byte, error) {
+	type options RunOptions
+	out := struct {
+		options
+		Timeout int64          `json:"timeout,omitempty"`
+		Restart *RestartPolicy `json:"restart,omitempty"`
+	}{options: options(o), Timeout: int64(o.Timeout / time.Second)}
+	if !o.Restart.IsZero() {
+		out.Restart = &o.Restart
+	}
+	return json.Marshal(out)
+}
+
+// ExecuteImageWithOptions runs the image on the given node like ExecuteImage
+// and returns the container id. If opts.Timeout is set the client also stops
+// and removes the container should the node fail to do so in time.
+func (rpc *CCClient) ExecuteImageWithOptions(nodeID, dockImageID string, opts RunOptions) (string, error) {
+	return rpc.executeImageWithOptions(context.Background(), nodeID, dockImageID, opts)
+}
+
+func (rpc *CCClient) executeImageWithOptions(ctx context.Context, nodeID, dockImageID string, opts RunOptions) (string, error) {
+	var contID string
+	if err := rpc.callInto(ctx, &contID, "imagemanager_runImageWithOptions", nodeID, dockImageID, opts); err != nil {
+		return "", err
+	}
+	if opts.Timeout > 0 {
+		ctx, cancel, release := rpc.track(context.Background())
+		go func() {
+			defer release()
+			defer cancel()
+			rpc.enforceTimeout(ctx, nodeID, contID, opts.Timeout+timeoutGrace)
+		}()
+	}
+	return contID, nil
+}
+
+// enforceTimeout stops and removes the container if it still runs after
+// timeout. Failed inspections are retried until then. The container is left
+// alone if ctx ends first, e.g. because the client was closed.
+func (rpc *CCClient) enforceTimeout(ctx context.Context, nodeID, containerID string, timeout time.Duration) {
+	err := Poll(ctx, containerPollInterval, func(ctx context.Context) (bool, error) {
+		details, err := rpc.inspectContainerDetails(ctx, nodeID, containerID)
+		if err != nil {
+			return false, err
+		}
+		return details.State.Exited(), nil
+	}, PollMaxDuration(timeout), PollRetries(math.MaxInt32))
+	if err != ErrPollTimeout {
+		return
+	}
+	if rpc.Debug {
+		log.Printf("container %s on node %s exceeded its timeout, stopping it\n", containerID, nodeID)
+	}
+	if err := rpc.StopContainer(nodeID, containerID); err != nil && rpc.Debug {
+		log.Printf("stopping container %s: %v\n", containerID, err)
+	}
+	if err := rpc.RemoveContainer(nodeID, containerID); err != nil && rpc.Debug {
+		log.Printf("removing container %s: %v\n", containerID, err)
+	}
+}
@@ -0,0 +1,315 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// cronSearchYears bounds the search for the next activation of a cron
+// expression that may never match, such as February 30th
+const cronSearchYears = 5
+
+// Schedule decides when a scheduled job runs
+type Schedule interface {
+	// Next returns the first activation after t, or the zero time if there is none
+	Next(t time.Time) time.Time
+}
+
+type intervalSchedule time.Duration
+
+func (s intervalSchedule) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(s))
+}
+
+// Every returns a schedule activating at a fixed interval, which must be
+// positive for Scheduler.Add to accept it
+func Every(interval time.Duration) Schedule {
+	return intervalSchedule(interval)
+}
+
+// cronSchedule holds the allowed values of each field of a cron expression
+type cronSchedule struct {
+	minute, hour, dom, month, dow map[int]bool
+	// domAny and dowAny tell whether the day fields were "*", since cron
+	// matches either of them when both are restricted
+	domAny, dowAny bool
+}
+
+// ParseCron parses a standard five field cron expression
+// (minute hour day-of-month month day-of-week), e.g. "*/15 2-4 * * 1,3"
+func ParseCron(expr string) (Schedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression %q must have 5 fields", expr)
+	}
+	var s cronSchedule
+	var err error
+	if s.minute, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, err
+	}
+	if s.hour, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, err
+	}
+	if s.dom, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, err
+	}
+	if s.month, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, err
+	}
+	if s.dow, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, err
+	}
+	if s.dow[7] {
+		s.dow[0] = true
+	}
+	s.domAny = fields[2] == "*"
+	s.dowAny = fields[4] == "*"
+	return &s, nil
+}
+
+// parseCronField parses a comma separated list of values, ranges and steps
+func parseCronField(field string, min, max int) (map[int]bool, error) {
+	values := make(map[int]bool)
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step <= 0 {
+				return nil, fmt.Errorf("invalid step in cron field %q", field)
+			}
+			part = part[:i]
+		}
+		lo, hi := min, max
+		if part != "*" {
+			bounds := strings.SplitN(part, "-", 2)
+			var err error
+			if lo, err = strconv.Atoi(bounds[0]); err != nil {
+				return nil, fmt.Errorf("invalid cron field %q", field)
+			}
+			hi = lo
+			if len(bounds) == 2 {
+				if hi, err = strconv.Atoi(bounds[1]); err != nil {
+					return nil, fmt.Errorf("invalid cron field %q", field)
+				}
+			} else if step > 1 {
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return nil, fmt.Errorf("cron field %q out of range %d-%d", field, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			values[v] = true
+		}
+	}
+	return values, nil
+}
+
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	dom, dow := s.dom[t.Day()], s.dow[int(t.Weekday())]
+	if !s.domAny && !s.dowAny {
+		return dom || dow
+	}
+	return dom && dow
+}
+
+func (s *cronSchedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(cronSearchYears, 0, 0)
+	for t.Before(limit) {
+		y, m, d := t.Date()
+		switch {
+		case !s.month[int(m)]:
+			t = time.Date(y, m+1, 1, 0, 0, 0, 0, t.Location())
+		case !s.dayMatches(t):
+			t = time.Date(y, m, d+1, 0, 0, 0, 0, t.Location())
+		case !s.hour[t.Hour()]:
+			t = time.Date(y, m, d, t.Hour()+1, 0, 0, 0, t.Location())
+		case !s.minute[t.Minute()]:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// ScheduleStore persists when scheduled jobs last ran, so that a restarted
+// scheduler neither repeats nor silently skips runs
+type ScheduleStore interface {
+	// LastRun returns the time the job last ran, the zero time if never
+	LastRun(jobID string) (time.Time, error)
+	SaveLastRun(jobID string, t time.Time) error
+}
+
+// ScheduledJob is an image run repeatedly on a node
+type ScheduledJob struct {
+	ID       string
+	NodeID   string
+	ImageID  string
+	Options  RunOptions
+	Schedule Schedule
+}
+
+// ScheduledRun reports a single run of a scheduled job
+type ScheduledRun struct {
+	JobID       string
+	Time        time.Time
+	ContainerID string
+	// State is the final state of the container, nil if it didn't start
+	State *ContainerState
+	Err   error
+	// StoreErr is the error saving the run to the store, in which case it
+	// may be repeated after a restart
+	StoreErr error
+}
+
+type scheduleEntry struct {
+	job  ScheduledJob
+	next time.Time
+}
+
+// Scheduler runs scheduled jobs from within the client process
+type Scheduler struct {
+	rpc   *CCClient
+	store ScheduleStore
+	onRun func(ScheduledRun)
+
+	mu      sync.Mutex
+	entries map[string]*scheduleEntry
+	wake    chan struct{}
+}
+
+// NewScheduler creates a scheduler running jobs through rpc. store may be nil
+// if runs need not survive restarts. onRun, if set, is called when each run
+// has finished, possibly concurrently.
+func (rpc *CCClient) NewScheduler(store ScheduleStore, onRun func(ScheduledRun)) *Scheduler {
+	return &Scheduler{
+		rpc:     rpc,
+		store:   store,
+		onRun:   onRun,
+		entries: make(map[string]*scheduleEntry),
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// Add registers a job. A job that missed runs while no scheduler was running
+// is run once right away.
+func (s *Scheduler) Add(job ScheduledJob) error {
+	if job.ID == "" || job.Schedule == nil {
+		return errors.New("scheduled job needs an id and a schedule")
+	}
+	if interval, ok := job.Schedule.(intervalSchedule); ok && interval <= 0 {
+		return fmt.Errorf("scheduled job %s: interval %v is not positive", job.ID, time.Duration(interval))
+	}
+	last := time.Now()
+	if s.store != nil {
+		t, err := s.store.LastRun(job.ID)
+		if err != nil {
+			return err
+		}
+		if !t.IsZero() {
+			last = t
+		}
+	}
+	s.mu.Lock()
+	s.entries[job.ID] = &scheduleEntry{job: job, next: job.Schedule.Next(last)}
+	s.mu.Unlock()
+	s.notify()
+	return nil
+}
+
+// Remove unregisters a job, runs already started are not affected
+func (s *Scheduler) Remove(jobID string) {
+	s.mu.Lock()
+	delete(s.entries, jobID)
+	s.mu.Unlock()
+	s.notify()
+}
+
+func (s *Scheduler) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run starts due jobs until ctx is done, which also ends the runs it started
+// that are still being started or waited for. Failing to save a run to the
+// store doesn't stop the scheduler, it is reported in the run's StoreErr.
+func (s *Scheduler) Run(ctx context.Context) error {
+	for {
+		now := time.Now()
+		var due []ScheduledJob
+		wait := time.Duration(-1)
+		s.mu.Lock()
+		for id, e := range s.entries {
+			if e.next.IsZero() {
+				delete(s.entries, id)
+				continue
+			}
+			if !e.next.After(now) {
+				due = append(due, e.job)
+				// a schedule that doesn't advance would run the job in a loop
+				if e.next = e.job.Schedule.Next(now); !e.next.After(now) {
+					e.next = time.Time{}
+				}
+			}
+			if until := e.next.Sub(now); !e.next.IsZero() && (wait < 0 || until < wait) {
+				wait = until
+			}
+		}
+		s.mu.Unlock()
+
+		for _, job := range due {
+			var storeErr error
+			if s.store != nil {
+				storeErr = s.store.SaveLastRun(job.ID, now)
+			}
+			go s.run(ctx, job, now, storeErr)
+		}
+
+		var timer <-chan time.Time
+		if wait >= 0 {
+			timer = time.After(wait)
+		}
+		select {
+		case <-timer:
+		case <-s.wake:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (s *Scheduler) run(ctx context.Context, job ScheduledJob, at time.Time, storeErr error) {
+	run := ScheduledRun{JobID: job.ID, Time: at, StoreErr: storeErr}
+	run.ContainerID, run.Err = s.rpc.executeImageWithOptions(ctx, job.NodeID, job.ImageID, job.Options)
+	if run.Err == nil {
+		run.State, run.Err = s.rpc.WaitForContainer(ctx, job.NodeID, run.ContainerID)
+	}
+	if s.onRun != nil {
+		s.onRun(run)
+	}
+}
@@ -0,0 +1,230 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseCronInvalid(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * 32 * *",
+		"* * * 0 *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"*/x * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"1-b * * * *",
+		"1,,2 * * * *",
+	} {
+		if _, err := ParseCron(expr); err == nil {
+			t.Errorf("ParseCron(%q) succeeded, want an error", expr)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	date := func(year int, month time.Month, day, hour, min int) time.Time {
+		return time.Date(year, month, day, hour, min, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		// activations are strictly after the given time
+		{"* * * * *", date(2024, 1, 1, 12, 0), date(2024, 1, 1, 12, 1)},
+		{"* * * * *", date(2024, 1, 1, 12, 0).Add(30 * time.Second), date(2024, 1, 1, 12, 1)},
+		{"*/15 * * * *", date(2024, 1, 1, 12, 7), date(2024, 1, 1, 12, 15)},
+		{"*/15 * * * *", date(2024, 1, 1, 12, 45), date(2024, 1, 1, 13, 0)},
+		{"5-10/2 * * * *", date(2024, 1, 1, 0, 5), date(2024, 1, 1, 0, 7)},
+		{"5/20 * * * *", date(2024, 1, 1, 0, 26), date(2024, 1, 1, 0, 45)},
+		{"0,30 9 * * *", date(2024, 1, 1, 9, 30), date(2024, 1, 2, 9, 0)},
+		{"*/15 2-4 * * 1,3", date(2024, 1, 1, 0, 0), date(2024, 1, 1, 2, 0)},
+		{"*/15 2-4 * * 1,3", date(2024, 1, 1, 4, 45), date(2024, 1, 3, 2, 0)},
+		// month and year rollover
+		{"59 23 31 12 *", date(2024, 6, 1, 0, 0), date(2024, 12, 31, 23, 59)},
+		{"0 0 1 * *", date(2024, 12, 31, 23, 59), date(2025, 1, 1, 0, 0)},
+		{"0 0 29 2 *", date(2024, 3, 1, 0, 0), date(2028, 2, 29, 0, 0)},
+		// day of week 0 and 7 are both Sunday
+		{"0 0 * * 0", date(2024, 1, 1, 0, 0), date(2024, 1, 7, 0, 0)},
+		{"0 0 * * 7", date(2024, 1, 1, 0, 0), date(2024, 1, 7, 0, 0)},
+		// with both day fields restricted either matches: the 13th or Fridays
+		{"0 0 13 * 5", date(2024, 1, 1, 0, 0), date(2024, 1, 5, 0, 0)},
+		{"0 0 13 * 5", date(2024, 1, 12, 0, 0), date(2024, 1, 13, 0, 0)},
+		{"0 0 13 * 5", date(2024, 1, 13, 0, 0), date(2024, 1, 19, 0, 0)},
+		// with one restricted only that one counts
+		{"0 0 13 * *", date(2024, 1, 1, 0, 0), date(2024, 1, 13, 0, 0)},
+		{"0 0 * * 5", date(2024, 1, 12, 0, 0), date(2024, 1, 19, 0, 0)},
+		// never
+		{"0 0 30 2 *", date(2024, 1, 1, 0, 0), time.Time{}},
+		{"0 0 31 4 *", date(2024, 1, 1, 0, 0), time.Time{}},
+	}
+	for _, tt := range tests {
+		s, err := ParseCron(tt.expr)
+		if err != nil {
+			t.Errorf("ParseCron(%q): %v", tt.expr, err)
+			continue
+		}
+		if got := s.Next(tt.from); !got.Equal(tt.want) {
+			t.Errorf("ParseCron(%q).Next(%v) = %v, want %v", tt.expr, tt.from, got, tt.want)
+		}
+	}
+}
+
+func TestCronNextKeepsLocation(t *testing.T) {
+	zone := time.FixedZone("UTC+5", 5*60*60)
+	s, err := ParseCron("0 9 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	from := time.Date(2024, 1, 1, 10, 0, 0, 0, zone)
+	want := time.Date(2024, 1, 2, 9, 0, 0, 0, zone)
+	if got := s.Next(from); !got.Equal(want) || got.Location() != zone {
+		t.Errorf("Next(%v) = %v, want %v", from, got, want)
+	}
+}
+
+func TestEveryNext(t *testing.T) {
+	from := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	for _, interval := range []time.Duration{time.Second, 90 * time.Minute, 24 * time.Hour} {
+		if got, want := Every(interval).Next(from), from.Add(interval); !got.Equal(want) {
+			t.Errorf("Every(%v).Next(%v) = %v, want %v", interval, from, got, want)
+		}
+	}
+}
+
+func TestSchedulerAddRejects(t *testing.T) {
+	s := NewCCClient("http://127.0.0.1:0").NewScheduler(nil, nil)
+	tests := []struct {
+		name string
+		job  ScheduledJob
+	}{
+		{"no id", ScheduledJob{Schedule: Every(time.Minute)}},
+		{"no schedule", ScheduledJob{ID: "job"}},
+		{"zero interval", ScheduledJob{ID: "job", Schedule: Every(0)}},
+		{"negative interval", ScheduledJob{ID: "job", Schedule: Every(-time.Second)}},
+	}
+	for _, tt := range tests {
+		if err := s.Add(tt.job); err == nil {
+			t.Errorf("%s: Add succeeded, want an error", tt.name)
+		}
+	}
+}
+
+// stuckSchedule is due right away and never advances
+type stuckSchedule struct{}
+
+func (stuckSchedule) Next(t time.Time) time.Time { return t }
+
+func TestSchedulerStuckScheduleRunsOnce(t *testing.T) {
+	var runs int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req rpcRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		w.Header().Set("Content-Type", "application/json")
+		switch req.Method {
+		case "imagemanager_runImageWithOptions":
+			atomic.AddInt32(&runs, 1)
+			fmt.Fprint(w, `{"jsonrpc":"2.0","id":1,"result":"c1"}`)
+		default:
+			fmt.Fprint(w, `{"jsonrpc":"2.0","id":1,"result":"{\"State\":{\"Status\":\"exited\"}}"}`)
+		}
+	}))
+	defer srv.Close()
+
+	finished := make(chan ScheduledRun, 10)
+	s := NewCCClient(srv.URL, WithThrottleRetries(0, 0)).NewScheduler(nil, func(run ScheduledRun) { finished <- run })
+	if err := s.Add(ScheduledJob{ID: "job", NodeID: "node", ImageID: "image", Schedule: stuckSchedule{}}); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	s.Run(ctx)
+	select {
+	case run := <-finished:
+		if run.Err != nil {
+			t.Error(run.Err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the job never ran")
+	}
+	if n := atomic.LoadInt32(&runs); n != 1 {
+		t.Errorf("the job ran %d times, want 1", n)
+	}
+}
+
+// failingStore fails to save runs
+type failingStore struct{}
+
+func (failingStore) LastRun(string) (time.Time, error) { return time.Time{}, nil }
+
+func (failingStore) SaveLastRun(string, time.Time) error { return fmt.Errorf("disk full") }
+
+func TestSchedulerKeepsRunningWhenSavingFails(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req rpcRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		w.Header().Set("Content-Type", "application/json")
+		if req.Method == "imagemanager_runImageWithOptions" {
+			fmt.Fprint(w, `{"jsonrpc":"2.0","id":1,"result":"c1"}`)
+			return
+		}
+		fmt.Fprint(w, `{"jsonrpc":"2.0","id":1,"result":"{\"State\":{\"Status\":\"exited\"}}"}`)
+	}))
+	defer srv.Close()
+
+	finished := make(chan ScheduledRun, 100)
+	s := NewCCClient(srv.URL, WithThrottleRetries(0, 0)).NewScheduler(failingStore{}, func(run ScheduledRun) { finished <- run })
+	if err := s.Add(ScheduledJob{ID: "job", NodeID: "node", ImageID: "image", Schedule: Every(20 * time.Millisecond)}); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.Run(ctx) }()
+	for i := 0; i < 2; i++ {
+		select {
+		case run := <-finished:
+			if run.StoreErr == nil {
+				t.Error("the failure to save the run wasn't reported")
+			}
+		case err := <-done:
+			t.Fatalf("Run stopped: %v", err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("the job didn't run")
+		}
+	}
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("Run returned %v, want %v", err, context.Canceled)
+	}
+}
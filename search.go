@@ -0,0 +1,119 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// defaultSearchLimit is the page size of searches without a limit
+const defaultSearchLimit = 100
+
+// SearchQuery selects images or containers in the node's database. Zero
+// fields don't filter.
+type SearchQuery struct {
+	// Name matches the names registered with RegisterImageMetadata, and
+	// for containers those of their images, as a case-insensitive substring
+	Name string `json:"name,omitempty"`
+	// Tags selects the images, or containers of images, with all of them
+	Tags    []string `json:"tags,omitempty"`
+	Account string   `json:"account,omitempty"`
+	// Status matches the status of containers, e.g. "running"
+	Status string `json:"status,omitempty"`
+	// Since and Until bound the creation time of the records
+	Since time.Time `json:"-"`
+	Until time.Time `json:"-"`
+	// Cursor is empty for the first page and the Next cursor of the
+	// previous page afterwards
+	Cursor string `json:"cursor,omitempty"`
+	// Limit is the page size, 100 if it isn't positive
+	Limit int `json:"limit"`
+}
+
+// MarshalJSON encodes the query the way the node expects it, with unix times
+func (q SearchQuery) MarshalJSON() ([]byte, error) {
+	type query SearchQuery
+	out := struct {
+		query
+		Since int64 `json:"since,omitempty"`
+		Until int64 `json:"until,omitempty"`
+	}{query: query(q)}
+	if out.Limit <= 0 {
+		out.Limit = defaultSearchLimit
+	}
+	if !q.Since.IsZero() {
+		out.Since = q.Since.Unix()
+	}
+	if !q.Until.IsZero() {
+		out.Until = q.Until.Unix()
+	}
+	return json.Marshal(out)
+}
+
+// ImageMatch is an image found by SearchImages, with its registered metadata
+type ImageMatch struct {
+	ImageRecord
+	Name        string   `json:"name,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Description string   `json:"description,omitempty"`
+}
+
+// ImagePage is a page of the images found by SearchImages
+type ImagePage struct {
+	Images []ImageMatch `json:"records"`
+	// Total is the number of matches over all pages
+	Total int `json:"total"`
+	// Next is the cursor of the following page, empty on the last page
+	Next string `json:"next"`
+}
+
+// ContainerMatch is a container found by SearchContainers, with the
+// registered name of its image
+type ContainerMatch struct {
+	ContainerRecord
+	ImageName string `json:"imageName,omitempty"`
+}
+
+// ContainerPage is a page of the containers found by SearchContainers
+type ContainerPage struct {
+	Containers []ContainerMatch `json:"records"`
+	// Total is the number of matches over all pages
+	Total int `json:"total"`
+	// Next is the cursor of the following page, empty on the last page
+	Next string `json:"next"`
+}
+
+// SearchImages returns a page of the images in the node's database matching query
+func (rpc *CCClient) SearchImages(query SearchQuery) (*ImagePage, error) {
+	page := new(ImagePage)
+	if err := rpc.lvldbQuery(context.Background(), page, "searchImages", query); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// SearchContainers returns a page of the containers in the node's database
+// matching query
+func (rpc *CCClient) SearchContainers(query SearchQuery) (*ContainerPage, error) {
+	page := new(ContainerPage)
+	if err := rpc.lvldbQuery(context.Background(), page, "searchContainers", query); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
@@ -0,0 +1,119 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// defaultLogsPollInterval is how often followed logs are fetched from the node
+const defaultLogsPollInterval = time.Second
+
+// ServiceLogsOptions controls which log lines SwarmServiceLogs returns
+type ServiceLogsOptions struct {
+	// Follow keeps the stream open and delivers new lines as they are written
+	Follow bool
+	// Since only returns lines written at or after this time
+	Since time.Time
+	// PollInterval is how often new lines are fetched when following
+	PollInterval time.Duration
+}
+
+// ServiceLogEntry is a single log line written by a task of a swarm service
+type ServiceLogEntry struct {
+	NodeID    string    `json:"nodeID"`
+	TaskID    string    `json:"taskID"`
+	Timestamp time.Time `json:"timestamp"`
+	Line      string    `json:"line"`
+}
+
+func (rpc *CCClient) serviceLogs(ctx context.Context, serviceID string, since time.Time) ([]ServiceLogEntry, error) {
+	var sinceParam string
+	if !since.IsZero() {
+		sinceParam = since.UTC().Format(time.RFC3339Nano)
+	}
+	var entries []ServiceLogEntry
+	if err := rpc.callInto(ctx, &entries, "service_logs", serviceID, sinceParam); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Timestamp.Before(entries[j].Timestamp)
+	})
+	return entries, nil
+}
+
+// SwarmServiceLogs streams the logs of all tasks of a swarm service, merged
+// across nodes in timestamp order. Without opts.Follow the channel is closed
+// once the existing lines have been delivered.
+func (rpc *CCClient) SwarmServiceLogs(ctx context.Context, serviceID string, opts ServiceLogsOptions) (<-chan ServiceLogEntry, *Subscription, error) {
+	entries, err := rpc.serviceLogs(ctx, serviceID, opts.Since)
+	if err != nil {
+		return nil, nil, err
+	}
+	interval := opts.PollInterval
+	if interval <= 0 {
+		interval = defaultLogsPollInterval
+	}
+	ch := make(chan ServiceLogEntry)
+	sub, ctx := rpc.newSubscription(ctx)
+	go func() {
+		err := rpc.followServiceLogs(ctx, ch, serviceID, opts.Since, entries, opts.Follow, interval)
+		if ctx.Err() != nil {
+			err = nil
+		}
+		close(ch)
+		sub.finish(err)
+	}()
+	return ch, sub, nil
+}
+
+func (rpc *CCClient) followServiceLogs(ctx context.Context, ch chan<- ServiceLogEntry, serviceID string, since time.Time, entries []ServiceLogEntry, follow bool, interval time.Duration) error {
+	// lines sharing the newest timestamp are remembered so that the next
+	// poll, which starts at that timestamp, doesn't deliver them twice
+	seen := make(map[ServiceLogEntry]bool)
+	for {
+		for _, e := range entries {
+			if e.Timestamp.Before(since) || seen[e] {
+				continue
+			}
+			if e.Timestamp.After(since) {
+				since = e.Timestamp
+				seen = make(map[ServiceLogEntry]bool)
+			}
+			seen[e] = true
+			select {
+			case ch <- e:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		if !follow {
+			return nil
+		}
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		var err error
+		if entries, err = rpc.serviceLogs(ctx, serviceID, since); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,243 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// ssePath is the path of the node's server-sent events endpoint, relative
+// to its rpc url
+const ssePath = "/events"
+
+// sseEvent is an event of a server-sent events stream
+type sseEvent struct {
+	id    string
+	event string
+	data  string
+}
+
+// sseSubscription is a subscription streamed as server-sent events. The node
+// first sends a "subscribed" event with the subscription id, then an event
+// for every notification, or an "error" event carrying an rpc error.
+type sseSubscription struct {
+	body      io.ReadCloser
+	r         *bufio.Reader
+	cancel    context.CancelFunc
+	namespace string
+	id        string
+	lastID    string
+	debug     bool
+	closeOnce sync.Once
+}
+
+// sseURL returns the url subscribing to method with params as event stream
+func (rpc *CCClient) sseURL(ctx context.Context, method string, params []interface{}) (string, error) {
+	u, err := url.Parse(rpc.endpoint(ctx))
+	if err != nil {
+		return "", err
+	}
+	if params == nil {
+		params = []interface{}{}
+	}
+	encoded, err := json.Marshal(params)
+	if err != nil {
+		return "", err
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + ssePath
+	u.RawQuery = url.Values{"method": {method}, "params": {string(encoded)}}.Encode()
+	return u.String(), nil
+}
+
+// sseSubscribe subscribes to the given namespace's events, calling
+// namespace_subscribe with params, over a server-sent events stream
+func (rpc *CCClient) sseSubscribe(ctx context.Context, namespace string, params ...interface{}) (*sseSubscription, error) {
+	u, err := rpc.sseURL(ctx, namespace+"_subscribe", params)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+	setHeaders(ctx, req.Header, rpc.userAgent, rpc.header)
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Cache-Control", "no-cache")
+	token, err := rpc.authToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	// the stream outlives the call, it is ended with close
+	streamCtx, cancel := context.WithCancel(context.Background())
+	stop := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			cancel()
+		case <-stop:
+		}
+	}()
+	resp, err := rpc.client.Do(req.WithContext(streamCtx))
+	if err != nil {
+		close(stop)
+		cancel()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, &transportError{transport: TransportSSE, err: err}
+	}
+	sub := &sseSubscription{
+		body:      resp.Body,
+		r:         bufio.NewReader(resp.Body),
+		cancel:    cancel,
+		namespace: namespace,
+		debug:     rpc.Debug,
+	}
+	err = sub.handshake(resp)
+	close(stop)
+	if err != nil {
+		sub.close()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+	return sub, nil
+}
+
+// handshake checks that resp is an event stream and reads the subscription id
+func (s *sseSubscription) handshake(resp *http.Response) error {
+	if err := authStatusError(resp.StatusCode); err != nil {
+		return err
+	}
+	if err := throttleStatusError(resp); err != nil {
+		return err
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if resp.StatusCode != http.StatusOK || mediaType != "text/event-stream" {
+		return &transportError{transport: TransportSSE, err: fmt.Errorf("no event stream: %s", resp.Status)}
+	}
+	ev, err := s.read()
+	if err != nil {
+		return &transportError{transport: TransportSSE, err: err}
+	}
+	switch ev.event {
+	case "error":
+		return decodeSSEError(ev)
+	case "subscribed":
+		if err := json.Unmarshal([]byte(ev.data), &s.id); err != nil || s.id == "" {
+			return fmt.Errorf("%s_subscribe returned no subscription id", s.namespace)
+		}
+		return nil
+	default:
+		return fmt.Errorf("%s_subscribe returned unexpected %q event", s.namespace, ev.event)
+	}
+}
+
+// read reads the next event of the stream, skipping comments
+func (s *sseSubscription) read() (sseEvent, error) {
+	var ev sseEvent
+	var data []string
+	for {
+		line, err := s.r.ReadString('\n')
+		if err != nil {
+			if err == io.EOF && line != "" {
+				err = io.ErrUnexpectedEOF
+			}
+			return ev, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			if data == nil && ev.event == "" {
+				continue
+			}
+			ev.data = strings.Join(data, "\n")
+			return ev, nil
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+		field, value := line, ""
+		if i := strings.IndexByte(line, ':'); i >= 0 {
+			field, value = line[:i], strings.TrimPrefix(line[i+1:], " ")
+		}
+		switch field {
+		case "data":
+			data = append(data, value)
+		case "event":
+			ev.event = value
+		case "id":
+			ev.id = value
+		}
+	}
+}
+
+// decodeSSEError returns the rpc error carried by an "error" event
+func decodeSSEError(ev sseEvent) error {
+	var rerr rpcError
+	if err := json.Unmarshal([]byte(ev.data), &rerr); err != nil {
+		return &DecodeError{Method: "subscription", Type: "error event", Payload: []byte(ev.data), Err: err}
+	}
+	return rerr
+}
+
+// next blocks until the next notification of the subscription arrives
+func (s *sseSubscription) next() (json.RawMessage, error) {
+	for {
+		ev, err := s.read()
+		if err != nil {
+			return nil, err
+		}
+		if s.debug {
+			log.Printf("%s subscription %s\nNotification: %s\n", s.namespace, s.id, ev.data)
+		}
+		switch ev.event {
+		case "", "message":
+			if ev.id != "" {
+				s.lastID = ev.id
+			}
+			return json.RawMessage(ev.data), nil
+		case "error":
+			return nil, decodeSSEError(ev)
+		}
+	}
+}
+
+// close ends the stream, which unsubscribes on the node
+func (s *sseSubscription) close() {
+	s.closeOnce.Do(func() {
+		s.cancel()
+		s.body.Close()
+	})
+}
+
+func (s *sseSubscription) lastEventID() string {
+	return s.lastID
+}
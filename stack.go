@@ -0,0 +1,350 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// stackNamespaceLabel is the label docker uses to group the services of a stack
+const stackNamespaceLabel = "com.docker.stack.namespace"
+
+// ErrDependencyFailed is reported for stack services that were not deployed
+// because a service they depend on failed
+var ErrDependencyFailed = errors.New("dependency failed to deploy")
+
+// StackFile is the subset of a docker-compose/stack file the node can deploy
+type StackFile struct {
+	Version  string                  `yaml:"version"`
+	Name     string                  `yaml:"name"`
+	Services map[string]StackService `yaml:"services"`
+}
+
+// StackService is a single service entry of a stack file
+type StackService struct {
+	Image       string      `yaml:"image"`
+	Build       interface{} `yaml:"build"`
+	Entrypoint  stringList  `yaml:"entrypoint"`
+	Command     stringList  `yaml:"command"`
+	Environment envList     `yaml:"environment"`
+	Ports       []string    `yaml:"ports"`
+	DependsOn   dependsList `yaml:"depends_on"`
+	Deploy      struct {
+		Mode     string  `yaml:"mode"`
+		Replicas *uint64 `yaml:"replicas"`
+	} `yaml:"deploy"`
+}
+
+// StackServiceResult is the outcome of deploying one service of a stack
+type StackServiceResult struct {
+	Service string
+	Err     error
+}
+
+// stringList accepts both the string and the list form of a compose field
+type stringList []string
+
+func (l *stringList) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err == nil {
+		*l = strings.Fields(s)
+		return nil
+	}
+	var list []string
+	if err := unmarshal(&list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+// envList accepts both the map and the KEY=VALUE list form of environment
+type envList []string
+
+func (l *envList) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var list []string
+	if err := unmarshal(&list); err == nil {
+		*l = list
+		return nil
+	}
+	var m map[string]interface{}
+	if err := unmarshal(&m); err != nil {
+		return err
+	}
+	env := make([]string, 0, len(m))
+	for k, v := range m {
+		if v == nil {
+			env = append(env, k)
+			continue
+		}
+		env = append(env, fmt.Sprintf("%s=%v", k, v))
+	}
+	sort.Strings(env)
+	*l = env
+	return nil
+}
+
+// dependsList accepts both the list and the map form of depends_on. The
+// conditions of the map form are not checked, dependents are deployed once
+// their dependencies are.
+type dependsList []string
+
+func (l *dependsList) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var list []string
+	if err := unmarshal(&list); err == nil {
+		*l = list
+		return nil
+	}
+	var m map[string]interface{}
+	if err := unmarshal(&m); err != nil {
+		return err
+	}
+	deps := make([]string, 0, len(m))
+	for name := range m {
+		deps = append(deps, name)
+	}
+	sort.Strings(deps)
+	*l = deps
+	return nil
+}
+
+// ParseStackFile parses and validates a docker-compose/stack file
+func ParseStackFile(composeYAML []byte) (*StackFile, error) {
+	stack := new(StackFile)
+	if err := yaml.Unmarshal(composeYAML, stack); err != nil {
+		return nil, fmt.Errorf("parsing stack file: %v", err)
+	}
+	if err := stack.Validate(); err != nil {
+		return nil, err
+	}
+	return stack, nil
+}
+
+// Validate checks that the stack can be deployed to a swarm
+func (s *StackFile) Validate() error {
+	if s.Version != "" && !strings.HasPrefix(s.Version, "3") {
+		return fmt.Errorf("stack file version %q is not supported, version 3 is required", s.Version)
+	}
+	if len(s.Services) == 0 {
+		return errors.New("stack file defines no services")
+	}
+	for name, svc := range s.Services {
+		if svc.Image == "" {
+			if svc.Build != nil {
+				return fmt.Errorf("service %q: build is not supported, an image is required", name)
+			}
+			return fmt.Errorf("service %q: image is required", name)
+		}
+		for _, dep := range svc.DependsOn {
+			if _, ok := s.Services[dep]; !ok {
+				return fmt.Errorf("service %q depends on undefined service %q", name, dep)
+			}
+		}
+		for _, p := range svc.Ports {
+			if _, err := parsePort(p); err != nil {
+				return fmt.Errorf("service %q: %v", name, err)
+			}
+		}
+		switch svc.Deploy.Mode {
+		case "", "replicated":
+		case "global":
+			if svc.Deploy.Replicas != nil {
+				return fmt.Errorf("service %q: replicas can not be used with global mode", name)
+			}
+		default:
+			return fmt.Errorf("service %q: unknown deploy mode %q", name, svc.Deploy.Mode)
+		}
+	}
+	_, err := s.deployOrder()
+	return err
+}
+
+// deployOrder sorts the services so that dependencies come first
+func (s *StackFile) deployOrder() ([]string, error) {
+	names := make([]string, 0, len(s.Services))
+	for name := range s.Services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int, len(names))
+	order := make([]string, 0, len(names))
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visiting:
+			return fmt.Errorf("service %q is part of a dependency cycle", name)
+		case visited:
+			return nil
+		}
+		state[name] = visiting
+		for _, dep := range s.Services[name].DependsOn {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		state[name] = visited
+		order = append(order, name)
+		return nil
+	}
+	for _, name := range names {
+		if err := visit(name); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+type swarmPortConfig struct {
+	Protocol      string `json:"Protocol"`
+	TargetPort    uint32 `json:"TargetPort"`
+	PublishedPort uint32 `json:"PublishedPort,omitempty"`
+}
+
+// swarmServiceSpec mirrors the parts of docker's swarm.ServiceSpec the node accepts
+type swarmServiceSpec struct {
+	Name         string            `json:"Name"`
+	Labels       map[string]string `json:"Labels,omitempty"`
+	TaskTemplate struct {
+		ContainerSpec struct {
+			Image   string   `json:"Image"`
+			Command []string `json:"Command,omitempty"`
+			Args    []string `json:"Args,omitempty"`
+			Env     []string `json:"Env,omitempty"`
+		} `json:"ContainerSpec"`
+	} `json:"TaskTemplate"`
+	Mode struct {
+		Replicated *struct {
+			Replicas *uint64 `json:"Replicas,omitempty"`
+		} `json:"Replicated,omitempty"`
+		Global *struct{} `json:"Global,omitempty"`
+	} `json:"Mode"`
+	EndpointSpec *struct {
+		Ports []swarmPortConfig `json:"Ports"`
+	} `json:"EndpointSpec,omitempty"`
+}
+
+// parsePort parses the short compose port syntax, e.g. "8080:80/udp"
+func parsePort(port string) (swarmPortConfig, error) {
+	cfg := swarmPortConfig{Protocol: "tcp"}
+	if i := strings.LastIndex(port, "/"); i >= 0 {
+		cfg.Protocol = port[i+1:]
+		port = port[:i]
+		if cfg.Protocol != "tcp" && cfg.Protocol != "udp" {
+			return cfg, fmt.Errorf("invalid port protocol %q", cfg.Protocol)
+		}
+	}
+	parts := strings.Split(port, ":")
+	if len(parts) > 2 {
+		return cfg, fmt.Errorf("invalid port %q", port)
+	}
+	target, err := strconv.ParseUint(parts[len(parts)-1], 10, 16)
+	if err != nil {
+		return cfg, fmt.Errorf("invalid port %q", port)
+	}
+	cfg.TargetPort = uint32(target)
+	if len(parts) == 2 {
+		published, err := strconv.ParseUint(parts[0], 10, 16)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid port %q", port)
+		}
+		cfg.PublishedPort = uint32(published)
+	}
+	return cfg, nil
+}
+
+func (s *StackFile) serviceSpec(name string) (string, error) {
+	svc := s.Services[name]
+	spec := swarmServiceSpec{Name: name}
+	if s.Name != "" {
+		spec.Name = s.Name + "_" + name
+		spec.Labels = map[string]string{stackNamespaceLabel: s.Name}
+	}
+	spec.TaskTemplate.ContainerSpec.Image = svc.Image
+	spec.TaskTemplate.ContainerSpec.Command = svc.Entrypoint
+	spec.TaskTemplate.ContainerSpec.Args = svc.Command
+	spec.TaskTemplate.ContainerSpec.Env = svc.Environment
+	if svc.Deploy.Mode == "global" {
+		spec.Mode.Global = &struct{}{}
+	} else {
+		spec.Mode.Replicated = &struct {
+			Replicas *uint64 `json:"Replicas,omitempty"`
+		}{Replicas: svc.Deploy.Replicas}
+	}
+	if len(svc.Ports) > 0 {
+		spec.EndpointSpec = &struct {
+			Ports []swarmPortConfig `json:"Ports"`
+		}{}
+		for _, p := range svc.Ports {
+			cfg, err := parsePort(p)
+			if err != nil {
+				return "", err
+			}
+			spec.EndpointSpec.Ports = append(spec.EndpointSpec.Ports, cfg)
+		}
+	}
+	b, err := json.Marshal(spec)
+	return string(b), err
+}
+
+// DeploySwarmStack parses the given docker-compose/stack file and runs each
+// of its services on the swarm formed by nodes, dependencies first.
+// Services whose dependencies failed are not deployed and report ErrDependencyFailed.
+func (rpc *CCClient) DeploySwarmStack(composeYAML []byte, nodes []string) ([]StackServiceResult, error) {
+	stack, err := ParseStackFile(composeYAML)
+	if err != nil {
+		return nil, err
+	}
+	order, err := stack.deployOrder()
+	if err != nil {
+		return nil, err
+	}
+	failed := make(map[string]bool)
+	results := make([]StackServiceResult, 0, len(order))
+	for _, name := range order {
+		result := StackServiceResult{Service: name}
+		for _, dep := range stack.Services[name].DependsOn {
+			if failed[dep] {
+				result.Err = ErrDependencyFailed
+				break
+			}
+		}
+		if result.Err == nil {
+			var spec string
+			if spec, result.Err = stack.serviceSpec(name); result.Err == nil {
+				result.Err = rpc.RunSwarmService(spec, nodes)
+			}
+		}
+		if result.Err != nil {
+			failed[name] = true
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
@@ -0,0 +1,137 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// statsWindow is the number of recent calls per method the rolling
+// statistics are computed over
+const statsWindow = 128
+
+// MethodStats are the latency and error statistics of the calls of one
+// method, see CCClient.Stats
+type MethodStats struct {
+	Method string
+	// Calls and Errors count all calls since the client was created or
+	// the statistics were reset
+	Calls  uint64
+	Errors uint64
+	// the remaining fields cover the most recent calls only
+	Window    int
+	ErrorRate float64
+	Mean      time.Duration
+	P50       time.Duration
+	P90       time.Duration
+	P99       time.Duration
+	Max       time.Duration
+}
+
+// callSample is a finished call
+type callSample struct {
+	duration time.Duration
+	failed   bool
+}
+
+// methodWindow holds the totals and the recent calls of a method
+type methodWindow struct {
+	calls   uint64
+	errors  uint64
+	samples [statsWindow]callSample
+	next    int
+	filled  int
+}
+
+// callStats tracks the calls of a client and the clients derived from it
+type callStats struct {
+	mu      sync.Mutex
+	methods map[string]*methodWindow
+}
+
+func newCallStats() *callStats {
+	return &callStats{methods: make(map[string]*methodWindow)}
+}
+
+// record adds a finished call of method
+func (s *callStats) record(method string, d time.Duration, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w := s.methods[method]
+	if w == nil {
+		w = new(methodWindow)
+		s.methods[method] = w
+	}
+	w.calls++
+	if err != nil {
+		w.errors++
+	}
+	w.samples[w.next] = callSample{duration: d, failed: err != nil}
+	w.next = (w.next + 1) % statsWindow
+	if w.filled < statsWindow {
+		w.filled++
+	}
+}
+
+// summary computes the statistics of a method. s.mu must be held.
+func (w *methodWindow) summary(method string) MethodStats {
+	stats := MethodStats{Method: method, Calls: w.calls, Errors: w.errors, Window: w.filled}
+	if w.filled == 0 {
+		return stats
+	}
+	durations := make([]time.Duration, w.filled)
+	var total time.Duration
+	failed := 0
+	for i, sample := range w.samples[:w.filled] {
+		durations[i] = sample.duration
+		total += sample.duration
+		if sample.failed {
+			failed++
+		}
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	percentile := func(p int) time.Duration {
+		return durations[(len(durations)-1)*p/100]
+	}
+	stats.ErrorRate = float64(failed) / float64(w.filled)
+	stats.Mean = total / time.Duration(w.filled)
+	stats.P50, stats.P90, stats.P99 = percentile(50), percentile(90), percentile(99)
+	stats.Max = durations[len(durations)-1]
+	return stats
+}
+
+// Stats returns the latency and error statistics of the calls made by the
+// client and the clients derived from it, by method, e.g. to avoid a node
+// that became slow. Calls answered from the cache aren't counted.
+func (rpc *CCClient) Stats() map[string]MethodStats {
+	rpc.stats.mu.Lock()
+	defer rpc.stats.mu.Unlock()
+	stats := make(map[string]MethodStats, len(rpc.stats.methods))
+	for method, w := range rpc.stats.methods {
+		stats[method] = w.summary(method)
+	}
+	return stats
+}
+
+// ResetStats drops the statistics returned by Stats
+func (rpc *CCClient) ResetStats() {
+	rpc.stats.mu.Lock()
+	rpc.stats.methods = make(map[string]*methodWindow)
+	rpc.stats.mu.Unlock()
+}
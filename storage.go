@@ -0,0 +1,98 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// NodeStorage is the disk usage of a worker node in bytes
+type NodeStorage struct {
+	Total      uint64 `json:"total"`
+	Free       uint64 `json:"free"`
+	Images     uint64 `json:"images"`
+	Containers uint64 `json:"containers"`
+	// Uploads are the files stored on the node, e.g. image tarballs and artifacts
+	Uploads uint64 `json:"uploads"`
+}
+
+// UsedPercent returns the share of the disk in use, 0 to 100
+func (s *NodeStorage) UsedPercent() float64 {
+	if s.Total == 0 {
+		return 0
+	}
+	return float64(s.Total-s.Free) * 100 / float64(s.Total)
+}
+
+// CleanupPolicy selects what CleanupNode removes. Only stopped containers,
+// unused images and uploads are removed.
+type CleanupPolicy struct {
+	Images     bool `json:"images"`
+	Containers bool `json:"containers"`
+	Uploads    bool `json:"uploads"`
+	// OlderThan only removes items created before that long ago
+	OlderThan time.Duration `json:"-"`
+	// FreeTarget stops the cleanup, oldest items first, once that many bytes
+	// are free. Zero removes everything selected.
+	FreeTarget uint64 `json:"freeTarget,omitempty"`
+	// DryRun reports what would be removed without removing it
+	DryRun bool `json:"dryRun,omitempty"`
+}
+
+// MarshalJSON encodes the policy the way the node expects it
+func (p CleanupPolicy) MarshalJSON() ([]byte, error) {
+	type policy CleanupPolicy
+	return json.Marshal(struct {
+		policy
+		OlderThan int64 `json:"olderThan,omitempty"`
+	}{policy(p), int64(p.OlderThan / time.Second)})
+}
+
+// CleanupReport lists what CleanupNode removed
+type CleanupReport struct {
+	Images     []string `json:"images,omitempty"`
+	Containers []string `json:"containers,omitempty"`
+	Uploads    []string `json:"uploads,omitempty"`
+	// Freed is the number of bytes reclaimed
+	Freed uint64 `json:"freed"`
+}
+
+// IsEmpty reports whether nothing was removed
+func (r *CleanupReport) IsEmpty() bool {
+	return len(r.Images) == 0 && len(r.Containers) == 0 && len(r.Uploads) == 0
+}
+
+// GetNodeStorage returns the disk usage of the given worker node
+func (rpc *CCClient) GetNodeStorage(nodeID string) (*NodeStorage, error) {
+	storage := new(NodeStorage)
+	if err := rpc.callInto(context.Background(), storage, "node_getStorage", nodeID); err != nil {
+		return nil, err
+	}
+	return storage, nil
+}
+
+// CleanupNode removes old images, containers and uploads from the given
+// worker node according to policy, to keep jobs from failing for lack of space
+func (rpc *CCClient) CleanupNode(nodeID string, policy CleanupPolicy) (*CleanupReport, error) {
+	report := new(CleanupReport)
+	if err := rpc.callInto(context.Background(), report, "node_cleanup", nodeID, policy); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
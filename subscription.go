@@ -0,0 +1,84 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"context"
+	"sync"
+)
+
+// Subscription is a handle on a stream of values the client delivers on a channel.
+// The channel is closed once the stream ends; Err reports why it ended.
+type Subscription struct {
+	cancel  context.CancelFunc
+	release func()
+	done    chan struct{}
+	// dropped counts the notifications dropped on overflow
+	dropped uint64
+
+	mu  sync.Mutex
+	err error
+}
+
+func (rpc *CCClient) newSubscription(ctx context.Context) (*Subscription, context.Context) {
+	ctx, cancel, release := rpc.track(ctx)
+	return &Subscription{cancel: cancel, release: release, done: make(chan struct{})}, ctx
+}
+
+// track derives the context of a stream, which also ends when the client is
+// closed. release must be called once the stream's goroutines have stopped.
+func (rpc *CCClient) track(ctx context.Context) (context.Context, context.CancelFunc, func()) {
+	rpc.life.streams.Add(1)
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		select {
+		case <-rpc.life.closed:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel, rpc.life.streams.Done
+}
+
+// Unsubscribe stops the stream and waits until its channel is closed
+func (s *Subscription) Unsubscribe() {
+	s.cancel()
+	<-s.done
+}
+
+// Done is closed when the stream has ended
+func (s *Subscription) Done() <-chan struct{} {
+	return s.done
+}
+
+// Err returns the error that ended the stream, or nil if it ended normally
+// or was unsubscribed
+func (s *Subscription) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// finish records the outcome of the stream and releases its resources
+func (s *Subscription) finish(err error) {
+	s.mu.Lock()
+	s.err = err
+	s.mu.Unlock()
+	s.cancel()
+	close(s.done)
+	s.release()
+}
@@ -0,0 +1,120 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultThrottleRetries is how often a throttled call is retried
+	DefaultThrottleRetries = 3
+	// DefaultMaxThrottleWait is the longest Retry-After delay waited for
+	// before retrying; longer ones fail the call right away
+	DefaultMaxThrottleWait = 30 * time.Second
+)
+
+// ErrThrottled matches the errors of requests the node or a proxy in front
+// of it refused for load, see ThrottledError
+var ErrThrottled = errors.New("throttled")
+
+// ThrottledError is returned when the node or a proxy answers 429, or 503
+// with a Retry-After header. RetryAfter is the delay it asked for, zero if
+// it gave none.
+type ThrottledError struct {
+	StatusCode int
+	RetryAfter time.Duration
+}
+
+func (e *ThrottledError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("throttled (%d), retry after %s", e.StatusCode, e.RetryAfter)
+	}
+	return fmt.Sprintf("throttled (%d)", e.StatusCode)
+}
+
+// Is makes ThrottledError match ErrThrottled
+func (e *ThrottledError) Is(target error) bool {
+	return target == ErrThrottled
+}
+
+// throttleStatusError reports a response asking the client to back off
+func throttleStatusError(resp *http.Response) error {
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+		return nil
+	}
+	delay, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+	// a 503 without delay is an outage rather than backpressure
+	if !ok && resp.StatusCode == http.StatusServiceUnavailable {
+		return nil
+	}
+	return &ThrottledError{StatusCode: resp.StatusCode, RetryAfter: delay}
+}
+
+// parseRetryAfter parses a Retry-After value, given either in seconds or as
+// an http date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if secs, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	at, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	if d := at.Sub(now); d > 0 {
+		return d, true
+	}
+	return 0, true
+}
+
+// throttlePolicy bounds how throttled requests are retried
+type throttlePolicy struct {
+	retries int
+	maxWait time.Duration
+}
+
+var defaultThrottlePolicy = throttlePolicy{retries: DefaultThrottleRetries, maxWait: DefaultMaxThrottleWait}
+
+// wait sleeps for the delay asked for by err if it is a ThrottledError that
+// may be retried after attempt previous retries, reporting whether to retry
+func (p throttlePolicy) wait(ctx context.Context, err error, attempt int) bool {
+	var terr *ThrottledError
+	if !errors.As(err, &terr) || attempt >= p.retries || terr.RetryAfter <= 0 || terr.RetryAfter > p.maxWait {
+		return false
+	}
+	timer := time.NewTimer(terr.RetryAfter)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,312 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// unlockConcurrency bounds the unlocks in flight when the node can't unlock
+// accounts in a batch
+const unlockConcurrency = 8
+
+// ErrUnknownAccount is returned by a TokenPool for accounts it doesn't hold
+var ErrUnknownAccount = errors.New("account not in token pool")
+
+// UnlockErrors are the failures of UnlockAccounts by account
+type UnlockErrors map[string]error
+
+func (e UnlockErrors) Error() string {
+	accounts := make([]string, 0, len(e))
+	for acc := range e {
+		accounts = append(accounts, acc)
+	}
+	sort.Strings(accounts)
+	msgs := make([]string, len(accounts))
+	for i, acc := range accounts {
+		msgs[i] = fmt.Sprintf("%s: %v", acc, e[acc])
+	}
+	return fmt.Sprintf("unlocking %d accounts failed: %s", len(e), strings.Join(msgs, "; "))
+}
+
+// unlockResult is the outcome of unlocking an account in a batch
+type unlockResult struct {
+	Account string `json:"account"`
+	Token   string `json:"token"`
+	Error   string `json:"error,omitempty"`
+	// err is the failure of an unlock outside a batch
+	err error
+}
+
+// UnlockAccounts unlocks the accounts in passphrases, keyed by account, in
+// one batch and returns their sessions by account. Accounts that failed to
+// unlock are reported in an UnlockErrors, along with the sessions of the
+// others.
+func (rpc *CCClient) UnlockAccounts(passphrases map[string]string) (map[string]Session, error) {
+	return rpc.unlockAccounts(context.Background(), passphrases)
+}
+
+func (rpc *CCClient) unlockAccounts(ctx context.Context, passphrases map[string]string) (map[string]Session, error) {
+	sessions := make(map[string]Session, len(passphrases))
+	if len(passphrases) == 0 {
+		return sessions, nil
+	}
+	var results []unlockResult
+	err := rpc.callInto(ctx, &results, "accounts_unlockAccounts", passphrases)
+	if isMethodNotFound(err) {
+		results, err = rpc.unlockEach(ctx, passphrases), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	failed := make(UnlockErrors)
+	for _, r := range results {
+		if r.err != nil {
+			failed[r.Account] = r.err
+			continue
+		}
+		if r.Error != "" {
+			failed[r.Account] = secondFactorError(rpcError{Message: r.Error})
+			continue
+		}
+		sessions[r.Account] = Session{Account: r.Account, Token: r.Token}
+	}
+	for acc := range passphrases {
+		if _, ok := sessions[acc]; !ok && failed[acc] == nil {
+			failed[acc] = errors.New("missing from the node's answer")
+		}
+	}
+	if len(failed) > 0 {
+		return sessions, failed
+	}
+	return sessions, nil
+}
+
+// unlockEach unlocks the accounts one by one, for nodes without batch unlocks
+func (rpc *CCClient) unlockEach(ctx context.Context, passphrases map[string]string) []unlockResult {
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		results = make([]unlockResult, 0, len(passphrases))
+		slots   = make(chan struct{}, unlockConcurrency)
+	)
+	for acc, passphrase := range passphrases {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(acc, passphrase string) {
+			defer func() { <-slots; wg.Done() }()
+			r := unlockResult{Account: acc}
+			r.err = secondFactorError(rpc.callInto(ctx, &r.Token, "accounts_unlockAccount", acc, passphrase))
+			mu.Lock()
+			results = append(results, r)
+			mu.Unlock()
+		}(acc, passphrase)
+	}
+	wg.Wait()
+	return results
+}
+
+// TokenPoolOptions configures a TokenPool
+type TokenPoolOptions struct {
+	// Refresh unlocks all accounts again that often, ahead of their tokens
+	// expiring. Zero only renews tokens once the node rejects them.
+	Refresh time.Duration
+}
+
+// TokenPool holds unlocked sessions of many accounts, e.g. of the end users
+// a service manages compute for, renewing their tokens as needed
+type TokenPool struct {
+	rpc  *CCClient
+	opts TokenPoolOptions
+
+	mu          sync.Mutex
+	passphrases map[string]string
+	tokens      map[string]*cachedToken
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewTokenPool unlocks the accounts in passphrases, keyed by account, and
+// returns a pool of their sessions. Accounts that failed to unlock are
+// reported in an UnlockErrors along with the pool, and are unlocked again
+// when their session is asked for.
+func (rpc *CCClient) NewTokenPool(passphrases map[string]string, opts TokenPoolOptions) (*TokenPool, error) {
+	p := &TokenPool{
+		rpc:         rpc.derive(),
+		opts:        opts,
+		passphrases: make(map[string]string, len(passphrases)),
+		tokens:      make(map[string]*cachedToken, len(passphrases)),
+		done:        make(chan struct{}),
+	}
+	// unlocking must not ask a provider for a token itself
+	p.rpc.auth = nil
+	for acc, passphrase := range passphrases {
+		p.passphrases[acc] = passphrase
+		p.tokens[acc] = new(cachedToken)
+	}
+	err := p.refresh(context.Background())
+	var failed UnlockErrors
+	if err != nil && !errors.As(err, &failed) {
+		return nil, err
+	}
+	if opts.Refresh > 0 {
+		rpc.life.streams.Add(1)
+		go p.loop()
+	}
+	return p, err
+}
+
+// refresh unlocks all accounts of the pool in a batch
+func (p *TokenPool) refresh(ctx context.Context) error {
+	p.mu.Lock()
+	passphrases := make(map[string]string, len(p.passphrases))
+	for acc, passphrase := range p.passphrases {
+		passphrases[acc] = passphrase
+	}
+	p.mu.Unlock()
+	sessions, err := p.rpc.unlockAccounts(ctx, passphrases)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for acc, s := range sessions {
+		if c := p.tokens[acc]; c != nil {
+			c.mu.Lock()
+			c.token, c.expires = s.Token, time.Time{}
+			c.mu.Unlock()
+		}
+	}
+	return err
+}
+
+func (p *TokenPool) loop() {
+	defer p.rpc.life.streams.Done()
+	ticker := time.NewTicker(p.opts.Refresh)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := p.refresh(context.Background()); err != nil && p.rpc.Debug {
+				log.Printf("refreshing token pool: %v\n", err)
+			}
+		case <-p.done:
+			return
+		case <-p.rpc.life.closed:
+			return
+		}
+	}
+}
+
+// entry returns the cached token and passphrase of account
+func (p *TokenPool) entry(account string) (*cachedToken, string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	c, ok := p.tokens[account]
+	if !ok {
+		return nil, "", fmt.Errorf("%w: %s", ErrUnknownAccount, account)
+	}
+	return c, p.passphrases[account], nil
+}
+
+func (p *TokenPool) token(ctx context.Context, account string, force bool) (string, error) {
+	c, passphrase, err := p.entry(account)
+	if err != nil {
+		return "", err
+	}
+	return c.get(ctx, force, func(ctx context.Context) (string, time.Time, error) {
+		var token string
+		err := p.rpc.callInto(ctx, &token, "accounts_unlockAccount", account, passphrase)
+		return token, time.Time{}, secondFactorError(err)
+	})
+}
+
+// Session returns the session of account, unlocking it if it has none
+func (p *TokenPool) Session(account string) (Session, error) {
+	token, err := p.token(context.Background(), account, false)
+	if err != nil {
+		return Session{}, err
+	}
+	return Session{Account: account, Token: token}, nil
+}
+
+// Client returns a client acting as account. Tokens the node rejects are
+// renewed by unlocking the account again.
+func (p *TokenPool) Client(account string) (*CCClient, error) {
+	if _, _, err := p.entry(account); err != nil {
+		return nil, err
+	}
+	c := p.rpc.derive()
+	c.auth = &poolAuth{pool: p, account: account}
+	c.account = account
+	return c, nil
+}
+
+// Add adds account to the pool, unlocking it
+func (p *TokenPool) Add(account, passphrase string) error {
+	p.mu.Lock()
+	p.passphrases[account] = passphrase
+	p.tokens[account] = new(cachedToken)
+	p.mu.Unlock()
+	_, err := p.token(context.Background(), account, true)
+	return err
+}
+
+// Remove removes account from the pool. Its token stays valid on the node
+// until it expires.
+func (p *TokenPool) Remove(account string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.passphrases, account)
+	delete(p.tokens, account)
+}
+
+// Accounts returns the accounts of the pool, sorted
+func (p *TokenPool) Accounts() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	accounts := make([]string, 0, len(p.passphrases))
+	for acc := range p.passphrases {
+		accounts = append(accounts, acc)
+	}
+	sort.Strings(accounts)
+	return accounts
+}
+
+// Close stops refreshing the tokens of the pool
+func (p *TokenPool) Close() {
+	p.closeOnce.Do(func() { close(p.done) })
+}
+
+// poolAuth provides the tokens of an account of a pool
+type poolAuth struct {
+	pool    *TokenPool
+	account string
+}
+
+func (a *poolAuth) Token(ctx context.Context) (string, error) {
+	return a.pool.token(ctx, a.account, false)
+}
+
+func (a *poolAuth) Refresh(ctx context.Context) (string, error) {
+	return a.pool.token(ctx, a.account, true)
+}
@@ -0,0 +1,202 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+)
+
+// SubscriptionTransport is the protocol subscriptions receive events over
+type SubscriptionTransport string
+
+const (
+	// TransportAuto uses the first transport the node can be reached with,
+	// probing them in the order below
+	TransportAuto      SubscriptionTransport = ""
+	TransportWebSocket SubscriptionTransport = "websocket"
+	// TransportSSE streams events as server-sent events over plain http,
+	// for networks blocking websockets
+	TransportSSE SubscriptionTransport = "sse"
+	// TransportLongPoll polls the node for events with blocking calls, as
+	// a last resort when no stream can be held open
+	TransportLongPoll SubscriptionTransport = "longpoll"
+)
+
+var transportOrder = []SubscriptionTransport{TransportWebSocket, TransportSSE, TransportLongPoll}
+
+// eventSource delivers the notifications of a subscription
+type eventSource interface {
+	// next blocks until the next notification arrives
+	next() (json.RawMessage, error)
+	// close ends the subscription, unblocking a pending next
+	close()
+	// lastEventID returns the id of the last notification, empty if the
+	// node doesn't number them
+	lastEventID() string
+}
+
+// transportError reports that a transport can't reach the node, in which
+// case the next one is tried
+type transportError struct {
+	transport SubscriptionTransport
+	err       error
+}
+
+func (e *transportError) Error() string {
+	return fmt.Sprintf("%s: %v", e.transport, e.err)
+}
+
+func (e *transportError) Unwrap() error {
+	return e.err
+}
+
+// transportSelection is the transport subscriptions of a client and the
+// clients derived from it use, forced with WithSubscriptionTransport or
+// remembered once probing found one
+type transportSelection struct {
+	forced SubscriptionTransport
+
+	mu       sync.Mutex
+	selected SubscriptionTransport
+}
+
+// order returns the transports to try, the one known to work first
+func (s *transportSelection) order() []SubscriptionTransport {
+	if s.forced != TransportAuto {
+		return []SubscriptionTransport{s.forced}
+	}
+	s.mu.Lock()
+	selected := s.selected
+	s.mu.Unlock()
+	if selected == TransportAuto {
+		return transportOrder
+	}
+	order := []SubscriptionTransport{selected}
+	for _, t := range transportOrder {
+		if t != selected {
+			order = append(order, t)
+		}
+	}
+	return order
+}
+
+func (s *transportSelection) remember(t SubscriptionTransport) {
+	s.mu.Lock()
+	s.selected = t
+	s.mu.Unlock()
+}
+
+// SubscriptionTransport returns the transport subscriptions use, TransportAuto
+// until one was found by probing the node
+func (rpc *CCClient) SubscriptionTransport() SubscriptionTransport {
+	if rpc.transports.forced != TransportAuto {
+		return rpc.transports.forced
+	}
+	rpc.transports.mu.Lock()
+	defer rpc.transports.mu.Unlock()
+	return rpc.transports.selected
+}
+
+// subscribe subscribes to the given namespace's events, calling
+// namespace_subscribe with params over the first transport that reaches
+// the node
+func (rpc *CCClient) subscribe(ctx context.Context, namespace string, params ...interface{}) (eventSource, error) {
+	var first error
+	for _, t := range rpc.transports.order() {
+		var src eventSource
+		var err error
+		switch t {
+		case TransportWebSocket:
+			src, err = rpc.wsSubscribe(ctx, namespace, params...)
+		case TransportSSE:
+			src, err = rpc.sseSubscribe(ctx, namespace, params...)
+		case TransportLongPoll:
+			src, err = rpc.longPollSubscribe(ctx, namespace, params...)
+		default:
+			err = fmt.Errorf("unknown subscription transport %q", t)
+		}
+		if err == nil {
+			rpc.transports.remember(t)
+			return src, nil
+		}
+		var terr *transportError
+		if ctx.Err() != nil || !errors.As(err, &terr) {
+			return nil, err
+		}
+		if rpc.Debug {
+			log.Printf("%s subscription unavailable over %v\n", namespace, err)
+		}
+		if first == nil {
+			first = err
+		}
+	}
+	return nil, first
+}
+
+// subscribeStream subscribes to the given namespace's events and calls
+// deliver with every notification from a background goroutine, until ctx is
+// done or deliver fails. Notifications are buffered between reading and
+// delivery according to the client's eventBuffer. done is called once
+// delivery has stopped, before the returned Subscription finishes.
+func (rpc *CCClient) subscribeStream(ctx context.Context, deliver func(ctx context.Context, raw json.RawMessage) error, done func(), namespace string, params ...interface{}) (*Subscription, error) {
+	src, err := rpc.subscribeReconnecting(ctx, nil, namespace, params...)
+	if err != nil {
+		return nil, err
+	}
+	sub, ctx := rpc.newSubscription(ctx)
+	go func() {
+		<-ctx.Done()
+		src.close()
+	}()
+	events := make(chan json.RawMessage, rpc.events.size)
+	var readErr error
+	go func() {
+		defer close(events)
+		for readErr == nil {
+			var raw json.RawMessage
+			if raw, readErr = src.next(); readErr == nil {
+				readErr = rpc.events.push(ctx, events, raw, sub)
+			}
+		}
+	}()
+	go func() {
+		var err error
+		for raw := range events {
+			if err != nil {
+				continue
+			}
+			if err = deliver(ctx, raw); err != nil {
+				// stops the reader, the buffer is drained until it has
+				src.close()
+			}
+		}
+		if err == nil {
+			err = readErr
+		}
+		if ctx.Err() != nil {
+			err = nil
+		}
+		done()
+		sub.finish(err)
+	}()
+	return sub, nil
+}
@@ -0,0 +1,129 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrSecondFactorRequired is returned when unlocking an account the node
+// protects with a second factor, see UnlockAccountWithFactor
+var ErrSecondFactorRequired = errors.New("second factor required")
+
+// SecondFactorType is the kind of a second factor
+type SecondFactorType string
+
+const (
+	// SecondFactorTOTP is a time-based one-time password
+	SecondFactorTOTP SecondFactorType = "totp"
+	// SecondFactorSignature is a signature of the node's unlock challenge,
+	// e.g. by a hardware wallet
+	SecondFactorSignature SecondFactorType = "signature"
+)
+
+// SecondFactor proves possession of a second factor when unlocking
+type SecondFactor struct {
+	Type SecondFactorType `json:"type"`
+	// Code is the one-time password of a TOTP factor
+	Code string `json:"code,omitempty"`
+	// Challenge and Signature are the hex encoded challenge and its
+	// signature of a signature factor
+	Challenge string `json:"challenge,omitempty"`
+	Signature string `json:"signature,omitempty"`
+}
+
+// TOTP returns the second factor of a one-time password
+func TOTP(code string) SecondFactor {
+	return SecondFactor{Type: SecondFactorTOTP, Code: code}
+}
+
+// ChallengeSigner signs unlock challenges with a key kept apart from the
+// passphrase, e.g. on a hardware wallet
+type ChallengeSigner interface {
+	SignChallenge(ctx context.Context, account string, challenge []byte) ([]byte, error)
+}
+
+// isSecondFactorError reports whether an rpc error asks for a second factor
+func isSecondFactorError(err rpcError) bool {
+	msg := strings.ToLower(err.Message)
+	return strings.Contains(msg, "second factor") || strings.Contains(msg, "2fa") || strings.Contains(msg, "totp")
+}
+
+// secondFactorError wraps the errors of unlock calls asking for a second
+// factor in ErrSecondFactorRequired
+func secondFactorError(err error) error {
+	var rerr rpcError
+	if errors.As(err, &rerr) && isSecondFactorError(rerr) {
+		return fmt.Errorf("%w: %v", ErrSecondFactorRequired, err)
+	}
+	return err
+}
+
+// UnlockAccountWithFactor unlocks an account protected with a second factor
+// and returns the session token
+func (rpc *CCClient) UnlockAccountWithFactor(account, passphrase string, factor SecondFactor) (string, error) {
+	return rpc.unlockWithFactor(context.Background(), account, passphrase, factor)
+}
+
+func (rpc *CCClient) unlockWithFactor(ctx context.Context, account, passphrase string, factor SecondFactor) (string, error) {
+	var token string
+	if err := rpc.callInto(ctx, &token, "accounts_unlockAccountWithFactor", account, passphrase, factor); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// UnlockChallenge returns a fresh challenge of the node to sign for
+// unlocking account with a signature factor
+func (rpc *CCClient) UnlockChallenge(account string) ([]byte, error) {
+	return rpc.unlockChallenge(context.Background(), account)
+}
+
+func (rpc *CCClient) unlockChallenge(ctx context.Context, account string) ([]byte, error) {
+	var challenge string
+	if err := rpc.callInto(ctx, &challenge, "accounts_unlockChallenge", account); err != nil {
+		return nil, err
+	}
+	decoded, err := hex.DecodeString(strings.TrimPrefix(challenge, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("accounts_unlockChallenge: invalid challenge: %v", err)
+	}
+	return decoded, nil
+}
+
+// UnlockAccountWithSigner unlocks an account protected with a signature
+// factor, having signer sign a fresh challenge of the node, and returns the
+// session token
+func (rpc *CCClient) UnlockAccountWithSigner(ctx context.Context, account, passphrase string, signer ChallengeSigner) (string, error) {
+	challenge, err := rpc.unlockChallenge(ctx, account)
+	if err != nil {
+		return "", err
+	}
+	sig, err := signer.SignChallenge(ctx, account, challenge)
+	if err != nil {
+		return "", fmt.Errorf("signing unlock challenge: %w", err)
+	}
+	return rpc.unlockWithFactor(ctx, account, passphrase, SecondFactor{
+		Type:      SecondFactorSignature,
+		Challenge: hex.EncodeToString(challenge),
+		Signature: hex.EncodeToString(sig),
+	})
+}
@@ -0,0 +1,344 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DefaultChunkSize is the size of the chunks of chunked uploads
+	DefaultChunkSize = 8 << 20
+	// DefaultUploadStreams is the number of chunks uploaded concurrently
+	DefaultUploadStreams = 4
+	// defaultChunkRetries is how often a failed chunk is sent again
+	defaultChunkRetries = 3
+	// chunkRetryDelay is the wait before the first retry of a chunk
+	chunkRetryDelay = 500 * time.Millisecond
+)
+
+// chunkHashHeader carries the hex encoded sha256 of a chunk, which the node
+// checks before storing it
+const chunkHashHeader = "X-Chunk-Hash"
+
+// errChunkingUnsupported is returned by nodes without chunk assembly
+var errChunkingUnsupported = errors.New("node doesn't assemble chunked uploads")
+
+// ChunkedUploadOptions configures UploadFileChunked
+type ChunkedUploadOptions struct {
+	// ChunkSize is the size of the chunks, DefaultChunkSize by default
+	ChunkSize int64
+	// Streams is the number of chunks sent concurrently, each over its own
+	// connection, DefaultUploadStreams by default
+	Streams int
+	// Retries is how often a failed chunk is sent again, 3 by default
+	Retries int
+	// OnProgress is called with the bytes stored by the node so far, never
+	// concurrently
+	OnProgress func(done, total int64)
+}
+
+// chunking is the chunked upload configuration of an UploadClient
+type chunking struct {
+	threshold int64
+	opts      ChunkedUploadOptions
+}
+
+// chunkedSession is the node's answer to starting a chunked upload
+type chunkedSession struct {
+	ID string `json:"id"`
+	// Received lists the chunks the node already holds, when resuming
+	Received []int `json:"received,omitempty"`
+}
+
+// chunkedURL returns the url of the chunked upload endpoint, followed by elems
+func (c *UploadClient) chunkedURL(elems ...string) string {
+	return strings.TrimSuffix(c.url, "/") + "/chunked/" + strings.Join(elems, "/")
+}
+
+// UploadFileChunked uploads the file like UploadFile, but split into chunks
+// sent over several concurrent connections, which is much faster for large
+// files over links with high latency. The node assembles the chunks and the
+// hash of the assembled file is verified like for UploadFile. Nodes without
+// chunk assembly are sent the file in a single upload, as are all following
+// files.
+func (c *UploadClient) UploadFileChunked(ctx context.Context, filename, token string, opts ChunkedUploadOptions) (*UploadResult, error) {
+	if atomic.LoadInt32(&c.unchunked) != 0 {
+		return c.UploadFileContext(ctx, filename, token)
+	}
+	if opts.ChunkSize <= 0 {
+		opts.ChunkSize = DefaultChunkSize
+	}
+	if opts.Streams <= 0 {
+		opts.Streams = DefaultUploadStreams
+	}
+	if opts.Retries <= 0 {
+		opts.Retries = defaultChunkRetries
+	}
+	fh, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+	info, err := fh.Stat()
+	if err != nil {
+		return nil, err
+	}
+	hash, err := HashFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	size := info.Size()
+	chunks := int((size + opts.ChunkSize - 1) / opts.ChunkSize)
+	name := filepath.Base(filename)
+
+	var session chunkedSession
+	err = c.chunkedCall(ctx, "POST", c.chunkedURL(), token, map[string]interface{}{
+		"filename":  name,
+		"size":      size,
+		"hash":      hash,
+		"chunkSize": opts.ChunkSize,
+		"chunks":    chunks,
+	}, &session)
+	if errors.Is(err, errChunkingUnsupported) {
+		atomic.StoreInt32(&c.unchunked, 1)
+		return c.UploadFileContext(ctx, filename, token)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := c.sendChunks(ctx, fh, size, token, session, opts); err != nil {
+		// leave nothing half assembled behind
+		abort, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		c.chunkedCall(abort, "DELETE", c.chunkedURL(session.ID), token, nil, nil)
+		cancel()
+		return nil, err
+	}
+	result := new(UploadResult)
+	if err := c.chunkedCall(ctx, "POST", c.chunkedURL(session.ID, "complete"), token, nil, result); err != nil {
+		return nil, err
+	}
+	if result.Filename == "" {
+		result.Filename = name
+	}
+	if result.Size == 0 {
+		result.Size = size
+	}
+	if !sameHash(hash, result.Hash) {
+		return nil, &ChecksumError{Name: filename, Expected: hash, Actual: result.Hash}
+	}
+	return result, nil
+}
+
+// sendChunks uploads the chunks of the file the node doesn't hold yet
+func (c *UploadClient) sendChunks(ctx context.Context, f *os.File, size int64, token string, session chunkedSession, opts ChunkedUploadOptions) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	chunks := int((size + opts.ChunkSize - 1) / opts.ChunkSize)
+	received := make(map[int]bool, len(session.Received))
+	for _, i := range session.Received {
+		received[i] = true
+	}
+	var (
+		done     int64
+		mu       sync.Mutex
+		firstErr error
+		wg       sync.WaitGroup
+		indexes  = make(chan int)
+	)
+	progress := func(n int64) {
+		d := atomic.AddInt64(&done, n)
+		if opts.OnProgress != nil {
+			mu.Lock()
+			opts.OnProgress(d, size)
+			mu.Unlock()
+		}
+	}
+	for w := 0; w < opts.Streams; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				off := int64(i) * opts.ChunkSize
+				n := opts.ChunkSize
+				if off+n > size {
+					n = size - off
+				}
+				if err := c.sendChunk(ctx, io.NewSectionReader(f, off, n), i, n, token, session.ID, opts.Retries); err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = fmt.Errorf("chunk %d: %w", i, err)
+					}
+					mu.Unlock()
+					cancel()
+					continue
+				}
+				progress(n)
+			}
+		}()
+	}
+	for i := 0; i < chunks; i++ {
+		if received[i] {
+			off := int64(i) * opts.ChunkSize
+			if off+opts.ChunkSize > size {
+				progress(size - off)
+			} else {
+				progress(opts.ChunkSize)
+			}
+			continue
+		}
+		select {
+		case indexes <- i:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	close(indexes)
+	wg.Wait()
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
+
+// sendChunk uploads one chunk, sending it again after failures
+func (c *UploadClient) sendChunk(ctx context.Context, r *io.SectionReader, index int, size int64, token, id string, retries int) error {
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return err
+	}
+	sum := hex.EncodeToString(h.Sum(nil))
+	var err error
+	for attempt := 0; ; attempt++ {
+		if _, serr := r.Seek(0, io.SeekStart); serr != nil {
+			return serr
+		}
+		err = c.putChunk(ctx, r, index, size, sum, token, id)
+		if err == nil || ctx.Err() != nil || attempt >= retries {
+			break
+		}
+		var terr *ThrottledError
+		if errors.As(err, &terr) {
+			if !defaultThrottlePolicy.wait(ctx, err, 0) {
+				break
+			}
+			continue
+		}
+		select {
+		case <-time.After(chunkRetryDelay << uint(attempt)):
+		case <-ctx.Done():
+		}
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+func (c *UploadClient) putChunk(ctx context.Context, r io.Reader, index int, size int64, sum, token, id string) error {
+	req, err := http.NewRequest("PUT", c.chunkedURL(id, strconv.Itoa(index)), ioutil.NopCloser(r))
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set(chunkHashHeader, sum)
+	return c.doChunked(ctx, req, token, nil)
+}
+
+// chunkedCall sends a request with a JSON body, if any, to the chunked
+// upload endpoint and decodes its JSON answer into result, if not nil
+func (c *UploadClient) chunkedCall(ctx context.Context, method, url, token string, body, result interface{}) error {
+	var r io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = strings.NewReader(string(data))
+	}
+	req, err := http.NewRequest(method, url, r)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return c.doChunked(ctx, req, token, result)
+}
+
+func (c *UploadClient) doChunked(ctx context.Context, req *http.Request, token string, result interface{}) error {
+	setHeaders(ctx, req.Header, c.userAgent, c.header)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := c.do(ctx, req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	switch resp.StatusCode {
+	case http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		if req.Method == "POST" && req.URL.String() == c.chunkedURL() {
+			return errChunkingUnsupported
+		}
+	}
+	if err := throttleStatusError(resp); err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var payload struct {
+			Error string `json:"error"`
+		}
+		msg := strings.TrimSpace(string(body))
+		if json.Unmarshal(body, &payload) == nil && payload.Error != "" {
+			msg = payload.Error
+		}
+		return &UploadError{StatusCode: resp.StatusCode, Message: msg}
+	}
+	if result == nil {
+		return nil
+	}
+	if err := json.Unmarshal(body, result); err != nil {
+		return fmt.Errorf("invalid answer of the upload endpoint: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"context"
+	"io"
+)
+
+// StoredUpload is a file an account has uploaded to the node
+type StoredUpload struct {
+	UploadResult
+	Account    string    `json:"account"`
+	UploadedAt Timestamp `json:"uploadedAt"`
+}
+
+// ListUploads returns the files account has stored on the node
+func (rpc *CCClient) ListUploads(account, token string) ([]StoredUpload, error) {
+	var uploads []StoredUpload
+	if err := rpc.callInto(tokenContext(token), &uploads, "imagemanager_listUploads", account); err != nil {
+		return nil, err
+	}
+	return uploads, nil
+}
+
+// DeleteUpload removes the file stored under hash from the node to reclaim
+// its space. Images and containers created from it are not affected.
+func (rpc *CCClient) DeleteUpload(hash, token string) error {
+	if _, err := rpc.callContext(tokenContext(token), "imagemanager_deleteUpload", hash); err != nil {
+		return err
+	}
+	if rpc.artifacts != nil {
+		rpc.artifacts.ForgetUpload(hash)
+	}
+	return nil
+}
+
+// DownloadArtifact streams the file stored on the node under hash to w,
+// verifying its hash, see DownloadClient.DownloadArtifact
+func (rpc *CCClient) DownloadArtifact(hash string, w io.Writer, onProgress func(done, total int64)) (*ArtifactInfo, error) {
+	if rpc.downloader == nil {
+		return nil, ErrNoDownloadClient
+	}
+	token, err := rpc.authToken(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	return rpc.downloader.DownloadArtifact(hash, w, token, onProgress)
+}
@@ -0,0 +1,70 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import "context"
+
+// AccountQuota are the limits a node enforces on an account, zero if unlimited
+type AccountQuota struct {
+	StorageBytes   int64   `json:"storageBytes"`
+	ComputeMinutes float64 `json:"computeMinutes"`
+}
+
+// AccountUsage are the resources an account has consumed on the node
+type AccountUsage struct {
+	Account        string  `json:"account"`
+	StorageBytes   int64   `json:"storageBytes"`
+	ComputeMinutes float64 `json:"computeMinutes"`
+	// JobsByNode counts the jobs the account ran per worker node
+	JobsByNode map[string]int `json:"jobsByNode"`
+	// Quota is nil if the node enforces no quota
+	Quota *AccountQuota `json:"quota,omitempty"`
+}
+
+// StorageLeft returns the bytes the account may still store, or -1 without
+// a storage quota
+func (u *AccountUsage) StorageLeft() int64 {
+	if u.Quota == nil || u.Quota.StorageBytes == 0 {
+		return -1
+	}
+	if left := u.Quota.StorageBytes - u.StorageBytes; left > 0 {
+		return left
+	}
+	return 0
+}
+
+// ComputeMinutesLeft returns the compute minutes the account may still
+// consume, or -1 without a compute quota
+func (u *AccountUsage) ComputeMinutesLeft() float64 {
+	if u.Quota == nil || u.Quota.ComputeMinutes == 0 {
+		return -1
+	}
+	if left := u.Quota.ComputeMinutes - u.ComputeMinutes; left > 0 {
+		return left
+	}
+	return 0
+}
+
+// GetAccountUsage returns the storage, compute and jobs account has used on
+// the node, and its quota if one is enforced
+func (rpc *CCClient) GetAccountUsage(account string) (*AccountUsage, error) {
+	usage := new(AccountUsage)
+	if err := rpc.callInto(context.Background(), usage, "accounts_getUsage", account); err != nil {
+		return nil, err
+	}
+	return usage, nil
+}
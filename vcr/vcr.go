@@ -0,0 +1,246 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package vcr records the HTTP traffic of the SDK's clients to fixture files
+// and replays it, so that applications built on the SDK can be tested
+// without a live node.
+//
+//	rec, err := vcr.New("testdata/deploy.json", vcr.ModeAuto, nil)
+//	...
+//	defer rec.Close()
+//	client := ccgosdk.NewCCClient(url, ccgosdk.WithHTTPClient(rec.Client()))
+//	uploader := ccgosdk.NewUploadClient(uploadURL, ccgosdk.WithUploadHTTPClient(rec.Client()))
+//
+// JSON-RPC calls are matched on their body. Other requests, such as uploads,
+// are matched on method and url only and their bodies are not recorded.
+// Authorization headers are never written to fixtures.
+package vcr
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Mode selects whether a Recorder records or replays
+type Mode int
+
+const (
+	// ModeAuto replays the fixture if it exists and records it otherwise
+	ModeAuto Mode = iota
+	// ModeRecord sends requests to the node and records them
+	ModeRecord
+	// ModeReplay answers requests from the fixture only
+	ModeReplay
+)
+
+// ErrNoInteraction is returned in replay mode for requests the fixture
+// has no unused recording of
+var ErrNoInteraction = errors.New("vcr: no recorded interaction matches the request")
+
+// Request is a recorded request
+type Request struct {
+	Method      string `json:"method"`
+	URL         string `json:"url"`
+	ContentType string `json:"contentType,omitempty"`
+	// Body is only kept for JSON requests, BodySize for all
+	Body     json.RawMessage `json:"body,omitempty"`
+	BodySize int64           `json:"bodySize"`
+}
+
+// Response is a recorded response
+type Response struct {
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body"`
+}
+
+// Interaction is a recorded request and the response to it
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Recorder is an http.RoundTripper recording to or replaying from a fixture
+type Recorder struct {
+	path      string
+	recording bool
+	real      http.RoundTripper
+
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+}
+
+// New returns a recorder for the fixture at path. Requests are recorded
+// through real, http.DefaultTransport if nil.
+func New(path string, mode Mode, real http.RoundTripper) (*Recorder, error) {
+	if real == nil {
+		real = http.DefaultTransport
+	}
+	r := &Recorder{path: path, real: real}
+	data, err := ioutil.ReadFile(path)
+	switch {
+	case mode == ModeRecord || (mode == ModeAuto && os.IsNotExist(err)):
+		r.recording = true
+		return r, nil
+	case err != nil:
+		return nil, err
+	}
+	var fixture struct {
+		Interactions []Interaction `json:"interactions"`
+	}
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		return nil, fmt.Errorf("vcr: reading %s: %v", path, err)
+	}
+	r.interactions = fixture.Interactions
+	r.used = make([]bool, len(r.interactions))
+	return r, nil
+}
+
+// Client returns an http client using the recorder
+func (r *Recorder) Client() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+// Recording reports whether the recorder records rather than replays
+func (r *Recorder) Recording() bool {
+	return r.recording
+}
+
+// RoundTrip records or replays a single request
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	recorded, err := recordRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	if !r.recording {
+		return r.replay(req, recorded)
+	}
+	resp, err := r.real.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	r.mu.Lock()
+	r.interactions = append(r.interactions, Interaction{
+		Request:  recorded,
+		Response: Response{StatusCode: resp.StatusCode, Header: resp.Header, Body: string(body)},
+	})
+	r.mu.Unlock()
+	return resp, nil
+}
+
+// recordRequest captures req, leaving its body readable
+func recordRequest(req *http.Request) (Request, error) {
+	recorded := Request{
+		Method:      req.Method,
+		URL:         req.URL.String(),
+		ContentType: req.Header.Get("Content-Type"),
+	}
+	if req.Body == nil {
+		return recorded, nil
+	}
+	if !strings.HasPrefix(recorded.ContentType, "application/json") {
+		// uploads are streamed and may be large, only their size is kept
+		recorded.BodySize = req.ContentLength
+		return recorded, nil
+	}
+	body, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return recorded, err
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	recorded.Body = body
+	recorded.BodySize = int64(len(body))
+	return recorded, nil
+}
+
+// replay answers req with the first unused matching interaction
+func (r *Recorder) replay(req *http.Request, recorded Request) (*http.Response, error) {
+	if req.Body != nil {
+		// drain uploads so that writers feeding them finish
+		ioutil.ReadAll(req.Body)
+		req.Body.Close()
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, in := range r.interactions {
+		if r.used[i] || !matches(in.Request, recorded) {
+			continue
+		}
+		r.used[i] = true
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", in.Response.StatusCode, http.StatusText(in.Response.StatusCode)),
+			StatusCode:    in.Response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        in.Response.Header,
+			Body:          ioutil.NopCloser(strings.NewReader(in.Response.Body)),
+			ContentLength: int64(len(in.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("%w: %s %s %s", ErrNoInteraction, recorded.Method, recorded.URL, recorded.Body)
+}
+
+func matches(a, b Request) bool {
+	if a.Method != b.Method || a.URL != b.URL {
+		return false
+	}
+	if a.Body == nil || b.Body == nil {
+		return a.Body == nil && b.Body == nil
+	}
+	return jsonEqual(a.Body, b.Body)
+}
+
+// jsonEqual compares two JSON documents ignoring formatting
+func jsonEqual(a, b []byte) bool {
+	var bufA, bufB bytes.Buffer
+	if json.Compact(&bufA, a) != nil || json.Compact(&bufB, b) != nil {
+		return bytes.Equal(a, b)
+	}
+	return bytes.Equal(bufA.Bytes(), bufB.Bytes())
+}
+
+// Close writes the recorded interactions to the fixture when recording
+func (r *Recorder) Close() error {
+	if !r.recording {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	data, err := json.MarshalIndent(struct {
+		Interactions []Interaction `json:"interactions"`
+	}{r.interactions}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(r.path, append(data, '\n'), 0644)
+}
@@ -0,0 +1,141 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrUnsupportedNodeVersion is returned, with version negotiation enabled,
+// for calls the node's release doesn't support
+var ErrUnsupportedNodeVersion = errors.New("unsupported by the node's version")
+
+// NodeVersion is a node release, ordered by Less
+type NodeVersion struct {
+	Major, Minor, Patch int
+}
+
+// ParseNodeVersion parses versions such as "v0.2.1" or "0.2.1-rc1"
+func ParseNodeVersion(s string) (NodeVersion, error) {
+	var v NodeVersion
+	core := strings.TrimPrefix(strings.TrimSpace(s), "v")
+	if i := strings.IndexAny(core, "-+"); i >= 0 {
+		core = core[:i]
+	}
+	parts := strings.Split(core, ".")
+	if len(parts) == 0 || len(parts) > 3 {
+		return v, fmt.Errorf("invalid node version %q", s)
+	}
+	fields := []*int{&v.Major, &v.Minor, &v.Patch}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return v, fmt.Errorf("invalid node version %q", s)
+		}
+		*fields[i] = n
+	}
+	return v, nil
+}
+
+// Less reports whether v is an older release than w
+func (v NodeVersion) Less(w NodeVersion) bool {
+	if v.Major != w.Major {
+		return v.Major < w.Major
+	}
+	if v.Minor != w.Minor {
+		return v.Minor < w.Minor
+	}
+	return v.Patch < w.Patch
+}
+
+func (v NodeVersion) String() string {
+	return fmt.Sprintf("v%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// compatRule describes a method added in a node release. adapt rewrites
+// calls for older nodes, or is nil if they can't serve the call at all.
+type compatRule struct {
+	since NodeVersion
+	adapt func(params []interface{}) (string, []interface{}, bool)
+}
+
+// compatRules is the compatibility matrix of the methods that older node
+// releases lack, by method
+var compatRules = map[string]compatRule{
+	"imagemanager_runImageWithOptions": {
+		since: NodeVersion{0, 2, 0},
+		// without options the call is a plain runImage
+		adapt: func(params []interface{}) (string, []interface{}, bool) {
+			if opts, ok := params[2].(RunOptions); ok && opts.Timeout == 0 && opts.GPUs == nil && len(opts.Inputs) == 0 &&
+				opts.Restart.IsZero() && opts.Reservation == "" {
+				return "imagemanager_runImage", params[:2], true
+			}
+			return "", nil, false
+		},
+	},
+	"discovery_discoverWithFilter": {since: NodeVersion{0, 2, 0}},
+	"discovery_listNodes":          {since: NodeVersion{0, 2, 0}},
+	"lvldb_select":                 {since: NodeVersion{0, 2, 0}},
+	"lvldb_selectAllPaged":         {since: NodeVersion{0, 2, 0}},
+	"node_getResources":            {since: NodeVersion{0, 3, 0}},
+	"node_benchmark":               {since: NodeVersion{0, 3, 0}},
+}
+
+// negotiation holds the node version, fetched once per client
+type negotiation struct {
+	once    sync.Once
+	version *NodeVersion
+}
+
+// NodeVersion returns the release the node runs
+func (rpc *CCClient) NodeVersion() (NodeVersion, error) {
+	var s string
+	res, err := rpc.doCall(context.Background(), newCorrelationID(), "node_version")
+	if err == nil {
+		err = rpc.codec.Unmarshal(res, &s)
+	}
+	if err != nil {
+		return NodeVersion{}, err
+	}
+	return ParseNodeVersion(s)
+}
+
+// adapt rewrites a call for the node's version. Nodes whose version can't
+// be determined are assumed to support every call.
+func (rpc *CCClient) adapt(method string, params []interface{}) (string, []interface{}, error) {
+	n := rpc.negotiation
+	n.once.Do(func() {
+		if v, err := rpc.NodeVersion(); err == nil {
+			n.version = &v
+		}
+	})
+	rule, ok := compatRules[method]
+	if n.version == nil || !ok || !n.version.Less(rule.since) {
+		return method, params, nil
+	}
+	if rule.adapt != nil {
+		if m, p, ok := rule.adapt(params); ok {
+			return m, p, nil
+		}
+	}
+	return "", nil, fmt.Errorf("%w: %s requires %s, the node runs %s", ErrUnsupportedNodeVersion, method, rule.since, n.version)
+}
@@ -0,0 +1,166 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// WebhookSignatureHeader carries the signature of a webhook payload,
+// "sha256=" followed by the hex encoded HMAC-SHA256 of the body
+const WebhookSignatureHeader = "X-CC-Signature"
+
+// maxWebhookPayload bounds the webhook bodies WebhookHandler accepts
+const maxWebhookPayload = 1 << 20
+
+// Webhook is a callback url the node POSTs container events to
+type Webhook struct {
+	ID     string               `json:"id"`
+	URL    string               `json:"url"`
+	Events []ContainerEventType `json:"events"`
+	// Secret is the key payloads are signed with, see VerifyWebhook
+	Secret string `json:"secret,omitempty"`
+}
+
+// RegisterWebhook has the node POST a signed ContainerEvent as JSON to url
+// for every event of the given types on the containers of the account, e.g.
+// ContainerDied and ContainerOOMKilled to learn when jobs finish or fail.
+// All events are sent if none are given.
+func (rpc *CCClient) RegisterWebhook(url string, events []ContainerEventType) (*Webhook, error) {
+	hook := new(Webhook)
+	if err := rpc.callInto(context.Background(), hook, "imagemanager_registerWebhook", url, events); err != nil {
+		return nil, err
+	}
+	return hook, nil
+}
+
+// UnregisterWebhook removes the webhook with the given id
+func (rpc *CCClient) UnregisterWebhook(id string) error {
+	_, err := rpc.call("imagemanager_unregisterWebhook", id)
+	return err
+}
+
+// ListWebhooks returns the webhooks registered for the account, without
+// their secrets
+func (rpc *CCClient) ListWebhooks() ([]Webhook, error) {
+	var hooks []Webhook
+	if err := rpc.callInto(context.Background(), &hooks, "imagemanager_listWebhooks"); err != nil {
+		return nil, err
+	}
+	return hooks, nil
+}
+
+// SignWebhook returns the WebhookSignatureHeader value for body
+func SignWebhook(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhook reports whether signature is the signature of body
+func VerifyWebhook(secret string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(SignWebhook(secret, body)), []byte(strings.TrimSpace(signature)))
+}
+
+// WebhookHandler returns a handler for webhook requests which verifies their
+// signature and passes the events to fn. Unsigned or forged requests are
+// answered with 401 Unauthorized.
+func WebhookHandler(secret string, fn func(ContainerEvent)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookPayload))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		if !VerifyWebhook(secret, body, r.Header.Get(WebhookSignatureHeader)) {
+			http.Error(w, "invalid signature", http.StatusUnauthorized)
+			return
+		}
+		var ev ContainerEvent
+		if err := json.Unmarshal(body, &ev); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		fn(ev)
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// RelayWebhook delivers the container events of the given node, or of all
+// nodes if nodeID is empty, to hook like the node would, for nodes without
+// webhook support. Events are relayed until the returned subscription is
+// unsubscribed; failed deliveries are not retried.
+func (rpc *CCClient) RelayWebhook(ctx context.Context, nodeID string, hook Webhook) (*Subscription, error) {
+	events, sub, err := rpc.SubscribeContainerEvents(ctx, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	wanted := make(map[ContainerEventType]bool, len(hook.Events))
+	for _, t := range hook.Events {
+		wanted[t] = true
+	}
+	go func() {
+		for ev := range events {
+			if len(wanted) > 0 && !wanted[ev.Type] {
+				continue
+			}
+			if err := rpc.deliverWebhook(ctx, hook, ev); err != nil && rpc.Debug {
+				log.Printf("webhook %s: %v\n", hook.URL, err)
+			}
+		}
+	}()
+	return sub, nil
+}
+
+// deliverWebhook POSTs the signed event to the webhook url
+func (rpc *CCClient) deliverWebhook(ctx context.Context, hook Webhook, ev ContainerEvent) error {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	// the client's headers are meant for the node and not passed on
+	setHeaders(context.Background(), req.Header, rpc.userAgent, nil)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookSignatureHeader, SignWebhook(hook.Secret, body))
+	resp, err := rpc.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+type rpcNotification struct {
+	ID     *int            `json:"id"`
+	Method string          `json:"method"`
+	Result json.RawMessage `json:"result"`
+	Error  *rpcError       `json:"error"`
+	Params struct {
+		Subscription string          `json:"subscription"`
+		Result       json.RawMessage `json:"result"`
+		// Seq numbers the notifications of nodes resuming subscriptions
+		Seq uint64 `json:"seq"`
+	} `json:"params"`
+}
+
+// wsSubscription is a subscription held open over a dedicated websocket
+type wsSubscription struct {
+	conn      *websocket.Conn
+	namespace string
+	id        string
+	version   string
+	debug     bool
+	closeOnce sync.Once
+
+	writeMu sync.Mutex
+	nextID  int
+	lastID  string
+}
+
+// webSocketURL returns the subscription endpoint of the node
+func (rpc *CCClient) webSocketURL() (string, error) {
+	if rpc.wsURL != "" {
+		return rpc.wsURL, nil
+	}
+	u, err := url.Parse(rpc.endpoint(context.Background()))
+	if err != nil {
+		return "", err
+	}
+	switch u.Scheme {
+	case "http":
+		u.Scheme = "ws"
+	case "https":
+		u.Scheme = "wss"
+	}
+	return u.String(), nil
+}
+
+// wsSubscribe subscribes to the given namespace's events, calling namespace_subscribe
+// with params. Notifications are read with next until close is called.
+func (rpc *CCClient) wsSubscribe(ctx context.Context, namespace string, params ...interface{}) (*wsSubscription, error) {
+	wsURL, err := rpc.webSocketURL()
+	if err != nil {
+		return nil, err
+	}
+	header := http.Header{}
+	setHeaders(ctx, header, rpc.userAgent, rpc.header)
+	token, err := rpc.authToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		header.Set("Authorization", "Bearer "+token)
+	}
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, wsURL, header)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, &transportError{transport: TransportWebSocket, err: err}
+	}
+	sub := &wsSubscription{conn: conn, namespace: namespace, version: rpc.versionJSONRPC, debug: rpc.Debug, nextID: 1}
+	request := rpcRequest{
+		ID:      1,
+		JSONRPC: rpc.versionJSONRPC,
+		Method:  namespace + "_subscribe",
+		Params:  params,
+	}
+	if err := conn.WriteJSON(request); err != nil {
+		conn.Close()
+		return nil, &transportError{transport: TransportWebSocket, err: err}
+	}
+	// abort the handshake if ctx is done before the node answers
+	stop := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-stop:
+		}
+	}()
+	var resp rpcNotification
+	err = conn.ReadJSON(&resp)
+	close(stop)
+	if err != nil {
+		conn.Close()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, &transportError{transport: TransportWebSocket, err: err}
+	}
+	if resp.Error != nil {
+		conn.Close()
+		return nil, *resp.Error
+	}
+	if err := json.Unmarshal(resp.Result, &sub.id); err != nil || sub.id == "" {
+		conn.Close()
+		return nil, fmt.Errorf("%s_subscribe returned no subscription id", namespace)
+	}
+	return sub, nil
+}
+
+// next blocks until the next notification of the subscription arrives
+func (s *wsSubscription) next() (json.RawMessage, error) {
+	for {
+		var msg rpcNotification
+		if err := s.conn.ReadJSON(&msg); err != nil {
+			return nil, err
+		}
+		if s.debug {
+			log.Printf("%s subscription %s\nNotification: %s\n", s.namespace, s.id, msg.Params.Result)
+		}
+		if msg.ID != nil || msg.Params.Subscription != s.id {
+			continue
+		}
+		if msg.Params.Seq != 0 {
+			s.lastID = strconv.FormatUint(msg.Params.Seq, 10)
+		}
+		return msg.Params.Result, nil
+	}
+}
+
+// send calls method over the subscription's connection without waiting for
+// its response, which next skips
+func (s *wsSubscription) send(method string, params ...interface{}) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	s.nextID++
+	return s.conn.WriteJSON(rpcRequest{
+		ID:      s.nextID,
+		JSONRPC: s.version,
+		Method:  method,
+		Params:  params,
+	})
+}
+
+// close unsubscribes and closes the connection, unblocking a pending next
+func (s *wsSubscription) close() {
+	s.closeOnce.Do(func() {
+		s.send(s.namespace+"_unsubscribe", s.id)
+		s.conn.Close()
+	})
+}
+
+func (s *wsSubscription) lastEventID() string {
+	return s.lastID
+}
@@ -0,0 +1,148 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccgosdk
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+)
+
+// BinaryCodec encodes RPC payloads in a binary format such as MessagePack or
+// CBOR, see WithBinaryCodec. Unmarshal into an interface{} must produce maps,
+// slices and scalars, as the generic decoders of such libraries do.
+type BinaryCodec interface {
+	// ContentType is the media type of the format, e.g. "application/msgpack"
+	ContentType() string
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// wireFormat is the binary format negotiated with the node, shared by
+// derived clients
+type wireFormat struct {
+	codec BinaryCodec
+	// jsonOnly is set once the node refused the format
+	jsonOnly int32
+}
+
+// binary returns the codec to encode calls with, nil for JSON
+func (w *wireFormat) binary() BinaryCodec {
+	if w == nil || atomic.LoadInt32(&w.jsonOnly) != 0 {
+		return nil
+	}
+	return w.codec
+}
+
+// refused reports whether resp rejects the binary format, in which case
+// the client falls back to JSON
+func (w *wireFormat) refused(resp *http.Response) bool {
+	if resp.StatusCode != http.StatusUnsupportedMediaType && resp.StatusCode != http.StatusNotAcceptable {
+		return false
+	}
+	atomic.StoreInt32(&w.jsonOnly, 1)
+	return true
+}
+
+// isJSON reports whether a Content-Type header denotes JSON
+func isJSON(contentType string) bool {
+	t, _, err := mime.ParseMediaType(contentType)
+	return err != nil || t == "application/json"
+}
+
+// toBinary transcodes a JSON document, so that values with custom JSON
+// encodings are sent the same way in both formats
+func toBinary(codec BinaryCodec, data []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	// numbers would lose precision as float64, e.g. large int64 ids
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return codec.Marshal(binaryNumbers(v))
+}
+
+// binaryNumbers converts the numbers of a document decoded with UseNumber to
+// int64 or uint64 if they are integers in range, float64 otherwise
+func binaryNumbers(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		if u, err := strconv.ParseUint(string(v), 10, 64); err == nil {
+			return u
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]interface{}:
+		for k, e := range v {
+			v[k] = binaryNumbers(e)
+		}
+		return v
+	case []interface{}:
+		for i, e := range v {
+			v[i] = binaryNumbers(e)
+		}
+		return v
+	}
+	return v
+}
+
+// fromBinary transcodes a binary document read from r to JSON
+func fromBinary(codec BinaryCodec, r io.Reader) ([]byte, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var v interface{}
+	if err := codec.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	return json.Marshal(jsonCompatible(v))
+}
+
+// jsonCompatible converts the map types some binary decoders produce to
+// ones encoding/json can marshal
+func jsonCompatible(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[fmt.Sprint(k)] = jsonCompatible(e)
+		}
+		return m
+	case map[string]interface{}:
+		for k, e := range v {
+			v[k] = jsonCompatible(e)
+		}
+		return v
+	case []interface{}:
+		for i, e := range v {
+			v[i] = jsonCompatible(e)
+		}
+		return v
+	}
+	return v
+}
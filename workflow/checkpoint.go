@@ -0,0 +1,299 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+package workflow
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	ccgosdk "github.com/crowdcompute/cc-go-sdk"
+)
+
+// ErrNoCheckpoint is returned by Store.Load for workflows without checkpoint
+var ErrNoCheckpoint = errors.New("no checkpoint")
+
+// Checkpoint is the state of a workflow run, saved whenever it changes
+type Checkpoint struct {
+	// Completed holds the results of the completed tasks, which aren't run
+	// again when the workflow is resumed
+	Completed Results `json:"completed"`
+	// Running holds the tasks submitted when the checkpoint was taken.
+	// They are run again when the workflow is resumed.
+	Running map[string]TaskRun `json:"running"`
+	// Failed holds the errors of the failed tasks, which are run again
+	Failed    map[string]string `json:"failed"`
+	UpdatedAt time.Time         `json:"updatedAt"`
+}
+
+// TaskRun is a submitted task and the node it was placed on
+type TaskRun struct {
+	NodeID    string           `json:"nodeId,omitempty"`
+	State     ccgosdk.JobState `json:"state"`
+	StartedAt time.Time        `json:"startedAt"`
+}
+
+// Store persists the checkpoints of workflows by id, so that a workflow can
+// be resumed after the process running it crashed. FileStore and SQLStore
+// are provided, other databases, e.g. bolt, implement it in a few lines.
+type Store interface {
+	// Load returns the checkpoint saved for id, ErrNoCheckpoint if none
+	Load(ctx context.Context, id string) (*Checkpoint, error)
+	Save(ctx context.Context, id string, cp *Checkpoint) error
+	Delete(ctx context.Context, id string) error
+}
+
+// FileStore stores every checkpoint as a JSON file in a directory
+type FileStore struct {
+	Dir string
+}
+
+func (s FileStore) path(id string) string {
+	return filepath.Join(s.Dir, url.PathEscape(id)+".json")
+}
+
+// Load reads the checkpoint of id
+func (s FileStore) Load(ctx context.Context, id string) (*Checkpoint, error) {
+	data, err := ioutil.ReadFile(s.path(id))
+	if os.IsNotExist(err) {
+		return nil, ErrNoCheckpoint
+	}
+	if err != nil {
+		return nil, err
+	}
+	cp := new(Checkpoint)
+	if err := json.Unmarshal(data, cp); err != nil {
+		return nil, fmt.Errorf("checkpoint %s: %w", id, err)
+	}
+	return cp, nil
+}
+
+// Save replaces the checkpoint of id atomically, so that a crash while
+// saving leaves the previous one
+func (s FileStore) Save(ctx context.Context, id string, cp *Checkpoint) error {
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.Dir, 0755); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(s.Dir, ".checkpoint-")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), s.path(id))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// Delete removes the checkpoint of id, if any
+func (s FileStore) Delete(ctx context.Context, id string) error {
+	if err := os.Remove(s.path(id)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+const defaultCheckpointTable = "workflow_checkpoints"
+
+// SQLStore stores checkpoints as JSON in a table of a SQL database, which
+// is created with CreateTable
+type SQLStore struct {
+	DB *sql.DB
+	// Table is "workflow_checkpoints" by default
+	Table string
+	// Numbered uses $1 style placeholders, e.g. for PostgreSQL, instead of ?
+	Numbered bool
+}
+
+func (s *SQLStore) table() string {
+	if s.Table == "" {
+		return defaultCheckpointTable
+	}
+	return s.Table
+}
+
+// query replaces the ? placeholders of q if the database numbers them
+func (s *SQLStore) query(q string) string {
+	if !s.Numbered {
+		return q
+	}
+	var out []byte
+	n := 0
+	for i := 0; i < len(q); i++ {
+		if q[i] == '?' {
+			n++
+			out = append(out, fmt.Sprintf("$%d", n)...)
+			continue
+		}
+		out = append(out, q[i])
+	}
+	return string(out)
+}
+
+// CreateTable creates the table of the store unless it exists
+func (s *SQLStore) CreateTable(ctx context.Context) error {
+	_, err := s.DB.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS "+s.table()+" (id VARCHAR(255) PRIMARY KEY, checkpoint TEXT NOT NULL)")
+	return err
+}
+
+// Load reads the checkpoint of id
+func (s *SQLStore) Load(ctx context.Context, id string) (*Checkpoint, error) {
+	var data string
+	err := s.DB.QueryRowContext(ctx, s.query("SELECT checkpoint FROM "+s.table()+" WHERE id = ?"), id).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, ErrNoCheckpoint
+	}
+	if err != nil {
+		return nil, err
+	}
+	cp := new(Checkpoint)
+	if err := json.Unmarshal([]byte(data), cp); err != nil {
+		return nil, fmt.Errorf("checkpoint %s: %w", id, err)
+	}
+	return cp, nil
+}
+
+// Save replaces the checkpoint of id in a transaction
+func (s *SQLStore) Save(ctx context.Context, id string, cp *Checkpoint) error {
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	// delete and insert rather than upsert, whose syntax differs by database
+	if _, err := tx.ExecContext(ctx, s.query("DELETE FROM "+s.table()+" WHERE id = ?"), id); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, s.query("INSERT INTO "+s.table()+" (id, checkpoint) VALUES (?, ?)"), id, string(data)); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// Delete removes the checkpoint of id, if any
+func (s *SQLStore) Delete(ctx context.Context, id string) error {
+	_, err := s.DB.ExecContext(ctx, s.query("DELETE FROM "+s.table()+" WHERE id = ?"), id)
+	return err
+}
+
+// checkpointer keeps the checkpoint of a run up to date in its store
+type checkpointer struct {
+	ctx   context.Context
+	store Store
+	id    string
+
+	mu  sync.Mutex
+	cp  Checkpoint
+	err error
+}
+
+// newCheckpointer loads the checkpoint of id, starting a new one if there
+// is none
+func newCheckpointer(ctx context.Context, store Store, id string) (*checkpointer, error) {
+	c := &checkpointer{ctx: ctx, store: store, id: id}
+	cp, err := store.Load(ctx, id)
+	switch {
+	case errors.Is(err, ErrNoCheckpoint):
+		cp = new(Checkpoint)
+	case err != nil:
+		return nil, fmt.Errorf("loading checkpoint: %w", err)
+	}
+	c.cp = *cp
+	if c.cp.Completed == nil {
+		c.cp.Completed = make(Results)
+	}
+	// interrupted and failed tasks are run again
+	c.cp.Running = make(map[string]TaskRun)
+	c.cp.Failed = make(map[string]string)
+	return c, nil
+}
+
+// update changes the checkpoint with fn and saves it, keeping the first
+// error saving failed with
+func (c *checkpointer) update(fn func(cp *Checkpoint)) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fn(&c.cp)
+	c.cp.UpdatedAt = time.Now()
+	if err := c.store.Save(c.ctx, c.id, &c.cp); err != nil && c.err == nil {
+		c.err = err
+	}
+}
+
+func (c *checkpointer) started(name string) {
+	c.update(func(cp *Checkpoint) {
+		cp.Running[name] = TaskRun{State: ccgosdk.JobQueued, StartedAt: time.Now()}
+	})
+}
+
+func (c *checkpointer) changed(name string, change ccgosdk.JobStateChange) {
+	c.update(func(cp *Checkpoint) {
+		run := cp.Running[name]
+		run.NodeID, run.State = change.NodeID, change.To
+		cp.Running[name] = run
+	})
+}
+
+func (c *checkpointer) finished(name string, result *ccgosdk.JobResult, err error) {
+	c.update(func(cp *Checkpoint) {
+		delete(cp.Running, name)
+		if err != nil {
+			cp.Failed[name] = err.Error()
+		} else {
+			cp.Completed[name] = result
+		}
+	})
+}
+
+// saveErr returns the first error saving the checkpoint failed with
+func (c *checkpointer) saveErr() error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return fmt.Errorf("saving checkpoint: %w", c.err)
+	}
+	return nil
+}
@@ -0,0 +1,303 @@
+// Copyright 2019 The crowdcompute:cc-go-sdk Authors
+// This file is part of the crowdcompute:cc-go-sdk library.
+//
+// The crowdcompute:cc-go-sdk library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:cc-go-sdk library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:cc-go-sdk library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package workflow runs jobs that depend on each other as a DAG across the
+// worker nodes of a crowdcompute network, passing the outputs of upstream
+// jobs as inputs to downstream ones.
+package workflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	ccgosdk "github.com/crowdcompute/cc-go-sdk"
+)
+
+const (
+	defaultInputDir    = "/inputs"
+	defaultConcurrency = 4
+)
+
+// ErrSkipped is reported for tasks not run because a dependency failed
+var ErrSkipped = errors.New("skipped, a dependency failed")
+
+// Task is a job of the workflow
+type Task struct {
+	Name string
+	Job  ccgosdk.JobSpec
+	// DependsOn names the tasks whose outputs this task needs
+	DependsOn []string
+	// Retries is the number of additional attempts after a failure
+	Retries int
+}
+
+// Results maps task names to the results of completed tasks.
+// It can be persisted and passed to Run to resume a workflow.
+type Results map[string]*ccgosdk.JobResult
+
+// Error reports the tasks of a workflow that failed or were skipped
+type Error struct {
+	Failed map[string]error
+}
+
+func (e *Error) Error() string {
+	names := make([]string, 0, len(e.Failed))
+	for name := range e.Failed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	msgs := make([]string, len(names))
+	for i, name := range names {
+		msgs[i] = fmt.Sprintf("%s: %v", name, e.Failed[name])
+	}
+	return "workflow failed: " + strings.Join(msgs, "; ")
+}
+
+// Workflow is a set of tasks and their dependencies
+type Workflow struct {
+	// WorkDir holds the outputs of the tasks, each in a directory named after it
+	WorkDir string
+	// InputDir is the container directory the outputs of a task's
+	// dependencies are placed in, each in a directory named after the
+	// dependency, "/inputs" by default
+	InputDir string
+	// Concurrency bounds the number of tasks running at once, 4 by default
+	Concurrency int
+	// Store, if set, keeps a checkpoint of the run under ID, from which
+	// Run resumes the workflow after the process running it crashed.
+	// The checkpoint is kept once the workflow completed.
+	Store Store
+	ID    string
+
+	tasks map[string]*Task
+}
+
+// New creates an empty workflow storing task outputs in workDir
+func New(workDir string) *Workflow {
+	return &Workflow{WorkDir: workDir, tasks: make(map[string]*Task)}
+}
+
+// Add adds a task to the workflow
+func (w *Workflow) Add(t Task) error {
+	if t.Name == "" {
+		return errors.New("task needs a name")
+	}
+	if _, ok := w.tasks[t.Name]; ok {
+		return fmt.Errorf("duplicate task %q", t.Name)
+	}
+	w.tasks[t.Name] = &t
+	return nil
+}
+
+// Validate checks that all dependencies exist and don't form a cycle
+func (w *Workflow) Validate() error {
+	const (
+		visiting = iota + 1
+		visited
+	)
+	state := make(map[string]int, len(w.tasks))
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visiting:
+			return fmt.Errorf("task %q is part of a dependency cycle", name)
+		case visited:
+			return nil
+		}
+		state[name] = visiting
+		for _, dep := range w.tasks[name].DependsOn {
+			if _, ok := w.tasks[dep]; !ok {
+				return fmt.Errorf("task %q depends on undefined task %q", name, dep)
+			}
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		state[name] = visited
+		return nil
+	}
+	for name := range w.tasks {
+		if err := visit(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type taskOutcome struct {
+	name   string
+	result *ccgosdk.JobResult
+	err    error
+}
+
+// Run runs the tasks of the workflow through client, each as soon as its
+// dependencies completed. Tasks found in completed, the results of an earlier
+// run, or in the checkpoint of the Store are not run again. The results of
+// all completed tasks are returned, along with an *Error if any task failed.
+func (w *Workflow) Run(ctx context.Context, client ccgosdk.JobRunner, completed Results) (Results, error) {
+	if err := w.Validate(); err != nil {
+		return nil, err
+	}
+	var cp *checkpointer
+	if w.Store != nil {
+		if w.ID == "" {
+			return nil, errors.New("workflow with a store needs an id")
+		}
+		var err error
+		if cp, err = newCheckpointer(ctx, w.Store, w.ID); err != nil {
+			return nil, err
+		}
+		merged := make(Results, len(cp.cp.Completed)+len(completed))
+		for name, r := range cp.cp.Completed {
+			merged[name] = r
+		}
+		for name, r := range completed {
+			merged[name] = r
+		}
+		completed = merged
+	}
+	concurrency := w.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultConcurrency
+	}
+	results := make(Results, len(w.tasks))
+	for name, r := range completed {
+		if _, ok := w.tasks[name]; ok {
+			results[name] = r
+		}
+	}
+	failed := make(map[string]error)
+	running := make(map[string]bool)
+	outcomes := make(chan taskOutcome)
+
+	for {
+		for _, name := range w.ready(results, failed, running) {
+			if len(running) >= concurrency {
+				break
+			}
+			if err := w.blocked(name, failed); err != nil {
+				failed[name] = err
+				cp.finished(name, nil, err)
+				continue
+			}
+			running[name] = true
+			cp.started(name)
+			go func(t *Task, job ccgosdk.JobSpec) {
+				if cp != nil {
+					onChange := job.OnStateChange
+					job.OnStateChange = func(change ccgosdk.JobStateChange) {
+						cp.changed(t.Name, change)
+						if onChange != nil {
+							onChange(change)
+						}
+					}
+				}
+				r, err := w.runTask(ctx, client, t, job)
+				cp.finished(t.Name, r, err)
+				outcomes <- taskOutcome{name: t.Name, result: r, err: err}
+			}(w.tasks[name], w.jobSpec(w.tasks[name], results))
+		}
+		if len(running) == 0 {
+			// nothing runs and nothing more can start: done
+			if len(results)+len(failed) == len(w.tasks) || len(w.ready(results, failed, running)) == 0 {
+				break
+			}
+			continue
+		}
+		o := <-outcomes
+		delete(running, o.name)
+		if o.err != nil {
+			failed[o.name] = o.err
+		} else {
+			results[o.name] = o.result
+		}
+	}
+	if len(failed) > 0 {
+		return results, &Error{Failed: failed}
+	}
+	return results, cp.saveErr()
+}
+
+// ready returns the tasks, sorted by name, that have neither run nor failed
+// and whose dependencies have all completed or failed
+func (w *Workflow) ready(results Results, failed map[string]error, running map[string]bool) []string {
+	var names []string
+	for name, t := range w.tasks {
+		if results[name] != nil || failed[name] != nil || running[name] {
+			continue
+		}
+		settled := true
+		for _, dep := range t.DependsOn {
+			if results[dep] == nil && failed[dep] == nil {
+				settled = false
+				break
+			}
+		}
+		if settled {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// blocked returns ErrSkipped if a dependency of the task failed
+func (w *Workflow) blocked(name string, failed map[string]error) error {
+	for _, dep := range w.tasks[name].DependsOn {
+		if failed[dep] != nil {
+			return ErrSkipped
+		}
+	}
+	return nil
+}
+
+// jobSpec completes the task's job with its output directory and the
+// outputs of its dependencies
+func (w *Workflow) jobSpec(t *Task, results Results) ccgosdk.JobSpec {
+	job := t.Job
+	if job.OutputPath != "" && job.OutputDir == "" {
+		job.OutputDir = filepath.Join(w.WorkDir, t.Name)
+	}
+	inputDir := w.InputDir
+	if inputDir == "" {
+		inputDir = defaultInputDir
+	}
+	job.Inputs = append([]ccgosdk.JobInput(nil), job.Inputs...)
+	for _, dep := range t.DependsOn {
+		if out := results[dep].OutputDir; out != "" {
+			job.Inputs = append(job.Inputs, ccgosdk.JobInput{Source: out, Path: inputDir})
+		}
+	}
+	return job
+}
+
+func (w *Workflow) runTask(ctx context.Context, client ccgosdk.JobRunner, t *Task, job ccgosdk.JobSpec) (*ccgosdk.JobResult, error) {
+	var err error
+	for attempt := 0; attempt <= t.Retries; attempt++ {
+		var r *ccgosdk.JobResult
+		if r, err = client.RunJob(ctx, job); err == nil {
+			return r, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+	}
+	return nil, err
+}